@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/pflag"
+	"github.com/threatcat-dev/threatcat/internal/output"
+	"github.com/threatcat-dev/threatcat/internal/threatdragon"
 )
 
 var threatCatLogo string = `
@@ -40,8 +43,14 @@ var threatCatLogo string = `
 
 type inputFiles struct {
 	DockerComposeFiles []string
+	ComposeProjects    []string
 	ThreatDragonFiles  []string
 	DataFlowYamlFiles  []string
+	KubernetesFiles    []string
+	TerraformModules   []string
+	TerraformJSONFiles []string
+	ThreagileFiles     []string
+	OTMFiles           []string
 }
 
 // arguments to initialize logger
@@ -50,6 +59,17 @@ type loggingOptions struct {
 	LogFilePath string
 }
 
+// Docker Compose project related arguments
+type composeOptions struct {
+	Profiles []string
+	EnvFiles []string
+}
+
+// Terraform related arguments
+type terraformOptions struct {
+	Workspace string
+}
+
 // Config files related arguments
 type configFileOptions struct {
 	DockerImageMapConfig string
@@ -59,7 +79,12 @@ type configFileOptions struct {
 type userArguments struct {
 	LogOpts       loggingOptions
 	InFiles       inputFiles
-	OutFilePath   string
+	ComposeOpts   composeOptions
+	TerraformOpts terraformOptions
+	Outputs       []string
+	Format        string
+	Diagrams      string
+	Layout        string
 	SilentMode    bool
 	ConfigFiles   configFileOptions
 	ChangelogPath string
@@ -71,10 +96,22 @@ func readArguments() userArguments {
 
 	//input file related arguments
 	pflag.StringSliceVarP(&args.InFiles.DockerComposeFiles, "dockercompose", "d", []string{}, "Indicates a DockerCompose input file")
-	pflag.StringSliceVarP(&args.InFiles.ThreatDragonFiles, "threatdragon", "t", []string{}, "Indicates a ThreatDragon input file")
+	pflag.StringArrayVar(&args.InFiles.ComposeProjects, "compose-project", []string{}, "Indicates the comma separated DockerCompose files of one project, later files override earlier ones")
+	pflag.StringSliceVarP(&args.InFiles.ThreatDragonFiles, "threatdragon", "t", []string{}, "Indicates a ThreatDragon input file (1.x or 2.x)")
 	pflag.StringSliceVarP(&args.InFiles.DataFlowYamlFiles, "dataflow", "w", []string{}, "Define path to data flow input file")
+	pflag.StringSliceVarP(&args.InFiles.KubernetesFiles, "kubernetes", "k", []string{}, "Indicates a Kubernetes manifest input file")
+	pflag.StringSliceVar(&args.InFiles.TerraformModules, "terraform", []string{}, "Indicates a Terraform module directory")
+	pflag.StringSliceVar(&args.ComposeOpts.Profiles, "compose-profile", []string{}, "Enable a DockerCompose profile")
+	pflag.StringSliceVar(&args.ComposeOpts.EnvFiles, "env-file", []string{}, "Define path to an environment file for DockerCompose")
+	pflag.StringSliceVar(&args.InFiles.TerraformJSONFiles, "terraform-json", []string{}, "Indicates a Terraform plan or state file in the format of 'terraform show -json'")
+	pflag.StringVar(&args.TerraformOpts.Workspace, "terraform-workspace", "", "Name the Terraform workspace of the Terraform inputs, to tell apart resources with the same address in different workspaces")
+	pflag.StringSliceVar(&args.InFiles.ThreagileFiles, "threagile", []string{}, "Indicates a Threagile input file")
+	pflag.StringSliceVar(&args.InFiles.OTMFiles, "otm", []string{}, "Indicates an Open Threat Model input file in JSON or YAML")
 	//threat model output file related arguments
-	pflag.StringVarP(&args.OutFilePath, "output", "o", "out.json", "Define Output Filepath")
+	pflag.StringArrayVarP(&args.Outputs, "output", "o", []string{"out.json"}, "Define an output file, repeat it with a format prefix to write several, e.g. -o td:model.json -o md:report.md")
+	pflag.StringVar(&args.Format, "format", output.FormatThreatDragon, "Define the format of outputs without prefix: threatdragon (td), threagile, otm, markdown (md) or sarif")
+	pflag.StringVar(&args.Diagrams, "diagrams", "single", "Split a new ThreatDragon model into diagrams: single, per-file or per-boundary")
+	pflag.StringVar(&args.Layout, "layout", "layered", "Place the cells of new ThreatDragon diagrams: layered or grid")
 	//logging related arguments
 	pflag.BoolVarP(&args.LogOpts.Verbose, "verbose", "v", false, "Enable verbose logging")
 	pflag.StringVarP(&args.LogOpts.LogFilePath, "logfile", "f", "", "Define path to Logfile")
@@ -91,12 +128,12 @@ func readArguments() userArguments {
 
 func (a userArguments) validate() error {
 	// check if at least one input file is provided
-	if len(a.InFiles.DockerComposeFiles) == 0 && len(a.InFiles.ThreatDragonFiles) == 0 {
+	if len(a.InFiles.DockerComposeFiles) == 0 && len(a.InFiles.ComposeProjects) == 0 && len(a.InFiles.ThreatDragonFiles) == 0 && len(a.InFiles.KubernetesFiles) == 0 && len(a.InFiles.TerraformModules) == 0 && len(a.InFiles.TerraformJSONFiles) == 0 && len(a.InFiles.ThreagileFiles) == 0 && len(a.InFiles.OTMFiles) == 0 {
 		return fmt.Errorf("at least one input file must be provided")
 	}
 
 	// check if output file path is provided
-	if a.OutFilePath == "" {
+	if len(a.Outputs) == 0 {
 		return fmt.Errorf("output file path must be provided")
 	}
 
@@ -106,20 +143,78 @@ func (a userArguments) validate() error {
 			return fmt.Errorf("invalid DockerCompose file path: %s", fpath)
 		}
 	}
+	for _, project := range a.InFiles.ComposeProjects {
+		for _, fpath := range strings.Split(project, ",") {
+			if !validInputPath(fpath) {
+				return fmt.Errorf("invalid DockerCompose file path: %s", fpath)
+			}
+		}
+	}
+	for _, fpath := range a.ComposeOpts.EnvFiles {
+		if !validInputPath(fpath) {
+			return fmt.Errorf("invalid DockerCompose environment file path: %s", fpath)
+		}
+	}
 	for _, fpath := range a.InFiles.ThreatDragonFiles {
 		if !validInputPath(fpath) {
 			return fmt.Errorf("invalid ThreatDragon file path: %s", fpath)
 		}
 	}
+	for _, fpath := range a.InFiles.KubernetesFiles {
+		if !validInputPath(fpath) {
+			return fmt.Errorf("invalid Kubernetes file path: %s", fpath)
+		}
+	}
+	for _, dpath := range a.InFiles.TerraformModules {
+		if !validInputDirectory(dpath) {
+			return fmt.Errorf("invalid Terraform module directory: %s", dpath)
+		}
+	}
+	for _, fpath := range a.InFiles.TerraformJSONFiles {
+		if !validInputPath(fpath) {
+			return fmt.Errorf("invalid Terraform JSON file path: %s", fpath)
+		}
+	}
+	for _, fpath := range a.InFiles.ThreagileFiles {
+		if !validInputPath(fpath) {
+			return fmt.Errorf("invalid Threagile file path: %s", fpath)
+		}
+	}
+	for _, fpath := range a.InFiles.OTMFiles {
+		if !validInputPath(fpath) {
+			return fmt.Errorf("invalid OTM file path: %s", fpath)
+		}
+	}
 	for _, fpath := range a.InFiles.DataFlowYamlFiles {
 		if !validInputPath(fpath) {
 			return fmt.Errorf("invalid Dataflows ")
 		}
 	}
 
-	// check if the output file path is valid
-	if !validOutputPath(a.OutFilePath) {
-		return fmt.Errorf("invalid output file path: %s", a.OutFilePath)
+	// check if the output format is known
+	if _, ok := outputWriters.Lookup(a.Format); !ok {
+		return fmt.Errorf("unknown output format '%s', expected one of %s", a.Format, strings.Join(outputWriters.Formats(), ", "))
+	}
+
+	// check if the diagram strategy is known
+	if _, err := threatdragon.ParseDiagramStrategy(a.Diagrams); err != nil {
+		return err
+	}
+
+	// check if the layout is known
+	if _, err := threatdragon.ParseLayoutStrategy(a.Layout); err != nil {
+		return err
+	}
+
+	// check if the output files are valid and have a known format
+	targets, err := outputWriters.ParseTargets(a.Outputs, a.Format)
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		if !validOutputPath(target.Path) {
+			return fmt.Errorf("invalid output file path: %s", target.Path)
+		}
 	}
 
 	// if a log file path is provided, check if it is valid
@@ -159,6 +254,23 @@ func validInputPath(path string) bool {
 	return !info.IsDir()
 }
 
+// validInputDirectory checks the following criteria for input directories:
+// 1. The path is not empty.
+// 2. The directory exists.
+func validInputDirectory(path string) bool {
+	cleanPath := filepath.Clean(path)
+	if len(cleanPath) == 0 {
+		return false
+	}
+
+	info, err := os.Stat(cleanPath)
+	if err != nil {
+		return false
+	}
+
+	return info.IsDir()
+}
+
 // validOutputPath checks the following criteria for output paths:
 // 1. The path is not empty.
 // 2. The path is a valid file path.
@@ -200,7 +312,12 @@ func (a userArguments) print() {
 	fmt.Printf("%-20s | %-30t\n", "verbose mode", a.LogOpts.Verbose)
 	fmt.Printf("%-20s | %-30t\n", "silent mode", a.SilentMode)
 	fmt.Printf("%-20s | %-30s\n", "log file path", a.LogOpts.LogFilePath)
-	fmt.Printf("%-20s | %-30s\n", "output file path", a.OutFilePath)
+	for _, value := range a.Outputs {
+		fmt.Printf("%-20s | %-30s\n", "output file", value)
+	}
+	fmt.Printf("%-20s | %-30s\n", "default format", a.Format)
+	fmt.Printf("%-20s | %-30s\n", "diagrams", a.Diagrams)
+	fmt.Printf("%-20s | %-30s\n", "layout", a.Layout)
 	fmt.Printf("%-20s | %-30s\n", "docker image config file", a.ConfigFiles.DockerImageMapConfig)
 	fmt.Printf("%-20s | %-30s\n", "changelog path", a.ChangelogPath)
 	for _, fpath := range a.InFiles.DockerComposeFiles {
@@ -208,9 +325,45 @@ func (a userArguments) print() {
 
 	}
 
+	for _, project := range a.InFiles.ComposeProjects {
+		fmt.Printf("%-20s | %-12s\n", "compose project", project)
+	}
+
+	for _, profile := range a.ComposeOpts.Profiles {
+		fmt.Printf("%-20s | %-12s\n", "compose profile", profile)
+	}
+
+	for _, fpath := range a.ComposeOpts.EnvFiles {
+		fmt.Printf("%-20s | %-12s\n", "compose env file", fpath)
+	}
+
 	for _, fpath := range a.InFiles.ThreatDragonFiles {
 		fmt.Printf("%-20s | %-12s\n", "threat dragon file", fpath)
 	}
 
+	for _, fpath := range a.InFiles.KubernetesFiles {
+		fmt.Printf("%-20s | %-12s\n", "kubernetes file", fpath)
+	}
+
+	for _, dpath := range a.InFiles.TerraformModules {
+		fmt.Printf("%-20s | %-12s\n", "terraform module", dpath)
+	}
+
+	for _, fpath := range a.InFiles.TerraformJSONFiles {
+		fmt.Printf("%-20s | %-12s\n", "terraform json file", fpath)
+	}
+
+	if a.TerraformOpts.Workspace != "" {
+		fmt.Printf("%-20s | %-12s\n", "terraform workspace", a.TerraformOpts.Workspace)
+	}
+
+	for _, fpath := range a.InFiles.ThreagileFiles {
+		fmt.Printf("%-20s | %-12s\n", "threagile file", fpath)
+	}
+
+	for _, fpath := range a.InFiles.OTMFiles {
+		fmt.Printf("%-20s | %-12s\n", "otm file", fpath)
+	}
+
 	fmt.Println("-----------------------------------------------------------------------")
 }
@@ -6,16 +6,25 @@ import (
 	"log"
 	"log/slog"
 	"os"
+	"strings"
 
 	"github.com/threatcat-dev/threatcat/internal/changelog"
 	"github.com/threatcat-dev/threatcat/internal/common"
 	"github.com/threatcat-dev/threatcat/internal/dataflowyaml"
 	"github.com/threatcat-dev/threatcat/internal/dockercompose"
+	"github.com/threatcat-dev/threatcat/internal/kubernetes"
 	"github.com/threatcat-dev/threatcat/internal/logging"
 	"github.com/threatcat-dev/threatcat/internal/modelmerger"
+	"github.com/threatcat-dev/threatcat/internal/otm"
+	"github.com/threatcat-dev/threatcat/internal/output"
+	"github.com/threatcat-dev/threatcat/internal/terraform"
+	"github.com/threatcat-dev/threatcat/internal/threagile"
 	"github.com/threatcat-dev/threatcat/internal/threatdragon"
 )
 
+// outputWriters are the output formats that can be chosen with --output and --format
+var outputWriters = output.DefaultRegistry()
+
 func main() {
 	//print logo
 
@@ -68,12 +77,16 @@ func main() {
 	if err != nil {
 		log.Fatalf("Could not handle Docker Image Map Config file: %v", err)
 	}
+	allowedRegistries, err := dockercompose.NewRegistryAllowList(cmd.ConfigFiles.DockerImageMapConfig)
+	if err != nil {
+		log.Fatalf("Could not handle Docker Image Map Config file: %v", err)
+	}
 
 	// set changelog instance
 	cl := changelog.NewChangelog(logger)
 
 	fmt.Println("[3/7] 🔍  Parse and analyze input files")
-	threatModels, err := parseAndAnalyzeInputFiles(cmd.InFiles, dockerImageMap, logger)
+	threatModels, err := parseAndAnalyzeInputFiles(cmd.InFiles, cmd.ComposeOpts, cmd.TerraformOpts, dockerImageMap, allowedRegistries, logger)
 	if err != nil {
 		log.Fatalf("Could not analyze input files")
 	}
@@ -83,6 +96,14 @@ func main() {
 		cmd.InFiles.ThreatDragonFiles...,
 	//TODO commint info for dataflow yaml
 	)
+	for _, project := range composeProjects(nil, cmd.InFiles.ComposeProjects) {
+		InputFiles = append(InputFiles, project...)
+	}
+	InputFiles = append(InputFiles, cmd.InFiles.KubernetesFiles...)
+	InputFiles = append(InputFiles, cmd.InFiles.TerraformModules...)
+	InputFiles = append(InputFiles, cmd.InFiles.TerraformJSONFiles...)
+	InputFiles = append(InputFiles, cmd.InFiles.ThreagileFiles...)
+	InputFiles = append(InputFiles, cmd.InFiles.OTMFiles...)
 
 	for _, file := range InputFiles {
 		if err := cl.AddCommitInfo(file); err != nil {
@@ -95,10 +116,13 @@ func main() {
 	merged := modelMerger.Merge(threatModels)
 
 	fmt.Println("[5/7] 💾  Generating output model")
-	output := threatdragon.NewThreatdragonOutput(cmd.OutFilePath, cl, logger)
-	err = output.Generate(&merged)
+	targets, _ := outputWriters.ParseTargets(cmd.Outputs, cmd.Format) // validated with the other arguments
+	options := output.Options{Changelog: cl, Logger: logger}
+	options.DiagramStrategy, _ = threatdragon.ParseDiagramStrategy(cmd.Diagrams)
+	options.Layout, _ = threatdragon.ParseLayoutStrategy(cmd.Layout)
+	err = outputWriters.Generate(targets, &merged, options)
 	if err != nil {
-		log.Fatalf("Could not generate output threat model to requested filepath: %s err: %v", cmd.OutFilePath, err)
+		log.Fatalf("Could not generate the output threat models: %v", err)
 	}
 
 	// Am Ende: Changelog schreiben
@@ -116,18 +140,120 @@ func main() {
 	fmt.Print("[7/7] ✅  Done!")
 }
 
-// helper to parse and analyze docker compose files
-func parseAndAnalyzeDockerComposeFiles(filePath string, dockerImageMap dockercompose.DockerImageMap, logger *slog.Logger) (*common.ThreatModel, error) {
-	parser := dockercompose.NewDockerComposeParser(filePath, logger)
+// helper to parse and analyze the files of a docker compose project
+func parseAndAnalyzeDockerComposeFiles(filePaths []string, opts composeOptions, dockerImageMap dockercompose.DockerImageMap, allowedRegistries dockercompose.RegistryAllowList, logger *slog.Logger) (*common.ThreatModel, error) {
+	parser := dockercompose.NewDockerComposeProjectParser(filePaths, dockercompose.ProjectOptions{
+		Profiles: opts.Profiles,
+		EnvFiles: opts.EnvFiles,
+	}, logger)
 	parsed, err := parser.ParseDockerComposeYML()
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse DockerCompose file: %s err: %w", filePath, err)
+		return nil, fmt.Errorf("failed to parse DockerCompose files: %v err: %w", filePaths, err)
 	}
-	analyzer := dockercompose.NewDockerComposeAnalyzer(filePath, logger)
+	analyzer := dockercompose.NewDockerComposeAnalyzer(filePaths[0], logger)
+	analyzer.AllowedRegistries = allowedRegistries
 
 	tModel, err := analyzer.Analyze(parsed, dockerImageMap)
 	if err != nil {
-		return nil, fmt.Errorf("failed to analyze DockerCompose file %s err: %w", filePath, err)
+		return nil, fmt.Errorf("failed to analyze DockerCompose files %v err: %w", filePaths, err)
+	}
+
+	return tModel, nil
+}
+
+// composeProjects returns the docker compose projects to analyze. Every single docker compose file is a
+// project of its own. The files of an explicit project keep their order, so later files override earlier ones.
+func composeProjects(filePaths []string, projects []string) [][]string {
+	result := make([][]string, 0, len(filePaths)+len(projects))
+	for _, fpath := range filePaths {
+		result = append(result, []string{fpath})
+	}
+	for _, project := range projects {
+		result = append(result, strings.Split(project, ","))
+	}
+	return result
+}
+
+// helper to parse and analyze kubernetes manifest files
+func parseAndAnalyzeKubernetesFile(filePath string, dockerImageMap dockercompose.DockerImageMap, logger *slog.Logger) (*common.ThreatModel, error) {
+	parser := kubernetes.NewKubernetesParser(filePath, logger)
+	parsed, err := parser.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Kubernetes file: %s err: %w", filePath, err)
+	}
+	analyzer := kubernetes.NewKubernetesAnalyzer(filePath, logger)
+
+	tModel, err := analyzer.Analyze(parsed, dockerImageMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze Kubernetes file %s err: %w", filePath, err)
+	}
+
+	return tModel, nil
+}
+
+// helper to parse and analyze terraform module directories
+func parseAndAnalyzeTerraformModule(dirPath, workspace string, logger *slog.Logger) (*common.ThreatModel, error) {
+	parser := terraform.NewTerraformParser(dirPath, logger)
+	parsed, err := parser.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Terraform module: %s err: %w", dirPath, err)
+	}
+	analyzer := terraform.NewTerraformAnalyzer(workspace, logger)
+
+	tModel, err := analyzer.Analyze(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze Terraform module %s err: %w", dirPath, err)
+	}
+
+	return tModel, nil
+}
+
+// helper to parse and analyze terraform plan and state files
+func parseAndAnalyzeTerraformJSONFile(filePath, workspace string, logger *slog.Logger) (*common.ThreatModel, error) {
+	parser := terraform.NewTerraformJSONParser(filePath, logger)
+	parsed, err := parser.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Terraform JSON file: %s err: %w", filePath, err)
+	}
+	analyzer := terraform.NewTerraformAnalyzer(workspace, logger)
+
+	tModel, err := analyzer.Analyze(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze Terraform JSON file %s err: %w", filePath, err)
+	}
+
+	return tModel, nil
+}
+
+// helper to parse and analyze threagile files
+func parseAndAnalyzeThreagileFile(filePath string, logger *slog.Logger) (*common.ThreatModel, error) {
+	parser := threagile.NewThreagileParser(filePath, logger)
+	parsed, err := parser.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Threagile file: %s err: %w", filePath, err)
+	}
+	analyzer := threagile.NewThreagileAnalyzer(filePath, logger)
+
+	tModel, err := analyzer.Analyze(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze Threagile file %s err: %w", filePath, err)
+	}
+
+	return tModel, nil
+}
+
+// helper to parse and analyze OTM files
+func parseAndAnalyzeOTMFile(filePath string, logger *slog.Logger) (*common.ThreatModel, error) {
+	parser := otm.NewOTMParser(filePath, logger)
+	parsed, err := parser.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OTM file: %s err: %w", filePath, err)
+	}
+	analyzer := otm.NewOTMAnalyzer(filePath, logger)
+
+	tModel, err := analyzer.Analyze(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze OTM file %s err: %w", filePath, err)
 	}
 
 	return tModel, nil
@@ -159,18 +285,74 @@ func parseDataflowYamlFile(filePath string, logger *slog.Logger) (*common.Threat
 }
 
 // Parse/Analyze all input files provided by user
-func parseAndAnalyzeInputFiles(inFiles inputFiles, dockerImageMap dockercompose.DockerImageMap, logger *slog.Logger) ([]common.ThreatModel, error) {
+func parseAndAnalyzeInputFiles(inFiles inputFiles, composeOpts composeOptions, terraformOpts terraformOptions, dockerImageMap dockercompose.DockerImageMap, allowedRegistries dockercompose.RegistryAllowList, logger *slog.Logger) ([]common.ThreatModel, error) {
 	logger = logger.With("package", "main")
 	var threatModels []common.ThreatModel
-	// handle docker compose files
-	for _, dcmpFile := range inFiles.DockerComposeFiles {
-		logger.Info("Parsing and analyzing docker-compose file", "filepath", dcmpFile)
-		tModel, err := parseAndAnalyzeDockerComposeFiles(dcmpFile, dockerImageMap, logger)
+	// handle docker compose projects
+	for _, dcmpFiles := range composeProjects(inFiles.DockerComposeFiles, inFiles.ComposeProjects) {
+		logger.Info("Parsing and analyzing docker-compose project", "filepaths", dcmpFiles)
+		tModel, err := parseAndAnalyzeDockerComposeFiles(dcmpFiles, composeOpts, dockerImageMap, allowedRegistries, logger)
+		if err != nil {
+			return nil, fmt.Errorf("could not analyze DockerCompose project: %v err: %v", dcmpFiles, err)
+		}
+		setInputFile(tModel, dcmpFiles[0])
+		threatModels = append(threatModels, *tModel)
+		logger.Info("Successfully parsed and  analyzed docker-compose project", "filepaths", dcmpFiles)
+	}
+	// handle kubernetes manifest files
+	for _, k8sFile := range inFiles.KubernetesFiles {
+		logger.Info("Parsing and analyzing Kubernetes file", "filepath", k8sFile)
+		tModel, err := parseAndAnalyzeKubernetesFile(k8sFile, dockerImageMap, logger)
+		if err != nil {
+			return nil, fmt.Errorf("could not analyze Kubernetes File: %s err: %v", k8sFile, err)
+		}
+		setInputFile(tModel, k8sFile)
+		threatModels = append(threatModels, *tModel)
+		logger.Info("Successfully parsed and analyzed Kubernetes file", "filepath", k8sFile)
+	}
+	// handle terraform module directories
+	for _, tfModule := range inFiles.TerraformModules {
+		logger.Info("Parsing and analyzing Terraform module", "dirpath", tfModule)
+		tModel, err := parseAndAnalyzeTerraformModule(tfModule, terraformOpts.Workspace, logger)
+		if err != nil {
+			return nil, fmt.Errorf("could not analyze Terraform module: %s err: %v", tfModule, err)
+		}
+		setInputFile(tModel, tfModule)
+		threatModels = append(threatModels, *tModel)
+		logger.Info("Successfully parsed and analyzed Terraform module", "dirpath", tfModule)
+	}
+	// handle terraform plan and state files
+	for _, tfFile := range inFiles.TerraformJSONFiles {
+		logger.Info("Parsing and analyzing Terraform JSON file", "filepath", tfFile)
+		tModel, err := parseAndAnalyzeTerraformJSONFile(tfFile, terraformOpts.Workspace, logger)
+		if err != nil {
+			return nil, fmt.Errorf("could not analyze Terraform JSON file: %s err: %v", tfFile, err)
+		}
+		setInputFile(tModel, tfFile)
+		threatModels = append(threatModels, *tModel)
+		logger.Info("Successfully parsed and analyzed Terraform JSON file", "filepath", tfFile)
+	}
+	// handle threagile files
+	for _, tgFile := range inFiles.ThreagileFiles {
+		logger.Info("Parsing and analyzing Threagile file", "filepath", tgFile)
+		tModel, err := parseAndAnalyzeThreagileFile(tgFile, logger)
 		if err != nil {
-			return nil, fmt.Errorf("could not analyze DockerCompose File: %s err: %v", dcmpFile, err)
+			return nil, fmt.Errorf("could not analyze Threagile file: %s err: %v", tgFile, err)
 		}
+		setInputFile(tModel, tgFile)
 		threatModels = append(threatModels, *tModel)
-		logger.Info("Successfully parsed and  analyzed docker-compose file", "filepath", dcmpFile)
+		logger.Info("Successfully parsed and analyzed Threagile file", "filepath", tgFile)
+	}
+	// handle otm files
+	for _, otmFile := range inFiles.OTMFiles {
+		logger.Info("Parsing and analyzing OTM file", "filepath", otmFile)
+		tModel, err := parseAndAnalyzeOTMFile(otmFile, logger)
+		if err != nil {
+			return nil, fmt.Errorf("could not analyze OTM file: %s err: %v", otmFile, err)
+		}
+		setInputFile(tModel, otmFile)
+		threatModels = append(threatModels, *tModel)
+		logger.Info("Successfully parsed and analyzed OTM file", "filepath", otmFile)
 	}
 	// handle threat dragon files
 	for _, tdFile := range inFiles.ThreatDragonFiles {
@@ -190,6 +372,7 @@ func parseAndAnalyzeInputFiles(inFiles inputFiles, dockerImageMap dockercompose.
 		if err != nil {
 			return nil, err
 		}
+		setInputFile(tModel, dfyFile)
 		threatModels = append(threatModels, *tModel)
 		logger.Info("Successfully parsed Dataflow yaml file", "filepath", dfyFile)
 	}
@@ -201,3 +384,20 @@ func parseAndAnalyzeInputFiles(inFiles inputFiles, dockerImageMap dockercompose.
 
 	return threatModels, nil
 }
+
+// setInputFile records the input file of the assets and dataflows, so that the output can draw one diagram per input file.
+// The assets of Threat Dragon files already have their diagram and are not touched.
+func setInputFile(model *common.ThreatModel, filePath string) {
+	for k := range model.Assets {
+		if model.Assets[k].Extra == nil {
+			model.Assets[k].Extra = make(map[string]any)
+		}
+		model.Assets[k].Extra["InputFile"] = filePath
+	}
+	for k := range model.DataFlows {
+		if model.DataFlows[k].Extra == nil {
+			model.DataFlows[k].Extra = make(map[string]any)
+		}
+		model.DataFlows[k].Extra["InputFile"] = filePath
+	}
+}
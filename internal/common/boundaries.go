@@ -0,0 +1,72 @@
+package common
+
+import "slices"
+
+// BoundaryParents nests trust boundaries, given as the asset IDs of each boundary, for formats that allow a boundary
+// in only one parent. It returns the index of the parent of each boundary, or -1. The parent is the smallest boundary
+// that contains all assets of the boundary. Of boundaries with the same assets, each is nested into the one before it.
+// Empty boundaries are not nested.
+func BoundaryParents(members [][]string) []int {
+	parents := make([]int, len(members))
+	for i := range members {
+		parents[i] = -1
+		if len(members[i]) == 0 {
+			continue
+		}
+		for j := range members {
+			if i == j || len(members[j]) < len(members[i]) || (len(members[j]) == len(members[i]) && j > i) {
+				continue
+			}
+			if !isSubset(members[i], members[j]) {
+				continue
+			}
+			if parents[i] < 0 || len(members[j]) < len(members[parents[i]]) ||
+				(len(members[j]) == len(members[parents[i]]) && j > parents[i]) {
+				parents[i] = j
+			}
+		}
+	}
+	return parents
+}
+
+func isSubset(subset, set []string) bool {
+	for _, id := range subset {
+		if !slices.Contains(set, id) {
+			return false
+		}
+	}
+	return true
+}
+
+// InnermostBoundary returns the index of the smallest boundary that contains the asset, or -1, for formats that allow
+// an asset in only one boundary. It also returns the other boundaries with the asset that are not around the innermost
+// one, which the asset is lost from.
+func InnermostBoundary(members [][]string, parents []int, assetID string) (int, []int) {
+	innermost := -1
+	containing := make([]int, 0)
+	for i := range members {
+		if !slices.Contains(members[i], assetID) {
+			continue
+		}
+		containing = append(containing, i)
+		// boundaries with the same assets are nested in their order, so the last one is the innermost
+		if innermost < 0 || len(members[i]) <= len(members[innermost]) {
+			innermost = i
+		}
+	}
+	if innermost < 0 {
+		return -1, nil
+	}
+
+	ancestors := []int{}
+	for i := innermost; i >= 0; i = parents[i] {
+		ancestors = append(ancestors, i)
+	}
+	others := make([]int, 0)
+	for _, i := range containing {
+		if !slices.Contains(ancestors, i) {
+			others = append(others, i)
+		}
+	}
+	return innermost, others
+}
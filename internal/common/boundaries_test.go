@@ -0,0 +1,40 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoundaryParents(t *testing.T) {
+	members := [][]string{
+		{"a", "b", "c", "d"}, // vpc
+		{"a", "b"},           // subnet
+		{"a"},                // security group
+		{"a", "b"},           // the same subnet again
+		{"c", "e"},           // overlaps the vpc
+		{},                   // empty
+	}
+	assert.Equal(t, []int{-1, 0, 3, 1, -1, -1}, BoundaryParents(members))
+}
+
+func TestInnermostBoundary(t *testing.T) {
+	members := [][]string{
+		{"web", "api", "db"}, // project
+		{"web", "api"},       // frontend
+		{"api", "db"},        // backend
+	}
+	parents := BoundaryParents(members)
+
+	innermost, others := InnermostBoundary(members, parents, "web")
+	assert.Equal(t, 1, innermost)
+	assert.Empty(t, others)
+
+	// the api is in both networks, but only the boundaries around one of them are kept
+	innermost, others = InnermostBoundary(members, parents, "api")
+	assert.Equal(t, 2, innermost)
+	assert.Equal(t, []int{1}, others)
+
+	innermost, _ = InnermostBoundary(members, parents, "cache")
+	assert.Equal(t, -1, innermost)
+}
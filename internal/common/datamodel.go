@@ -25,6 +25,8 @@ type Asset struct {
 	Threats     []Threat
 	Source      DataSource
 	Extra       map[string]any
+	// StoresCredentials marks data stores that hold secrets such as passwords, keys or tokens
+	StoresCredentials bool
 }
 
 type AssetType int
@@ -35,6 +37,7 @@ const (
 	AssetTypeDatabase
 	AssetTypeWebserver
 	AssetTypeInfrastructure
+	AssetTypeExternalEntity
 	//AssetTypeDataFlow
 )
 
@@ -46,6 +49,10 @@ func (assetType AssetType) String() string {
 		return "AssetTypeDatabase"
 	case AssetTypeWebserver:
 		return "AssetTypeWebserver"
+	case AssetTypeInfrastructure:
+		return "AssetTypeInfrastructure"
+	case AssetTypeExternalEntity:
+		return "AssetTypeExternalEntity"
 	}
 	return "AssetTypeUnknown"
 }
@@ -57,6 +64,10 @@ const (
 	DataSourceThreatDragon
 	DataSourceDockerCompose
 	DataSourceMerged
+	DataSourceKubernetes
+	DataSourceTerraform
+	DataSourceThreagile
+	DataSourceOTM
 )
 
 func (dataSource DataSource) ShortString() string {
@@ -69,6 +80,14 @@ func (dataSource DataSource) ShortString() string {
 		return "Docker Compose"
 	case DataSourceMerged:
 		return "Merged"
+	case DataSourceKubernetes:
+		return "Kubernetes"
+	case DataSourceTerraform:
+		return "Terraform"
+	case DataSourceThreagile:
+		return "Threagile"
+	case DataSourceOTM:
+		return "OTM"
 	}
 	return "Unknown"
 }
@@ -216,19 +235,28 @@ func ThreatStatus(status string) Status {
 
 type DataFlow struct {
 	ID            string
-	Name          string `yaml:"name"`
-	Protocol      string `yaml:"protocol"`
-	Encrypted     bool   `yaml:"encrypted"`
-	PublicNetwork bool   `yaml:"publicnetwork"`
-	Source        string `yaml:"source"`
-	Target        string `yaml:"target"`
-	Bidirectional bool   `yaml:"bidirectional"`
+	Name          string   `yaml:"name"`
+	Protocol      string   `yaml:"protocol"`
+	Encrypted     bool     `yaml:"encrypted"`
+	PublicNetwork bool     `yaml:"publicnetwork"`
+	Source        string   `yaml:"source"`
+	Target        string   `yaml:"target"`
+	Bidirectional bool     `yaml:"bidirectional"`
+	Threats       []Threat `yaml:"-"`
+	// Inferred marks dataflows that were guessed by an analyzer instead of being declared explicitly.
+	// Explicitly declared dataflows take precedence over inferred ones.
+	Inferred bool `yaml:"-"`
+	// DataSource is the input the dataflow was read from. Source and Target name the connected assets.
+	DataSource DataSource     `yaml:"-"`
+	Extra      map[string]any `yaml:"-"`
 }
 
 type TrustBoundary struct {
 	ID              string
 	DisplayName     string
 	ContainedAssets []string
-	Source          DataSource
-	Extra           map[string]any
+	// CrossingDataFlows are the IDs of the dataflows that cross a boundary drawn as a line instead of a box.
+	CrossingDataFlows []string
+	Source            DataSource
+	Extra             map[string]any
 }
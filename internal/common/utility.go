@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"fmt"
 )
 
 var ErrKeyNotFound = errors.New("the map does not conatin the requested key")
@@ -63,3 +64,27 @@ func GenerateIDHash(filePath, name string) string {
 	hasher.Write([]byte(filePath + name))
 	return hex.EncodeToString(hasher.Sum(nil))[:MaxIDHashLength]
 }
+
+// UUIDFormat formats an ID hash like a UUID, which is the format ThreatDragon uses for threat IDs
+func UUIDFormat(id string) string {
+	if len(id) != MaxIDHashLength {
+		return id
+	}
+	return fmt.Sprintf("%s-%s-%s-%s-%s", id[0:8], id[8:12], id[12:16], id[16:20], id[20:32])
+}
+
+// EndpointAsset returns the asset with the name of a dataflow endpoint. Several input files can have assets with the
+// same name, so an asset of the input file of the dataflow is preferred.
+func EndpointAsset(assets []Asset, dataflow DataFlow, name string) Asset {
+	var found Asset
+	file := GetOr(dataflow.Extra, "InputFile", "")
+	for _, asset := range assets {
+		if asset.DisplayName != name {
+			continue
+		}
+		if found.ID == "" || (file != "" && GetOr(asset.Extra, "InputFile", "") == file) {
+			found = asset
+		}
+	}
+	return found
+}
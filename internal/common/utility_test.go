@@ -41,3 +41,18 @@ func TestGenerateIDHash(t *testing.T) {
 		})
 	}
 }
+
+func TestUUIDFormat(t *testing.T) {
+	assert.Equal(t, "0123abcd-4567-89ef-0123-456789abcdef", UUIDFormat("0123abcd456789ef0123456789abcdef"))
+	assert.Equal(t, "short", UUIDFormat("short"))
+}
+
+func TestEndpointAsset(t *testing.T) {
+	assets := []Asset{
+		{ID: "a", DisplayName: "web", Extra: map[string]any{"InputFile": "a.yml"}},
+		{ID: "b", DisplayName: "web", Extra: map[string]any{"InputFile": "b.yml"}},
+	}
+	assert.Equal(t, "b", EndpointAsset(assets, DataFlow{Extra: map[string]any{"InputFile": "b.yml"}}, "web").ID)
+	assert.Equal(t, "a", EndpointAsset(assets, DataFlow{}, "web").ID)
+	assert.Empty(t, EndpointAsset(assets, DataFlow{}, "db").ID)
+}
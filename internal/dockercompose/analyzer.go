@@ -10,9 +10,13 @@ import (
 	"github.com/threatcat-dev/threatcat/internal/common"
 )
 
-// DockerComposeAnalyzer analyzes Docker Compose files
+// DockerComposeAnalyzer analyzes Docker Compose projects.
+// The file path is the first file of the project and is part of all generated IDs,
+// so override files do not change the IDs of the assets.
+// Images from registries that are not on the allow-list are reported as threats, unless the list is empty.
 type DockerComposeAnalyzer struct {
 	DockerComposeFilePath string
+	AllowedRegistries     RegistryAllowList
 	logger                *slog.Logger
 }
 
@@ -53,13 +57,15 @@ func (a *DockerComposeAnalyzer) Analyze(proj *types.Project, imageMap DockerImag
 		// Generate a unique ID for the asset by hashing the file path and service name
 		idHash := common.GenerateIDHash(a.DockerComposeFilePath, service.Name)
 		assetIDs = append(assetIDs, idHash)
+		imageThreats, imageExtra := a.imageThreats(service)
 		// Create a new asset with the generated ID and service name
 		asset := common.Asset{
 			ID:          idHash,
 			DisplayName: service.Name,
-			Type:        imageMap.determineAssetType(service.Image, a.logger),
+			Type:        imageMap.DetermineAssetType(service.Image, a.logger),
+			Threats:     slices.Concat(a.hardeningThreats(service), imageThreats, a.secretThreats(proj, service)),
 			Source:      common.DataSourceDockerCompose,
-			Extra:       map[string]any{},
+			Extra:       imageExtra,
 		}
 		logger.Debug("Created a new instance of Asset for docker compose service", "service.Name", service.Name, "asset", asset)
 		// Add the asset to the list of assets
@@ -88,12 +94,23 @@ func (a *DockerComposeAnalyzer) Analyze(proj *types.Project, imageMap DockerImag
 		})
 	}
 
-	dataflows, err := a.parseDataFlows()
+	storeAssets, storeFlows := a.storageAssets(proj)
+	model.Assets = append(model.Assets, storeAssets...)
+
+	dataflows, err := a.parseDataFlows(proj.ComposeFiles...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse dataflows: %w", err)
 	}
 
-	model.DataFlows = dataflows
+	model.DataFlows = mergeInferredDataFlows(dataflows, a.inferDataFlows(proj))
+	model.DataFlows = append(model.DataFlows, storeFlows...)
+
+	publicFlows := a.publishedPortDataFlows(proj)
+	if len(publicFlows) > 0 {
+		logger.Debug("Services publish ports, adding the internet as entry point", "publishedPortCount", len(publicFlows))
+		addInternet(&model)
+		model.DataFlows = append(model.DataFlows, publicFlows...)
+	}
 
 	logger.Debug("Docker compose analysis finished", "assetCount", len(model.Assets))
 
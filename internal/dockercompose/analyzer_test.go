@@ -8,14 +8,22 @@ import (
 
 	"github.com/compose-spec/compose-go/v2/cli"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/threatcat-dev/threatcat/internal/common"
 )
 
 // List of test assets found in the docker-compose-for-test.yml file
 var testAssets = []common.Asset{
-	{ID: "hash", DisplayName: "db", Type: common.AssetTypeDatabase, Extra: map[string]any{}},
-	{ID: "hash", DisplayName: "db2", Type: common.AssetTypeDatabase, Extra: map[string]any{}},
-	{ID: "hash", DisplayName: "web", Type: common.AssetTypeWebserver, Extra: map[string]any{}},
+	{ID: "hash", DisplayName: "db", Type: common.AssetTypeDatabase, Extra: map[string]any{
+		"DockerImageRegistry": "docker.io", "DockerImageRepository": "library/postgres", "DockerImageTag": "latest",
+	}},
+	{ID: "hash", DisplayName: "db2", Type: common.AssetTypeDatabase, Extra: map[string]any{
+		"DockerImageRegistry": "docker.hub", "DockerImageRepository": "postgres", "DockerImageTag": "latest",
+	}},
+	{ID: "hash", DisplayName: "web", Type: common.AssetTypeWebserver, Extra: map[string]any{
+		"DockerImageRegistry": "docker.io", "DockerImageRepository": "library/nginx", "DockerImageTag": "latest",
+	}},
+	{ID: "hash", DisplayName: "Internet / External User", Type: common.AssetTypeExternalEntity, Extra: map[string]any{}},
 }
 
 // TestAnalyzer tests the Analyze method of DockerComposeAnalyzer
@@ -80,6 +88,7 @@ func TestAnalyzer(t *testing.T) {
 			})
 
 			// Compare the result with the expected assets
+			assert.Len(t, result.Assets, len(tt.expected))
 			for i, asset := range result.Assets {
 				// Do not check ID because it is hashed
 				assert.Equal(t, tt.expected[i].DisplayName, asset.DisplayName)
@@ -90,7 +99,7 @@ func TestAnalyzer(t *testing.T) {
 	}
 }
 
-// TestDetermineAssetType tests the determineAssetType function
+// TestDetermineAssetType tests the DetermineAssetType function
 func TestDetermineAssetType(t *testing.T) {
 	// Create a new DockerImageMap instance
 	// This should be initialized with the internal image map
@@ -129,7 +138,7 @@ func TestDetermineAssetType(t *testing.T) {
 	// Iterate over each test case
 	for _, tt := range tests {
 		t.Run(tt.image, func(t *testing.T) {
-			result := dockerImageMap.determineAssetType(tt.image, slog.Default())
+			result := dockerImageMap.DetermineAssetType(tt.image, slog.Default())
 			assert.Equal(t, tt.expected, result)
 		})
 	}
@@ -193,3 +202,47 @@ func TestDetermineAssetTypeWithNoDockerImageMap(t *testing.T) {
 	_, err := analyzer.Analyze(nil, nil)
 	assert.Error(t, err, "Expected an error when DockerImageMap is nil")
 }
+
+// TestAnalyzeProjectWithOverrideFile tests that override files do not change the IDs of the assets
+// and that dataflows are read from all files of the project
+func TestAnalyzeProjectWithOverrideFile(t *testing.T) {
+	const baseFile = "testdata/project/docker-compose.yml"
+	dockerImageMap, err := NewDockerImageMap("")
+	require.NoError(t, err)
+
+	analyze := func(files ...string) *common.ThreatModel {
+		project, err := NewDockerComposeProjectParser(files, ProjectOptions{}, slog.Default()).ParseDockerComposeYML()
+		require.NoError(t, err)
+		model, err := NewDockerComposeAnalyzer(files[0], slog.Default()).Analyze(project, dockerImageMap)
+		require.NoError(t, err)
+		return model
+	}
+	assetID := func(model *common.ThreatModel, name string) string {
+		for _, asset := range model.Assets {
+			if asset.DisplayName == name {
+				return asset.ID
+			}
+		}
+		return ""
+	}
+
+	base := analyze(baseFile)
+	prod := analyze(baseFile, "testdata/project/docker-compose.prod.yml")
+
+	assert.Equal(t, common.GenerateIDHash(baseFile, "web"), assetID(base, "web"))
+	assert.Equal(t, assetID(base, "web"), assetID(prod, "web"))
+	assert.Equal(t, assetID(base, "api"), assetID(prod, "api"))
+	assert.Equal(t, common.GenerateIDHash(baseFile, "db"), assetID(prod, "db"))
+
+	// the override file redeclares the web -> api flow and adds the api -> db flow
+	declared := make(map[string]common.DataFlow)
+	for _, flow := range prod.DataFlows {
+		if !flow.Inferred && !flow.PublicNetwork {
+			declared[flow.Name] = flow
+		}
+	}
+	require.Len(t, declared, 2)
+	assert.Equal(t, "https", declared["Requests"].Protocol)
+	assert.True(t, declared["Requests"].Encrypted)
+	assert.Equal(t, "db", declared["Queries"].Target)
+}
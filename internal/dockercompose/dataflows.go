@@ -2,31 +2,66 @@ package dockercompose
 
 import (
 	"bufio"
+	"fmt"
 	"log/slog"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/threatcat-dev/threatcat/internal/common"
 )
 
-// ParseDataFlows loads a docker-compose file and extracts comment-based dataflows.
-// Malformed lines are skipped (with debug logging).
-func (a *DockerComposeAnalyzer) parseDataFlows() ([]common.DataFlow, error) {
-	lines, err := a.readComments(a.DockerComposeFilePath)
-	if err != nil {
-		return nil, err
+// ParseDataFlows loads the docker-compose files of a project and extracts comment-based dataflows.
+// Without files, the file of the analyzer is read. A dataflow declared again in a later file with the same
+// endpoints and name replaces the earlier one. Malformed lines are skipped (with debug logging).
+func (a *DockerComposeAnalyzer) parseDataFlows(files ...string) ([]common.DataFlow, error) {
+	if len(files) == 0 {
+		files = []string{a.DockerComposeFilePath}
 	}
 
 	var flows []common.DataFlow
-	for _, l := range lines {
-		df, ok := a.parseSingleDataFlow(l)
-		if ok {
-			flows = append(flows, df)
+	for _, file := range files {
+		lines, err := a.readComments(file)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, l := range lines {
+			df, ok := a.parseSingleDataFlow(l)
+			if !ok {
+				continue
+			}
+			index := slices.IndexFunc(flows, func(f common.DataFlow) bool {
+				return f.Source == df.Source && f.Target == df.Target && f.Name == df.Name
+			})
+			if index >= 0 {
+				flows[index] = df
+			} else {
+				flows = append(flows, df)
+			}
 		}
 	}
+	a.uniqueDataFlowIDs(flows)
 	return flows, nil
 }
 
+// uniqueDataFlowIDs gives every dataflow its own ID. The ID of a named dataflow only depends on its name,
+// so that it stays the same when the endpoints are renamed. Unnamed dataflows and further dataflows with
+// an already used name get an ID of their endpoints instead, so they are not merged with each other later.
+func (a *DockerComposeAnalyzer) uniqueDataFlowIDs(flows []common.DataFlow) {
+	used := make(map[string]bool)
+	for i := range flows {
+		if flows[i].ID != "" && used[flows[i].ID] {
+			a.logger.Warn("Several dataflows have the same name", "name", flows[i].Name, "source", flows[i].Source, "target", flows[i].Target)
+			flows[i].ID = ""
+		}
+		if flows[i].ID == "" {
+			flows[i].ID = common.GenerateIDHash(a.DockerComposeFilePath, fmt.Sprintf("dataflow:%s:%s:%s", flows[i].Source, flows[i].Target, flows[i].Name))
+		}
+		used[flows[i].ID] = true
+	}
+}
+
 // readComments keeps only lines containing "#".
 func (a *DockerComposeAnalyzer) readComments(path string) ([]string, error) {
 	f, err := os.Open(path)
@@ -82,6 +117,7 @@ func (a *DockerComposeAnalyzer) parseSingleDataFlow(line string) (common.DataFlo
 		Source:        asset1,
 		Target:        asset2,
 		Bidirectional: bidirectional,
+		DataSource:    common.DataSourceDockerCompose,
 	}
 
 	a.parseMeta(meta, &df)
@@ -137,3 +137,49 @@ func TestParseDataFlowsFromTestFile(t *testing.T) {
 	assert.False(t, f.PublicNetwork)
 	assert.Equal(t, true, f.Bidirectional)
 }
+
+func TestParseDataFlowsUnnamed(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "docker-compose.yml")
+	content := `services:
+  #(web)-->(api)
+  #(api)-->(db);;postgresql;Unencrypted;Private
+  web:
+    image: nginx
+`
+	assert.NoError(t, os.WriteFile(fpath, []byte(content), 0600))
+
+	an := &DockerComposeAnalyzer{DockerComposeFilePath: fpath, logger: slog.Default()}
+	flows, err := an.parseDataFlows()
+	assert.NoError(t, err)
+	assert.Len(t, flows, 2)
+	assert.Equal(t, "api", flows[0].Target)
+	assert.Equal(t, "db", flows[1].Target)
+	assert.NotEmpty(t, flows[0].ID)
+	assert.NotEqual(t, flows[0].ID, flows[1].ID)
+}
+
+func TestParseDataFlowsSameName(t *testing.T) {
+	tmp := t.TempDir()
+	base := filepath.Join(tmp, "docker-compose.yml")
+	override := filepath.Join(tmp, "docker-compose.override.yml")
+	assert.NoError(t, os.WriteFile(base, []byte(`services:
+  #(web)-->(api);Requests;http;Unencrypted;Private
+  #(api)-->(db);Requests;postgresql;Unencrypted;Private
+`), 0600))
+	assert.NoError(t, os.WriteFile(override, []byte(`services:
+  #(web)-->(api);Requests;https;Encrypted;Private
+`), 0600))
+
+	an := &DockerComposeAnalyzer{DockerComposeFilePath: base, logger: slog.Default()}
+	flows, err := an.parseDataFlows(base, override)
+	assert.NoError(t, err)
+	assert.Len(t, flows, 2)
+
+	// the override file only replaces the flow with the same endpoints
+	assert.Equal(t, "api", flows[0].Target)
+	assert.Equal(t, "https", flows[0].Protocol)
+	assert.Equal(t, common.GenerateIDHash(base, "Requests"), flows[0].ID)
+	assert.Equal(t, "db", flows[1].Target)
+	assert.Equal(t, "postgresql", flows[1].Protocol)
+	assert.NotEqual(t, flows[0].ID, flows[1].ID)
+}
@@ -49,6 +49,19 @@ func mergeDockerImageMaps(internalImageMap DockerImageMap, externalImageMap Dock
 	return nil
 }
 
+// NewRegistryAllowList reads the allowed registries from the Docker image map configuration.
+// Without a configPath the list is empty and images from all registries are allowed.
+func NewRegistryAllowList(configPath string) (RegistryAllowList, error) {
+	if configPath == "" {
+		return RegistryAllowList{}, nil
+	}
+	config, err := readConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Docker image map config: %w", err)
+	}
+	return RegistryAllowList(config.Registries), nil
+}
+
 // readDockerImageMapConfig reads the Docker image map configuration from a YAML file.
 // It returns a DockerImageMap containing the images and their corresponding asset types.
 func readDockerImageMapConfig(configFilePath string) (DockerImageMap, error) {
@@ -70,8 +83,9 @@ func readDockerImageMapConfig(configFilePath string) (DockerImageMap, error) {
 	return result, nil
 }
 
-// determineAssetType determines the asset type based on the service image
-func (m DockerImageMap) determineAssetType(image string, logger *slog.Logger) common.AssetType {
+// DetermineAssetType determines the asset type based on the container image.
+// It is exported so that other container based inputs can reuse the image classification.
+func (m DockerImageMap) DetermineAssetType(image string, logger *slog.Logger) common.AssetType {
 	logger = logger.With("sub-component", "DockerImageMap")
 	imageName := getImageName(image)
 	logger.Debug("Attempting to determine asset type", "image", image, "extractedName", imageName)
@@ -131,12 +145,13 @@ func readConfig(configFilePath string) (*DockerImageConfig, error) {
 }
 
 // DockerImageConfig represents the structure of the Docker image configuration file
-// It contains lists of images categorized by their asset types
+// It contains lists of images categorized by their asset types and the registries images may be pulled from
 type DockerImageConfig struct {
 	Applications   []string `yaml:"applications"`
 	Databases      []string `yaml:"databases"`
 	Webservers     []string `yaml:"webservers"`
 	Infrastructure []string `yaml:"infrastructure"`
+	Registries     []string `yaml:"registries"`
 }
 
 // internalImageMap is a predefined map of Docker images to their asset types.
@@ -96,6 +96,7 @@ func TestReadConfig(t *testing.T) {
 	assert.ElementsMatch(t, []string{"mydb"}, config.Databases)
 	assert.ElementsMatch(t, []string{"myweb"}, config.Webservers)
 	assert.ElementsMatch(t, []string{"myinfra"}, config.Infrastructure)
+	assert.ElementsMatch(t, []string{"docker.io", "ghcr.io/myorg"}, config.Registries)
 }
 
 // TestReadConfigFile checks reading a YAML config file and parsing its sections correctly.
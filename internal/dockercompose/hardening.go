@@ -0,0 +1,171 @@
+package dockercompose
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// dockerSocket is the path of the socket that gives full control over the docker daemon
+const dockerSocket = "/var/run/docker.sock"
+
+// hardeningCheck describes a risky service setting and the threat it causes.
+// The check returns whether the setting is present and an optional detail that is added to the title.
+type hardeningCheck struct {
+	rule        string
+	title       string
+	threatType  common.ThreatType
+	severity    string
+	description string
+	mitigation  string
+	check       func(service types.ServiceConfig) (bool, string)
+}
+
+// hardeningChecks are the checks applied to every service, in the order the threats are added
+var hardeningChecks = []hardeningCheck{
+	{
+		rule:        "privileged",
+		title:       "Container runs privileged",
+		threatType:  common.ElevationOfPrivilege,
+		severity:    "High",
+		description: "The container runs with 'privileged: true'. It has all capabilities and access to the devices of the host, so a compromise of the container is a compromise of the host.",
+		mitigation:  "Remove 'privileged: true'. Grant only the capabilities and devices the service needs.",
+		check: func(service types.ServiceConfig) (bool, string) {
+			return service.Privileged, ""
+		},
+	},
+	{
+		rule:        "cap_add",
+		title:       "Container has additional capabilities",
+		threatType:  common.ElevationOfPrivilege,
+		severity:    "Medium",
+		description: "The container adds Linux capabilities with 'cap_add'. Capabilities like SYS_ADMIN or NET_ADMIN allow an attacker to escape the container or to manipulate the host.",
+		mitigation:  "Remove capabilities that are not needed and drop all others with 'cap_drop: [ALL]'.",
+		check: func(service types.ServiceConfig) (bool, string) {
+			return len(service.CapAdd) > 0, strings.Join(service.CapAdd, ", ")
+		},
+	},
+	{
+		rule:        "network_mode_host",
+		title:       "Container uses the host network",
+		threatType:  common.InformationDisclosure,
+		severity:    "Medium",
+		description: "The container runs with 'network_mode: host'. It is not isolated from the network of the host and can reach services that only listen on the host, e.g. on localhost.",
+		mitigation:  "Use a compose network and publish only the ports that are needed.",
+		check: func(service types.ServiceConfig) (bool, string) {
+			return service.NetworkMode == "host", ""
+		},
+	},
+	{
+		rule:        "pid_host",
+		title:       "Container uses the host PID namespace",
+		threatType:  common.InformationDisclosure,
+		severity:    "Medium",
+		description: "The container runs with 'pid: host'. It can see all processes of the host including their arguments and environment, and can send signals to them.",
+		mitigation:  "Remove 'pid: host'.",
+		check: func(service types.ServiceConfig) (bool, string) {
+			return service.Pid == "host", ""
+		},
+	},
+	{
+		rule:        "docker_socket",
+		title:       "Container mounts the docker socket",
+		threatType:  common.ElevationOfPrivilege,
+		severity:    "High",
+		description: "The container mounts " + dockerSocket + ". Access to the socket allows to start privileged containers and is equivalent to root access on the host.",
+		mitigation:  "Do not mount the docker socket. If the service needs the docker API, use a proxy that only allows the required calls.",
+		check: func(service types.ServiceConfig) (bool, string) {
+			return slices.ContainsFunc(service.Volumes, func(v types.ServiceVolumeConfig) bool {
+				return v.Type == types.VolumeTypeBind && v.Source == dockerSocket
+			}), ""
+		},
+	},
+	{
+		rule:        "user",
+		title:       "Container user is not set",
+		threatType:  common.ElevationOfPrivilege,
+		severity:    "Low",
+		description: "The service does not set 'user'. Unless the image defines a user, the processes of the container run as root.",
+		mitigation:  "Set 'user' to an unprivileged user and group, e.g. 'user: 1000:1000'.",
+		check: func(service types.ServiceConfig) (bool, string) {
+			return service.User == "", ""
+		},
+	},
+	{
+		rule:        "read_only",
+		title:       "Container file system is writable",
+		threatType:  common.Tampering,
+		severity:    "Low",
+		description: "The service does not set 'read_only: true'. An attacker can modify the files of the container, e.g. to persist or to replace binaries.",
+		mitigation:  "Set 'read_only: true' and mount writable volumes or tmpfs only where the service writes data.",
+		check: func(service types.ServiceConfig) (bool, string) {
+			return !service.ReadOnly, ""
+		},
+	},
+	{
+		rule:        "resource_limits",
+		title:       "Container has no resource limits",
+		threatType:  common.DenialOfService,
+		severity:    "Low",
+		description: "The service limits neither memory nor CPU. A single container can exhaust the resources of the host and starve all other services.",
+		mitigation:  "Set 'mem_limit' and 'cpus' or 'deploy.resources.limits'.",
+		check: func(service types.ServiceConfig) (bool, string) {
+			return !hasResourceLimits(service), ""
+		},
+	},
+}
+
+// hardeningThreats checks the settings of a service and returns a threat for every risky setting
+func (a *DockerComposeAnalyzer) hardeningThreats(service types.ServiceConfig) []common.Threat {
+	return a.checkThreats(service, hardeningChecks)
+}
+
+// checkThreats applies the checks to a service and returns a threat for every check that found something.
+// The IDs of the threats only depend on the service and the check, so that the threats of an
+// existing model are recognized again.
+func (a *DockerComposeAnalyzer) checkThreats(service types.ServiceConfig, checks []hardeningCheck) []common.Threat {
+	threats := make([]common.Threat, 0)
+	for _, hc := range checks {
+		found, detail := hc.check(service)
+		if !found {
+			continue
+		}
+
+		title := hc.title
+		if detail != "" {
+			title = fmt.Sprintf("%s (%s)", title, detail)
+		}
+		id := common.GenerateIDHash(a.DockerComposeFilePath, fmt.Sprintf("threat:%s:%s", service.Name, hc.rule))
+		a.logger.Debug("Found risky service setting", "service", service.Name, "rule", hc.rule)
+
+		threats = append(threats, common.Threat{
+			InternalID:  id,
+			ID:          common.UUIDFormat(id),
+			Title:       title,
+			Status:      common.Open,
+			Severity:    hc.severity,
+			Type:        hc.threatType,
+			Description: hc.description,
+			Mitigation:  hc.mitigation,
+			ModelType:   common.STRIDE,
+			Source:      common.DataSourceDockerCompose,
+			MapIndex:    -1,
+		})
+	}
+	return threats
+}
+
+// hasResourceLimits checks if the memory or CPU usage of a service is limited
+func hasResourceLimits(service types.ServiceConfig) bool {
+	if service.MemLimit > 0 || service.CPUS > 0 {
+		return true
+	}
+	if service.Deploy != nil && service.Deploy.Resources.Limits != nil {
+		limits := service.Deploy.Resources.Limits
+		return limits.MemoryBytes > 0 || limits.NanoCPUs > 0
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package dockercompose
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+func TestHardeningThreats(t *testing.T) {
+	const filePath = "testdata/docker-compose-hardening.yml"
+	an := NewDockerComposeAnalyzer(filePath, slog.Default())
+	project := loadTestProject(t, filePath)
+
+	threats := an.hardeningThreats(project.Services["agent"])
+
+	expected := []struct {
+		rule, title string
+		threatType  common.ThreatType
+		severity    string
+	}{
+		{"privileged", "Container runs privileged", common.ElevationOfPrivilege, "High"},
+		{"cap_add", "Container has additional capabilities (SYS_ADMIN, NET_ADMIN)", common.ElevationOfPrivilege, "Medium"},
+		{"network_mode_host", "Container uses the host network", common.InformationDisclosure, "Medium"},
+		{"pid_host", "Container uses the host PID namespace", common.InformationDisclosure, "Medium"},
+		{"docker_socket", "Container mounts the docker socket", common.ElevationOfPrivilege, "High"},
+		{"user", "Container user is not set", common.ElevationOfPrivilege, "Low"},
+		{"read_only", "Container file system is writable", common.Tampering, "Low"},
+		{"resource_limits", "Container has no resource limits", common.DenialOfService, "Low"},
+	}
+	require.Len(t, threats, len(expected))
+	for i, e := range expected {
+		id := common.GenerateIDHash(filePath, "threat:agent:"+e.rule)
+		assert.Equal(t, id, threats[i].InternalID)
+		assert.Equal(t, common.UUIDFormat(id), threats[i].ID)
+		assert.Equal(t, e.title, threats[i].Title)
+		assert.Equal(t, e.threatType, threats[i].Type)
+		assert.Equal(t, e.severity, threats[i].Severity)
+		assert.Equal(t, common.Open, threats[i].Status)
+		assert.Equal(t, common.STRIDE, threats[i].ModelType)
+		assert.Equal(t, common.DataSourceDockerCompose, threats[i].Source)
+		assert.Equal(t, -1, threats[i].MapIndex)
+		assert.NotEmpty(t, threats[i].Description)
+		assert.NotEmpty(t, threats[i].Mitigation)
+	}
+}
+
+func TestHardeningThreatsOfHardenedServices(t *testing.T) {
+	const filePath = "testdata/docker-compose-hardening.yml"
+	an := NewDockerComposeAnalyzer(filePath, slog.Default())
+	project := loadTestProject(t, filePath)
+
+	// app limits the memory, worker limits the CPU with deploy.resources
+	for _, name := range []string{"app", "worker"} {
+		t.Run(name, func(t *testing.T) {
+			assert.Empty(t, an.hardeningThreats(project.Services[name]))
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package dockercompose
+
+import (
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/distribution/reference"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// imageReference is the parsed image of a service
+type imageReference struct {
+	Registry   string // e.g. docker.io or ghcr.io
+	Repository string // e.g. library/postgres
+	Tag        string
+	Digest     string
+}
+
+// parseImageReference splits an image into registry, repository, tag and digest.
+// Images without a registry are normalized to docker.io, like docker does.
+func parseImageReference(image string) (imageReference, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return imageReference{}, err
+	}
+	ref := imageReference{
+		Registry:   reference.Domain(named),
+		Repository: reference.Path(named),
+	}
+	if tagged, ok := named.(reference.Tagged); ok {
+		ref.Tag = tagged.Tag()
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		ref.Digest = digested.Digest().String()
+	}
+	return ref, nil
+}
+
+// String returns the registry and repository of the image
+func (r imageReference) String() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// extra returns the parts of the image as asset properties. Empty parts are left out.
+func (r imageReference) extra() map[string]any {
+	extra := map[string]any{
+		"DockerImageRegistry":   r.Registry,
+		"DockerImageRepository": r.Repository,
+	}
+	if r.Tag != "" {
+		extra["DockerImageTag"] = r.Tag
+	}
+	if r.Digest != "" {
+		extra["DockerImageDigest"] = r.Digest
+	}
+	return extra
+}
+
+// RegistryAllowList contains the registries, or registries with a repository prefix, that images may be pulled from.
+// An empty list allows all registries.
+type RegistryAllowList []string
+
+// Allows checks if the image is pulled from an allowed registry
+func (l RegistryAllowList) Allows(ref imageReference) bool {
+	if len(l) == 0 {
+		return true
+	}
+	name := ref.String()
+	for _, entry := range l {
+		entry = strings.TrimSuffix(entry, "/")
+		if name == entry || strings.HasPrefix(name, entry+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// imageChecks returns the checks for the provenance of the image of a service.
+// The registry is only checked if an allow-list is given.
+func imageChecks(ref imageReference, allowed RegistryAllowList) []hardeningCheck {
+	checks := []hardeningCheck{
+		{
+			rule:        "image_tag",
+			title:       "Image is not pinned to a version",
+			threatType:  common.Tampering,
+			severity:    "Medium",
+			description: "The image uses the 'latest' tag or no tag at all. Every pull can bring a different, untested or compromised version of the image.",
+			mitigation:  "Use a fixed version tag and pin the image by its digest, e.g. 'image: postgres:16.3@sha256:...'.",
+			check: func(types.ServiceConfig) (bool, string) {
+				if ref.Tag == "" && ref.Digest == "" {
+					return true, "untagged"
+				}
+				return ref.Tag == "latest", ref.Tag
+			},
+		},
+		{
+			rule:        "image_digest",
+			title:       "Image is not pinned by digest",
+			threatType:  common.Tampering,
+			severity:    "Low",
+			description: "The image is referenced by its tag only. Tags can be moved, so a compromised registry or repository can replace the image without notice.",
+			mitigation:  "Pin the image by its digest, e.g. 'image: postgres:16.3@sha256:...', and update the digest deliberately.",
+			check: func(types.ServiceConfig) (bool, string) {
+				return ref.Digest == "", ""
+			},
+		},
+	}
+	if len(allowed) > 0 {
+		checks = append(checks, hardeningCheck{
+			rule:        "image_registry",
+			title:       "Image is pulled from an untrusted registry",
+			threatType:  common.Tampering,
+			severity:    "Medium",
+			description: "The image is not pulled from one of the allowed registries. Its origin and content are not controlled.",
+			mitigation:  "Pull the image from an allowed registry, e.g. by mirroring it, or add the registry to the allow-list in the image map config.",
+			check: func(types.ServiceConfig) (bool, string) {
+				return !allowed.Allows(ref), ref.Registry
+			},
+		})
+	}
+	return checks
+}
+
+// imageThreats parses the image of a service and returns the threats of its provenance together with
+// the parts of the image as asset properties. Services that build their image are not checked.
+func (a *DockerComposeAnalyzer) imageThreats(service types.ServiceConfig) ([]common.Threat, map[string]any) {
+	if service.Image == "" || service.Build != nil {
+		return []common.Threat{}, map[string]any{}
+	}
+	ref, err := parseImageReference(service.Image)
+	if err != nil {
+		a.logger.Warn("Could not parse image of service. Skipping image checks.", "service", service.Name, "image", service.Image, "error", err)
+		return []common.Threat{}, map[string]any{}
+	}
+	a.logger.Debug("Parsed image of service", "service", service.Name, "image", ref)
+	return a.checkThreats(service, imageChecks(ref, a.AllowedRegistries)), ref.extra()
+}
@@ -0,0 +1,149 @@
+package dockercompose
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+const testDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestParseImageReference(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected imageReference
+	}{
+		{"postgres", imageReference{"docker.io", "library/postgres", "", ""}},
+		{"postgres:16.3", imageReference{"docker.io", "library/postgres", "16.3", ""}},
+		{"prom/prometheus:latest", imageReference{"docker.io", "prom/prometheus", "latest", ""}},
+		{"ghcr.io/myorg/api:1.4.2", imageReference{"ghcr.io", "myorg/api", "1.4.2", ""}},
+		{"localhost:5000/app", imageReference{"localhost:5000", "app", "", ""}},
+		{"nginx@" + testDigest, imageReference{"docker.io", "library/nginx", "", testDigest}},
+		{"nginx:1.27@" + testDigest, imageReference{"docker.io", "library/nginx", "1.27", testDigest}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			ref, err := parseImageReference(tt.image)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, ref)
+		})
+	}
+
+	_, err := parseImageReference("Invalid:Image:Name")
+	assert.Error(t, err)
+}
+
+func TestRegistryAllowList(t *testing.T) {
+	allowed := RegistryAllowList{"docker.io", "ghcr.io/myorg/"}
+	tests := []struct {
+		image    string
+		expected bool
+	}{
+		{"postgres:16", true},
+		{"ghcr.io/myorg/api:1.0", true},
+		{"ghcr.io/myorg-fork/api:1.0", false},
+		{"ghcr.io/otherorg/api:1.0", false},
+		{"quay.io/prometheus/prometheus", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			ref, err := parseImageReference(tt.image)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, allowed.Allows(ref))
+		})
+	}
+
+	ref, err := parseImageReference("quay.io/prometheus/prometheus")
+	require.NoError(t, err)
+	assert.True(t, RegistryAllowList{}.Allows(ref))
+}
+
+func TestImageThreats(t *testing.T) {
+	const filePath = "testdata/docker-compose-images.yml"
+	project := loadTestProject(t, filePath)
+
+	tests := []struct {
+		service  string
+		allowed  RegistryAllowList
+		expected []string
+	}{
+		{"latest", nil, []string{"Image is not pinned to a version (latest)", "Image is not pinned by digest"}},
+		{"untagged", nil, []string{"Image is not pinned to a version (untagged)", "Image is not pinned by digest"}},
+		{"tagged", nil, []string{"Image is not pinned by digest"}},
+		{"pinned", nil, []string{}},
+		{"built", nil, []string{}},
+		{"tagged", RegistryAllowList{"ghcr.io/myorg"}, []string{"Image is not pinned by digest"}},
+		{"pinned", RegistryAllowList{"ghcr.io/myorg"}, []string{"Image is pulled from an untrusted registry (ghcr.io)"}},
+		{"latest", RegistryAllowList{"ghcr.io"}, []string{
+			"Image is not pinned to a version (latest)",
+			"Image is not pinned by digest",
+			"Image is pulled from an untrusted registry (docker.io)",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			an := NewDockerComposeAnalyzer(filePath, slog.Default())
+			an.AllowedRegistries = tt.allowed
+			threats, _ := an.imageThreats(project.Services[tt.service])
+
+			titles := make([]string, 0, len(threats))
+			for _, threat := range threats {
+				titles = append(titles, threat.Title)
+				assert.Equal(t, common.Tampering, threat.Type)
+				assert.Equal(t, common.Open, threat.Status)
+				assert.Equal(t, common.DataSourceDockerCompose, threat.Source)
+			}
+			assert.Equal(t, tt.expected, titles)
+		})
+	}
+}
+
+func TestImageThreatsIDs(t *testing.T) {
+	const filePath = "testdata/docker-compose-images.yml"
+	project := loadTestProject(t, filePath)
+	an := NewDockerComposeAnalyzer(filePath, slog.Default())
+
+	threats, _ := an.imageThreats(project.Services["latest"])
+	require.Len(t, threats, 2)
+	assert.Equal(t, common.GenerateIDHash(filePath, "threat:latest:image_tag"), threats[0].InternalID)
+	assert.Equal(t, common.GenerateIDHash(filePath, "threat:latest:image_digest"), threats[1].InternalID)
+}
+
+func TestImageThreatsExtra(t *testing.T) {
+	const filePath = "testdata/docker-compose-images.yml"
+	project := loadTestProject(t, filePath)
+	an := NewDockerComposeAnalyzer(filePath, slog.Default())
+
+	_, extra := an.imageThreats(project.Services["pinned"])
+	assert.Equal(t, map[string]any{
+		"DockerImageRegistry":   "ghcr.io",
+		"DockerImageRepository": "otherorg/worker",
+		"DockerImageTag":        "2.0",
+		"DockerImageDigest":     testDigest,
+	}, extra)
+
+	_, extra = an.imageThreats(project.Services["untagged"])
+	assert.Equal(t, map[string]any{
+		"DockerImageRegistry":   "docker.io",
+		"DockerImageRepository": "library/nginx",
+	}, extra)
+
+	_, extra = an.imageThreats(project.Services["built"])
+	assert.Empty(t, extra)
+}
+
+func TestNewRegistryAllowList(t *testing.T) {
+	allowed, err := NewRegistryAllowList("testdata/dockerimage.config")
+	require.NoError(t, err)
+	assert.Equal(t, RegistryAllowList{"docker.io", "ghcr.io/myorg"}, allowed)
+
+	allowed, err = NewRegistryAllowList("")
+	require.NoError(t, err)
+	assert.Empty(t, allowed)
+
+	_, err = NewRegistryAllowList("testdata/does-not-exist.config")
+	assert.Error(t, err)
+}
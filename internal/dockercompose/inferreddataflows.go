@@ -0,0 +1,226 @@
+package dockercompose
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// hostEnvSuffixes are the suffixes of environment variables that usually hold the host name of another service
+var hostEnvSuffixes = []string{"_HOST", "_HOSTNAME", "_SERVER", "_ADDR", "_ADDRESS"}
+
+// schemeProtocols maps URL schemes to the protocol of the dataflow and whether it is encrypted
+var schemeProtocols = map[string]struct {
+	protocol  string
+	encrypted bool
+}{
+	"http":        {"http", false},
+	"https":       {"https", true},
+	"ws":          {"ws", false},
+	"wss":         {"wss", true},
+	"grpc":        {"grpc", false},
+	"grpcs":       {"grpc", true},
+	"postgres":    {"postgresql", false},
+	"postgresql":  {"postgresql", false},
+	"mysql":       {"mysql", false},
+	"mariadb":     {"mysql", false},
+	"sqlserver":   {"mssql", false},
+	"mongodb":     {"mongodb", false},
+	"mongodb+srv": {"mongodb", true},
+	"redis":       {"redis", false},
+	"rediss":      {"redis", true},
+	"amqp":        {"amqp", false},
+	"amqps":       {"amqp", true},
+	"kafka":       {"kafka", false},
+	"nats":        {"nats", false},
+	"mqtt":        {"mqtt", false},
+	"mqtts":       {"mqtt", true},
+	"memcached":   {"memcached", false},
+	"ldap":        {"ldap", false},
+	"ldaps":       {"ldap", true},
+	"ftp":         {"ftp", false},
+	"ftps":        {"ftp", true},
+	"sftp":        {"sftp", true},
+	"smtp":        {"smtp", false},
+	"smtps":       {"smtp", true},
+}
+
+// tlsQueryParameters are URL query parameters that enable encryption for schemes that are unencrypted by default
+var tlsQueryParameters = []string{
+	"sslmode=require", "sslmode=verify-ca", "sslmode=verify-full",
+	"ssl=true", "tls=true", "usessl=true",
+}
+
+// inferDataFlows guesses dataflows between the services of the project. Flows are inferred from
+// depends_on, links and external_links and from environment variables that contain a URL or host
+// name of another service. All inferred flows are marked as such.
+func (a *DockerComposeAnalyzer) inferDataFlows(proj *types.Project) []common.DataFlow {
+	hosts := serviceHostNames(proj)
+	flows := make([]common.DataFlow, 0)
+
+	// add keeps one flow per pair of services. Flows with a known protocol replace flows without.
+	add := func(flow common.DataFlow) {
+		if flow.Source == flow.Target {
+			return
+		}
+		index := slices.IndexFunc(flows, func(f common.DataFlow) bool {
+			return f.Source == flow.Source && f.Target == flow.Target
+		})
+		if index < 0 {
+			flows = append(flows, flow)
+		} else if flows[index].Protocol == "" && flow.Protocol != "" {
+			flows[index] = flow
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(proj.Services)) {
+		service := proj.Services[name]
+		logger := a.logger.With("service", name)
+
+		for _, key := range slices.Sorted(maps.Keys(service.Environment)) {
+			value := service.Environment[key]
+			if value == nil {
+				continue
+			}
+			for _, flow := range a.environmentDataFlows(name, key, *value, hosts) {
+				logger.Debug("Inferred dataflow from environment variable", "variable", key, "target", flow.Target)
+				add(flow)
+			}
+		}
+
+		for _, dependency := range slices.Sorted(maps.Keys(service.DependsOn)) {
+			add(a.inferredDataFlow(name, dependency, "depends on", ""))
+		}
+
+		for _, link := range slices.Concat(service.Links, service.ExternalLinks) {
+			target, ok := hosts[strings.Split(link, ":")[0]]
+			if !ok {
+				logger.Debug("Link does not point to a service of the project. Skipping.", "link", link)
+				continue
+			}
+			add(a.inferredDataFlow(name, target, "link", ""))
+		}
+	}
+	return flows
+}
+
+// environmentDataFlows infers the dataflows of a single environment variable.
+// Values can be URLs with one or more hosts (e.g. postgres://db:5432/app) or plain host names
+// in variables named like a host (e.g. REDIS_HOST=cache).
+func (a *DockerComposeAnalyzer) environmentDataFlows(service, key, value string, hosts map[string]string) []common.DataFlow {
+	flows := make([]common.DataFlow, 0)
+	value = strings.TrimPrefix(strings.TrimSpace(value), "jdbc:")
+
+	scheme, rest, isURL := strings.Cut(value, "://")
+	if !isURL {
+		if !slices.ContainsFunc(hostEnvSuffixes, func(suffix string) bool { return strings.HasSuffix(strings.ToUpper(key), suffix) }) {
+			return flows
+		}
+		if target, ok := hosts[hostName(value)]; ok {
+			flows = append(flows, a.inferredDataFlow(service, target, key, guessProtocolFromKey(key)))
+		}
+		return flows
+	}
+
+	scheme = strings.ToLower(scheme)
+	protocol, encrypted := scheme, false
+	if known, ok := schemeProtocols[scheme]; ok {
+		protocol, encrypted = known.protocol, known.encrypted
+	}
+
+	authority, query := rest, ""
+	if index := strings.IndexAny(authority, "/?"); index >= 0 {
+		authority = rest[:index]
+		if _, q, ok := strings.Cut(rest, "?"); ok {
+			query = strings.ToLower(q)
+		}
+	}
+	if index := strings.LastIndex(authority, "@"); index >= 0 {
+		authority = authority[index+1:]
+	}
+	if slices.ContainsFunc(tlsQueryParameters, func(p string) bool { return strings.Contains(query, p) }) {
+		encrypted = true
+	}
+
+	for _, host := range strings.Split(authority, ",") {
+		target, ok := hosts[hostName(host)]
+		if !ok {
+			continue
+		}
+		flow := a.inferredDataFlow(service, target, key, protocol)
+		flow.Encrypted = encrypted
+		flows = append(flows, flow)
+	}
+	return flows
+}
+
+// inferredDataFlow creates an inferred dataflow between two services
+func (a *DockerComposeAnalyzer) inferredDataFlow(source, target, name, protocol string) common.DataFlow {
+	return common.DataFlow{
+		ID:         common.GenerateIDHash(a.DockerComposeFilePath, fmt.Sprintf("inferred:%s->%s", source, target)),
+		Name:       name,
+		Protocol:   protocol,
+		Source:     source,
+		Target:     target,
+		Inferred:   true,
+		DataSource: common.DataSourceDockerCompose,
+	}
+}
+
+// mergeInferredDataFlows adds the inferred dataflows to the explicit ones.
+// Inferred flows between services that are already connected by an explicit flow are dropped.
+func mergeInferredDataFlows(explicit, inferred []common.DataFlow) []common.DataFlow {
+	merged := slices.Clone(explicit)
+	for _, flow := range inferred {
+		connected := slices.ContainsFunc(explicit, func(e common.DataFlow) bool {
+			return (e.Source == flow.Source && e.Target == flow.Target) || (e.Source == flow.Target && e.Target == flow.Source)
+		})
+		if !connected {
+			merged = append(merged, flow)
+		}
+	}
+	return merged
+}
+
+// serviceHostNames maps all names under which a service can be reached to the service name.
+// These are the service name, the container name, the host name and the network aliases.
+func serviceHostNames(proj *types.Project) map[string]string {
+	hosts := make(map[string]string)
+	for name, service := range proj.Services {
+		names := []string{name, service.ContainerName, service.Hostname}
+		for _, network := range service.Networks {
+			if network != nil {
+				names = append(names, network.Aliases...)
+			}
+		}
+		for _, host := range names {
+			if host != "" {
+				hosts[host] = name
+			}
+		}
+	}
+	return hosts
+}
+
+// hostName strips the port from a host
+func hostName(host string) string {
+	host = strings.TrimSpace(host)
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		return name
+	}
+	return host
+}
+
+// guessProtocolFromKey guesses the protocol from the prefix of an environment variable, e.g. REDIS_HOST
+func guessProtocolFromKey(key string) string {
+	prefix, _, _ := strings.Cut(strings.ToLower(key), "_")
+	if known, ok := schemeProtocols[prefix]; ok {
+		return known.protocol
+	}
+	return ""
+}
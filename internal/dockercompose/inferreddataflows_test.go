@@ -0,0 +1,104 @@
+package dockercompose
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/compose-spec/compose-go/v2/cli"
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+func loadTestProject(t *testing.T, filePath string) *types.Project {
+	t.Helper()
+	options, err := cli.NewProjectOptions([]string{filePath}, cli.WithName("test_project"))
+	require.NoError(t, err)
+	project, err := options.LoadProject(context.TODO())
+	require.NoError(t, err)
+	return project
+}
+
+func TestInferDataFlows(t *testing.T) {
+	const filePath = "testdata/docker-compose-inferred.yml"
+	an := NewDockerComposeAnalyzer(filePath, slog.Default())
+
+	flows := an.inferDataFlows(loadTestProject(t, filePath))
+
+	expected := []struct {
+		source, target, name, protocol string
+		encrypted                      bool
+	}{
+		{"api", "queue", "BROKER_URL", "amqp", false},
+		{"api", "db", "DATABASE_URL", "postgresql", true},
+		{"api", "cache", "REDIS_HOST", "redis", false},
+		{"web", "api", "depends on", "", false},
+	}
+	require.Len(t, flows, len(expected))
+	for i, e := range expected {
+		assert.Equal(t, e.source, flows[i].Source)
+		assert.Equal(t, e.target, flows[i].Target)
+		assert.Equal(t, e.name, flows[i].Name)
+		assert.Equal(t, e.protocol, flows[i].Protocol)
+		assert.Equal(t, e.encrypted, flows[i].Encrypted)
+		assert.True(t, flows[i].Inferred)
+		assert.Equal(t, common.GenerateIDHash(filePath, "inferred:"+e.source+"->"+e.target), flows[i].ID)
+	}
+}
+
+func TestEnvironmentDataFlows(t *testing.T) {
+	an := &DockerComposeAnalyzer{DockerComposeFilePath: "compose.yml", logger: slog.Default()}
+	hosts := map[string]string{"db": "db", "cache": "cache", "mongo-1": "mongo", "mongo-2": "mongo2"}
+
+	tests := []struct {
+		name      string
+		key       string
+		value     string
+		targets   []string
+		protocol  string
+		encrypted bool
+	}{
+		{"url with credentials", "DATABASE_URL", "postgresql://user:pw@db:5432/app", []string{"db"}, "postgresql", false},
+		{"jdbc url", "SPRING_DATASOURCE_URL", "jdbc:mysql://db:3306/app?useSSL=true", []string{"db"}, "mysql", true},
+		{"encrypted scheme", "REDIS_URL", "rediss://cache:6380", []string{"cache"}, "redis", true},
+		{"multiple hosts", "MONGO_URL", "mongodb://mongo-1:27017,mongo-2:27017/app", []string{"mongo", "mongo2"}, "mongodb", false},
+		{"unknown scheme", "CUSTOM_URL", "foo://db", []string{"db"}, "foo", false},
+		{"host with port", "CACHE_ADDR", "cache:11211", []string{"cache"}, "", false},
+		{"host with known prefix", "POSTGRES_HOST", "db", []string{"db"}, "postgresql", false},
+		{"external host", "API_URL", "https://api.example.com", nil, "", false},
+		{"host in unrelated variable", "DB_NAME", "db", nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flows := an.environmentDataFlows("app", tt.key, tt.value, hosts)
+			require.Len(t, flows, len(tt.targets))
+			for i, target := range tt.targets {
+				assert.Equal(t, "app", flows[i].Source)
+				assert.Equal(t, target, flows[i].Target)
+				assert.Equal(t, tt.protocol, flows[i].Protocol)
+				assert.Equal(t, tt.encrypted, flows[i].Encrypted)
+			}
+		})
+	}
+}
+
+// Explicit flows from comments win over inferred flows between the same services
+func TestAnalyzerExplicitDataFlowsWin(t *testing.T) {
+	const filePath = "testdata/docker-compose-inferred.yml"
+	dockerImageMap, err := NewDockerImageMap("")
+	require.NoError(t, err)
+
+	model, err := NewDockerComposeAnalyzer(filePath, slog.Default()).Analyze(loadTestProject(t, filePath), dockerImageMap)
+	require.NoError(t, err)
+
+	require.Len(t, model.DataFlows, 4)
+	explicit := model.DataFlows[0]
+	assert.Equal(t, "Session cache", explicit.Name)
+	assert.False(t, explicit.Inferred)
+	for _, flow := range model.DataFlows[1:] {
+		assert.True(t, flow.Inferred)
+		assert.NotEqual(t, "cache", flow.Target)
+	}
+}
@@ -4,18 +4,29 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/compose-spec/compose-go/v2/cli"
 	"github.com/compose-spec/compose-go/v2/types"
 )
 
-// DockerComposeAnalyzer analyzes Docker Compose files
+// DockerComposeParser parses a Docker Compose project that consists of one or more files
 type DockerComposeParser struct {
-	filePath string
-	logger   *slog.Logger
+	filePath      string
+	overridePaths []string
+	options       ProjectOptions
+	logger        *slog.Logger
+}
+
+// ProjectOptions select the profiles and environment files a compose project is loaded with,
+// like the --profile and --env-file flags of docker compose
+type ProjectOptions struct {
+	Profiles []string
+	EnvFiles []string
 }
 
 // NewDockerComposeParser creates a new instance of DockerComposeParser
@@ -26,6 +37,19 @@ func NewDockerComposeParser(filePath string, logger *slog.Logger) *DockerCompose
 	}
 }
 
+// NewDockerComposeProjectParser creates a new instance of DockerComposeParser for a project of several files.
+// The files are merged in the given order, like 'docker compose -f a.yml -f b.yml' does it.
+// The first file names the project.
+func NewDockerComposeProjectParser(filePaths []string, options ProjectOptions, logger *slog.Logger) *DockerComposeParser {
+	parser := NewDockerComposeParser("", logger)
+	if len(filePaths) > 0 {
+		parser.filePath = filePaths[0]
+		parser.overridePaths = filePaths[1:]
+	}
+	parser.options = options
+	return parser
+}
+
 // extracts filepath and sanitizes it to create an usable project namen
 func (dcp *DockerComposeParser) createProjectNameOutOfFilepath(filePath string) string {
 	//extract filename, replace "-" characters with "_"
@@ -48,9 +72,11 @@ func (dcp *DockerComposeParser) ParseDockerComposeYML() (*types.Project, error)
 	logger := dcp.logger.With("projectName", projectName)
 	// Create project options from the docker-compose file
 	options, err := cli.NewProjectOptions(
-		[]string{dcp.filePath},
+		append([]string{dcp.filePath}, dcp.overridePaths...),
 		cli.WithOsEnv,
+		cli.WithEnvFiles(dcp.options.EnvFiles...),
 		cli.WithDotEnv,
+		cli.WithProfiles(dcp.options.Profiles),
 		cli.WithName(projectName),
 	)
 	if err != nil {
@@ -62,6 +88,9 @@ func (dcp *DockerComposeParser) ParseDockerComposeYML() (*types.Project, error)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load compose project: %w", err)
 	}
-	logger.Info("Successfully parsed input yml")
+	if len(project.DisabledServices) > 0 {
+		logger.Info("Skipped services of inactive profiles", "services", slices.Sorted(maps.Keys(project.DisabledServices)))
+	}
+	logger.Info("Successfully parsed input yml", "files", project.ComposeFiles)
 	return project, nil
 }
@@ -68,3 +68,44 @@ func TestParser_TestProjectNameCreationValid(t *testing.T) {
 func TestParser_TestProjectNameCreationWithUnsupportedCharacters(t *testing.T) {
 	testProjectNameCreationFileWithPath(t, "example/directory/docker²³§-compose=?)($%!.yml", "docker_compose")
 }
+
+// Confirm override files are merged into the project of the first file
+func TestParser_ProjectWithOverrideFile(t *testing.T) {
+	parser := NewDockerComposeProjectParser([]string{
+		"testdata/project/docker-compose.yml",
+		"testdata/project/docker-compose.prod.yml",
+	}, ProjectOptions{}, slog.Default())
+	project, err := parser.ParseDockerComposeYML()
+	require.NoError(t, err)
+
+	assert.Equal(t, "docker_compose", project.Name)
+	assert.Len(t, project.ComposeFiles, 2)
+	assert.ElementsMatch(t, []string{"web", "api", "db"}, project.ServiceNames())
+	assert.Equal(t, "nginx:latest", project.Services["web"].Image)
+	assert.Len(t, project.Services["web"].Ports, 1)
+	assert.Contains(t, project.DisabledServices, "adminer")
+}
+
+// Confirm profiles enable services and env files are used for interpolation
+func TestParser_ProjectWithProfileAndEnvFile(t *testing.T) {
+	parser := NewDockerComposeProjectParser([]string{"testdata/project/docker-compose.yml"}, ProjectOptions{
+		Profiles: []string{"debug"},
+		EnvFiles: []string{"testdata/project/prod.env"},
+	}, slog.Default())
+	project, err := parser.ParseDockerComposeYML()
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"web", "api", "adminer"}, project.ServiceNames())
+	assert.Equal(t, "nginx:1.27", project.Services["web"].Image)
+}
+
+// Confirm parser rejects projects with a missing override file
+func TestParser_ProjectWithMissingOverrideFileWillFail(t *testing.T) {
+	parser := NewDockerComposeProjectParser([]string{
+		"testdata/project/docker-compose.yml",
+		"testdata/project/non_existing_file.yml",
+	}, ProjectOptions{}, slog.Default())
+	project, err := parser.ParseDockerComposeYML()
+	assert.Error(t, err)
+	assert.Nil(t, project)
+}
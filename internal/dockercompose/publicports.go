@@ -0,0 +1,106 @@
+package dockercompose
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// internetAssetName is the display name of the asset that represents the internet and its users
+const internetAssetName = "Internet / External User"
+
+// internetBoundaryName is the display name of the trust boundary that contains the internet asset
+const internetBoundaryName = "Internet"
+
+// The IDs of the internet asset and boundary do not depend on the compose file,
+// so that the internet of all inputs is merged into a single asset and boundary.
+var (
+	internetAssetID    = common.GenerateIDHash("internet", internetAssetName)
+	internetBoundaryID = common.GenerateIDHash("internet", internetBoundaryName)
+)
+
+// webPorts maps container ports to the web protocol that is usually served on them
+var webPorts = map[uint32]struct {
+	protocol  string
+	encrypted bool
+}{
+	80:   {"http", false},
+	443:  {"https", true},
+	8000: {"http", false},
+	8080: {"http", false},
+	8443: {"https", true},
+}
+
+// publishedPortDataFlows creates a dataflow from the internet to every port that is published on the host.
+// A port without a host port is published on a random one, so every entry of ports counts. Ports that are
+// only exposed to other services or that are bound to a loopback address are not reachable from the outside
+// and are skipped. A port that is published on several host addresses, e.g. on IPv4 and IPv6, results in a single dataflow.
+func (a *DockerComposeAnalyzer) publishedPortDataFlows(proj *types.Project) []common.DataFlow {
+	flows := make([]common.DataFlow, 0)
+	for _, name := range slices.Sorted(maps.Keys(proj.Services)) {
+		for _, port := range proj.Services[name].Ports {
+			if ip := net.ParseIP(port.HostIP); ip != nil && ip.IsLoopback() {
+				a.logger.Debug("Port is only published on a loopback address. Skipping.", "service", name, "port", port.Published)
+				continue
+			}
+			flow := a.publishedPortDataFlow(name, port)
+			if slices.ContainsFunc(flows, func(df common.DataFlow) bool { return df.ID == flow.ID }) {
+				a.logger.Debug("Port is published on several host addresses. Skipping duplicate.", "service", name, "port", port.Published, "hostIP", port.HostIP)
+				continue
+			}
+			flows = append(flows, flow)
+		}
+	}
+	return flows
+}
+
+// publishedPortDataFlow creates the public dataflow from the internet to a published port of a service
+func (a *DockerComposeAnalyzer) publishedPortDataFlow(service string, port types.ServicePortConfig) common.DataFlow {
+	protocol, encrypted := port.Protocol, false
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	if known, ok := webPorts[port.Target]; ok && protocol == "tcp" {
+		protocol, encrypted = known.protocol, known.encrypted
+	}
+
+	published := port.Published
+	if published == "" {
+		published = fmt.Sprintf("random->%d", port.Target)
+	}
+
+	return common.DataFlow{
+		ID:            common.GenerateIDHash(a.DockerComposeFilePath, fmt.Sprintf("published:%s:%s->%d/%s", service, port.Published, port.Target, port.Protocol)),
+		Name:          fmt.Sprintf("%s (%s)", protocol, published),
+		Protocol:      protocol,
+		Encrypted:     encrypted,
+		PublicNetwork: true,
+		Source:        internetAssetName,
+		Target:        service,
+		DataSource:    common.DataSourceDockerCompose,
+	}
+}
+
+// addInternet adds the internet asset and its trust boundary to the model
+func addInternet(model *common.ThreatModel) {
+	model.Assets = append(model.Assets, common.Asset{
+		ID:          internetAssetID,
+		DisplayName: internetAssetName,
+		Type:        common.AssetTypeExternalEntity,
+		Source:      common.DataSourceDockerCompose,
+		Extra:       map[string]any{},
+	})
+	model.Boundaries = append(model.Boundaries, common.TrustBoundary{
+		ID:              internetBoundaryID,
+		DisplayName:     internetBoundaryName,
+		ContainedAssets: []string{internetAssetID},
+		Source:          common.DataSourceDockerCompose,
+		Extra: map[string]any{
+			"initial-description": "Everything outside of the docker host",
+		},
+	})
+}
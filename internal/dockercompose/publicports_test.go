@@ -0,0 +1,85 @@
+package dockercompose
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+func TestPublishedPortDataFlows(t *testing.T) {
+	const filePath = "testdata/docker-compose-ports.yml"
+	an := NewDockerComposeAnalyzer(filePath, slog.Default())
+
+	flows := an.publishedPortDataFlows(loadTestProject(t, filePath))
+
+	expected := []struct {
+		target, name, protocol string
+		encrypted              bool
+	}{
+		{"admin", "tcp (random->3000)", "tcp", false},
+		{"dns", "udp (53)", "udp", false},
+		// published on 0.0.0.0 and [::]
+		{"proxy", "http (8080)", "http", false},
+		{"proxy", "https (8443)", "https", true},
+	}
+	require.Len(t, flows, len(expected))
+	for i, e := range expected {
+		assert.Equal(t, internetAssetName, flows[i].Source)
+		assert.Equal(t, e.target, flows[i].Target)
+		assert.Equal(t, e.name, flows[i].Name)
+		assert.Equal(t, e.protocol, flows[i].Protocol)
+		assert.Equal(t, e.encrypted, flows[i].Encrypted)
+		assert.True(t, flows[i].PublicNetwork)
+		assert.False(t, flows[i].Inferred)
+	}
+
+	ids := make(map[string]bool)
+	for _, flow := range flows {
+		assert.False(t, ids[flow.ID], "duplicate ID for %s", flow.Name)
+		ids[flow.ID] = true
+	}
+}
+
+func TestAnalyzeAddsInternetForPublishedPorts(t *testing.T) {
+	dockerImageMap, err := NewDockerImageMap("")
+	require.NoError(t, err)
+
+	for _, filePath := range []string{"testdata/docker-compose-ports.yml", "testdata/docker-compose-for-test.yml"} {
+		t.Run(filePath, func(t *testing.T) {
+			model, err := NewDockerComposeAnalyzer(filePath, slog.Default()).Analyze(loadTestProject(t, filePath), dockerImageMap)
+			require.NoError(t, err)
+
+			internet := model.Assets[len(model.Assets)-1]
+			assert.Equal(t, internetAssetName, internet.DisplayName)
+			assert.Equal(t, common.AssetTypeExternalEntity, internet.Type)
+			// the ID does not depend on the file, so that all inputs share the same internet asset
+			assert.Equal(t, internetAssetID, internet.ID)
+
+			boundary := model.Boundaries[len(model.Boundaries)-1]
+			assert.Equal(t, internetBoundaryName, boundary.DisplayName)
+			assert.Equal(t, []string{internetAssetID}, boundary.ContainedAssets)
+		})
+	}
+}
+
+func TestAnalyzeWithoutPublishedPorts(t *testing.T) {
+	const filePath = "testdata/docker-compose-inferred.yml"
+	dockerImageMap, err := NewDockerImageMap("")
+	require.NoError(t, err)
+
+	model, err := NewDockerComposeAnalyzer(filePath, slog.Default()).Analyze(loadTestProject(t, filePath), dockerImageMap)
+	require.NoError(t, err)
+
+	for _, boundary := range model.Boundaries {
+		assert.NotEqual(t, internetBoundaryName, boundary.DisplayName)
+	}
+	for _, asset := range model.Assets {
+		assert.NotEqual(t, common.AssetTypeExternalEntity, asset.Type)
+	}
+	for _, flow := range model.DataFlows {
+		assert.False(t, flow.PublicNetwork)
+	}
+}
@@ -0,0 +1,179 @@
+package dockercompose
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// ignoreSecretsExtension lists keys whose values are not reported as plaintext secrets.
+// It can be set for the whole project or for a single service.
+const ignoreSecretsExtension = "x-threatcat-ignore-secrets"
+
+// secretKeyWords are the words of environment variable names that hold credentials, e.g. POSTGRES_PASSWORD
+var secretKeyWords = []string{"PASSWORD", "PASSWD", "PWD", "PASS", "SECRET", "TOKEN", "APIKEY", "CREDENTIAL", "CREDENTIALS"}
+
+// secretKeyPairs are pairs of consecutive words that hold credentials, e.g. AWS_ACCESS_KEY
+var secretKeyPairs = [][2]string{{"API", "KEY"}, {"ACCESS", "KEY"}, {"PRIVATE", "KEY"}, {"SECRET", "KEY"}, {"AUTH", "KEY"}, {"SIGNING", "KEY"}, {"ENCRYPTION", "KEY"}}
+
+// referenceKeyWords are last words of names whose value points to a secret instead of holding it, e.g. POSTGRES_PASSWORD_FILE
+var referenceKeyWords = []string{"FILE", "PATH", "DIR", "NAME", "USER", "USERNAME"}
+
+// nonSecretValues are values of secret keys that are switches instead of credentials
+var nonSecretValues = []string{"true", "false", "yes", "no", "on", "off", "0", "1", "none", "null"}
+
+const (
+	// minEntropyLength is the minimal length of values that are checked for random content
+	minEntropyLength = 20
+	// minEntropy is the minimal shannon entropy in bits per character of a random secret
+	minEntropy = 3.5
+)
+
+// secretFinding is a value that looks like a plaintext secret
+type secretFinding struct {
+	kind   string // "environment variable" or "build argument"
+	key    string
+	value  string
+	reason string
+}
+
+// secretThreats returns an information disclosure threat for every environment variable and build argument
+// of the service that holds a plaintext secret. Values interpolated from .env files are checked as well.
+// Keys listed in the ignore extension of the project or the service are skipped.
+func (a *DockerComposeAnalyzer) secretThreats(proj *types.Project, service types.ServiceConfig) []common.Threat {
+	ignored := slices.Concat(extensionStrings(proj.Extensions, ignoreSecretsExtension), extensionStrings(service.Extensions, ignoreSecretsExtension))
+
+	findings := make([]secretFinding, 0)
+	collect := func(kind string, values map[string]*string) {
+		for _, key := range slices.Sorted(maps.Keys(values)) {
+			if values[key] == nil || slices.Contains(ignored, key) {
+				continue
+			}
+			if reason, ok := secretReason(key, *values[key]); ok {
+				findings = append(findings, secretFinding{kind, key, *values[key], reason})
+			}
+		}
+	}
+	collect("environment variable", service.Environment)
+	if service.Build != nil {
+		collect("build argument", service.Build.Args)
+	}
+
+	threats := make([]common.Threat, 0, len(findings))
+	for _, finding := range findings {
+		// never log the value itself
+		a.logger.Info("Found plaintext secret", "service", service.Name, "kind", finding.kind, "key", finding.key, "value", maskSecret(finding.value))
+
+		id := common.GenerateIDHash(a.DockerComposeFilePath, fmt.Sprintf("threat:%s:secret:%s:%s", service.Name, finding.kind, finding.key))
+		threats = append(threats, common.Threat{
+			InternalID: id,
+			ID:         common.UUIDFormat(id),
+			Title:      fmt.Sprintf("Plaintext secret in %s %s", finding.kind, finding.key),
+			Status:     common.Open,
+			Severity:   "High",
+			Type:       common.InformationDisclosure,
+			Description: fmt.Sprintf("The %s %s holds %s (%s). Everyone with access to the compose file, the image or the container configuration can read it.",
+				finding.kind, finding.key, finding.reason, maskSecret(finding.value)),
+			Mitigation: fmt.Sprintf("Pass the secret with 'secrets:' or from a secret store instead of a plaintext value. If the value is no secret, add %s to '%s'.",
+				finding.key, ignoreSecretsExtension),
+			ModelType: common.STRIDE,
+			Source:    common.DataSourceDockerCompose,
+			MapIndex:  -1,
+		})
+	}
+	return threats
+}
+
+// secretReason checks if the value of a key looks like a secret and returns why
+func secretReason(key, value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	// absolute paths, e.g. to files in /run/secrets, point to a secret instead of holding it
+	if value == "" || strings.HasPrefix(value, "/") {
+		return "", false
+	}
+
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if password, ok := u.User.Password(); ok && password != "" {
+			return "a URL with a password", true
+		}
+	}
+
+	if isSecretKey(key) && !slices.Contains(nonSecretValues, strings.ToLower(value)) {
+		return "a hard-coded credential", true
+	}
+
+	if looksRandom(value) {
+		return "a random value that looks like a key or token", true
+	}
+	return "", false
+}
+
+// isSecretKey checks if the name of an environment variable or build argument indicates a credential
+func isSecretKey(key string) bool {
+	words := strings.FieldsFunc(strings.ToUpper(key), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 || slices.Contains(referenceKeyWords, words[len(words)-1]) {
+		return false
+	}
+	for i, word := range words {
+		if slices.Contains(secretKeyWords, word) {
+			return true
+		}
+		if i > 0 && slices.Contains(secretKeyPairs, [2]string{words[i-1], word}) {
+			return true
+		}
+	}
+	return false
+}
+
+// looksRandom checks if a value is a long mix of letters and digits with a high entropy, like generated keys and tokens
+func looksRandom(value string) bool {
+	if len(value) < minEntropyLength || strings.ContainsAny(value, " /:.") {
+		return false
+	}
+	if !strings.ContainsFunc(value, unicode.IsLetter) || !strings.ContainsFunc(value, unicode.IsDigit) {
+		return false
+	}
+	return shannonEntropy(value) >= minEntropy
+}
+
+// shannonEntropy returns the entropy of the value in bits per character
+func shannonEntropy(value string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range value {
+		counts[r]++
+	}
+	length := float64(len([]rune(value)))
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / length
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// maskSecret hides a secret value for logs, threats and the changelog.
+// No part of the value is kept, not even its length.
+func maskSecret(value string) string {
+	return "****"
+}
+
+// extensionStrings reads a list of strings from a compose extension
+func extensionStrings(extensions types.Extensions, name string) []string {
+	values, _ := extensions[name].([]any)
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,103 @@
+package dockercompose
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+func TestSecretThreats(t *testing.T) {
+	const filePath = "testdata/secrets/docker-compose.yml"
+	// the parser loads the .env file next to the compose file
+	project, err := NewDockerComposeParser(filePath, slog.Default()).ParseDockerComposeYML()
+	require.NoError(t, err)
+	an := NewDockerComposeAnalyzer(filePath, slog.Default())
+
+	tests := []struct {
+		service  string
+		expected []string
+	}{
+		{"db", []string{"Plaintext secret in environment variable POSTGRES_PASSWORD"}},
+		{"api", []string{
+			"Plaintext secret in environment variable DATABASE_URL",
+			"Plaintext secret in environment variable SESSION_SEED",
+			"Plaintext secret in environment variable SIGNING_KEY",
+			"Plaintext secret in build argument NPM_TOKEN",
+		}},
+		{"worker", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			threats := an.secretThreats(project, project.Services[tt.service])
+
+			titles := make([]string, 0, len(threats))
+			for _, threat := range threats {
+				titles = append(titles, threat.Title)
+				assert.Equal(t, common.InformationDisclosure, threat.Type)
+				assert.Equal(t, common.Open, threat.Status)
+				assert.Equal(t, common.UUIDFormat(threat.InternalID), threat.ID)
+				assert.NotEmpty(t, threat.Mitigation)
+			}
+			assert.Equal(t, tt.expected, titles)
+		})
+	}
+}
+
+func TestSecretThreatsMaskValues(t *testing.T) {
+	const filePath = "testdata/secrets/docker-compose.yml"
+	project, err := NewDockerComposeParser(filePath, slog.Default()).ParseDockerComposeYML()
+	require.NoError(t, err)
+	an := NewDockerComposeAnalyzer(filePath, slog.Default())
+
+	for _, name := range []string{"db", "api"} {
+		for _, threat := range an.secretThreats(project, project.Services[name]) {
+			for _, secret := range []string{"example", "s3cr3t-from-dotenv", "9fQ2xL7vR4kT1mZ8pW3sN6bY", "npm_a1B2c3D4e5F6g7H8i9J0"} {
+				assert.NotContains(t, threat.Title, secret)
+				assert.NotContains(t, threat.Description, secret)
+				assert.NotContains(t, threat.Description, secret[:2]+"****")
+			}
+		}
+	}
+}
+
+func TestSecretReason(t *testing.T) {
+	tests := []struct {
+		key, value string
+		expected   bool
+	}{
+		{"POSTGRES_PASSWORD", "example", true},
+		{"MYSQL_ROOT_PASSWORD", "root", true},
+		{"db_pass", "hunter2", true},
+		{"GITHUB_TOKEN", "ghp_abc", true},
+		{"AWS_SECRET_ACCESS_KEY", "abc", true},
+		{"STRIPE_API_KEY", "sk_test", true},
+		{"apiKey", "abc", true},
+		{"SIGNING_KEY", "abc", true},
+		{"KEY_ID", "abc", false},
+		{"CACHE_URL", "redis://:pass@cache:6379", true},
+		{"CACHE_URL", "redis://cache:6379", false},
+		{"RANDOM", "Zk3x9Qa7Lm2Vt8Rb5Wn1Yc4P", true},
+		{"COMMIT", "deadbeef", false},
+		{"POSTGRES_PASSWORD_FILE", "/run/secrets/db", false},
+		{"POSTGRES_USER", "app", false},
+		{"PASSWORD_AUTH", "true", false},
+		{"PASSWORD", "", false},
+		{"PWD", "/app", false},
+		{"JAVA_OPTS", "-Xmx512m -Xms256m -XX:MaxMetaspaceSize=128m", false},
+		{"IMAGE", "ghcr.io/org/app:1.2.3", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			_, ok := secretReason(tt.key, tt.value)
+			assert.Equal(t, tt.expected, ok)
+		})
+	}
+}
+
+func TestMaskSecret(t *testing.T) {
+	assert.Equal(t, "****", maskSecret("example"))
+	assert.Equal(t, "****", maskSecret("9fQ2xL7vR4kT1mZ8pW3sN6bY"))
+}
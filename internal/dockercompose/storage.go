@@ -0,0 +1,158 @@
+package dockercompose
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// storageKind is the kind of compose storage that becomes a data store asset
+type storageKind string
+
+const (
+	storageVolume storageKind = "volume"
+	storageBind   storageKind = "bind mount"
+	storageSecret storageKind = "secret"
+	storageConfig storageKind = "config"
+)
+
+// storageMount is a single mount of a storage into a service
+type storageMount struct {
+	service  string
+	kind     storageKind
+	name     string
+	readOnly bool
+}
+
+// storageAssets creates a data store asset for every named volume, bind mount, secret and config of the
+// project and a dataflow from every service to the stores it mounts. Secrets are marked as storing credentials.
+// Stores whose name is already taken by a service are suffixed with their kind.
+func (a *DockerComposeAnalyzer) storageAssets(proj *types.Project) ([]common.Asset, []common.DataFlow) {
+	mounts := a.storageMounts(proj)
+
+	stores := make(map[storageKind][]string)
+	for _, kind := range []storageKind{storageVolume, storageSecret, storageConfig} {
+		stores[kind] = declaredStorage(proj, kind)
+	}
+	for _, mount := range mounts {
+		if !slices.Contains(stores[mount.kind], mount.name) {
+			stores[mount.kind] = append(stores[mount.kind], mount.name)
+		}
+	}
+
+	taken := make(map[string]bool)
+	for name := range proj.Services {
+		taken[name] = true
+	}
+
+	assets := make([]common.Asset, 0)
+	displayNames := make(map[string]string) // storage key -> display name
+	for _, kind := range []storageKind{storageVolume, storageBind, storageSecret, storageConfig} {
+		for _, name := range stores[kind] {
+			displayName := name
+			if taken[displayName] {
+				displayName = fmt.Sprintf("%s (%s)", name, kind)
+			}
+			taken[displayName] = true
+			displayNames[storageKey(kind, name)] = displayName
+
+			asset := common.Asset{
+				ID:          common.GenerateIDHash(a.DockerComposeFilePath, storageKey(kind, name)),
+				DisplayName: displayName,
+				Type:        common.AssetTypeDatabase,
+				Source:      common.DataSourceDockerCompose,
+				Extra: map[string]any{
+					"DockerComposeKind": string(kind),
+				},
+				StoresCredentials: kind == storageSecret,
+			}
+			a.logger.Debug("Created a new instance of Asset for docker compose storage", "kind", kind, "name", name, "asset", asset)
+			assets = append(assets, asset)
+		}
+	}
+
+	flows := make([]common.DataFlow, 0, len(mounts))
+	for _, mount := range mounts {
+		key := storageKey(mount.kind, mount.name)
+		flow := common.DataFlow{
+			ID:            common.GenerateIDHash(a.DockerComposeFilePath, fmt.Sprintf("mount:%s->%s", mount.service, key)),
+			Name:          "read/write",
+			Source:        mount.service,
+			Target:        displayNames[key],
+			Bidirectional: true,
+			DataSource:    common.DataSourceDockerCompose,
+		}
+		if mount.readOnly {
+			flow.Name = "read"
+			flow.Source, flow.Target = displayNames[key], mount.service
+			flow.Bidirectional = false
+		}
+		if !slices.ContainsFunc(flows, func(f common.DataFlow) bool { return f.ID == flow.ID }) {
+			flows = append(flows, flow)
+		}
+	}
+	return assets, flows
+}
+
+// storageMounts collects the volumes, bind mounts, secrets and configs mounted by the services.
+// Anonymous volumes and tmpfs mounts hold no persistent data and are skipped.
+// Secrets and configs are always mounted read only.
+func (a *DockerComposeAnalyzer) storageMounts(proj *types.Project) []storageMount {
+	mounts := make([]storageMount, 0)
+	for _, name := range slices.Sorted(maps.Keys(proj.Services)) {
+		service := proj.Services[name]
+		for _, volume := range service.Volumes {
+			switch {
+			case volume.Type == types.VolumeTypeVolume && volume.Source != "":
+				mounts = append(mounts, storageMount{name, storageVolume, volume.Source, volume.ReadOnly})
+			case volume.Type == types.VolumeTypeBind && volume.Source != "":
+				mounts = append(mounts, storageMount{name, storageBind, bindName(proj.WorkingDir, volume.Source), volume.ReadOnly})
+			default:
+				a.logger.Debug("Mount holds no persistent data. Skipping.", "service", name, "type", volume.Type, "target", volume.Target)
+			}
+		}
+		for _, secret := range service.Secrets {
+			mounts = append(mounts, storageMount{name, storageSecret, secret.Source, true})
+		}
+		for _, config := range service.Configs {
+			mounts = append(mounts, storageMount{name, storageConfig, config.Source, true})
+		}
+	}
+	return mounts
+}
+
+// declaredStorage returns the sorted names of the top-level volumes, secrets or configs of the project
+func declaredStorage(proj *types.Project, kind storageKind) []string {
+	switch kind {
+	case storageVolume:
+		return slices.Sorted(maps.Keys(proj.Volumes))
+	case storageSecret:
+		return slices.Sorted(maps.Keys(proj.Secrets))
+	case storageConfig:
+		return slices.Sorted(maps.Keys(proj.Configs))
+	}
+	return nil
+}
+
+// bindName returns the host path of a bind mount relative to the project directory, so that the
+// name and ID of the store do not depend on where the project is checked out
+func bindName(workingDir, source string) string {
+	rel, err := filepath.Rel(workingDir, source)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return source
+	}
+	if rel == "." {
+		return "."
+	}
+	return "./" + filepath.ToSlash(rel)
+}
+
+// storageKey identifies a storage within the project
+func storageKey(kind storageKind, name string) string {
+	return fmt.Sprintf("%s:%s", kind, name)
+}
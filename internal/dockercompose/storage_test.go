@@ -0,0 +1,75 @@
+package dockercompose
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+func TestStorageAssets(t *testing.T) {
+	const filePath = "testdata/docker-compose-storage.yml"
+	an := NewDockerComposeAnalyzer(filePath, slog.Default())
+
+	assets, flows := an.storageAssets(loadTestProject(t, filePath))
+
+	expectedAssets := []struct {
+		name, kind, key   string
+		storesCredentials bool
+	}{
+		{"db (volume)", "volume", "volume:db", false},
+		{"unused", "volume", "volume:unused", false},
+		{"uploads", "volume", "volume:uploads", false},
+		{"./config", "bind mount", "bind mount:./config", false},
+		{"db_password", "secret", "secret:db_password", true},
+		{"api_settings", "config", "config:api_settings", false},
+	}
+	require.Len(t, assets, len(expectedAssets))
+	for i, e := range expectedAssets {
+		assert.Equal(t, e.name, assets[i].DisplayName)
+		assert.Equal(t, common.AssetTypeDatabase, assets[i].Type)
+		assert.Equal(t, e.kind, assets[i].Extra["DockerComposeKind"])
+		assert.Equal(t, e.storesCredentials, assets[i].StoresCredentials)
+		assert.Equal(t, common.GenerateIDHash(filePath, e.key), assets[i].ID)
+	}
+
+	expectedFlows := []struct {
+		source, target, name string
+		bidirectional        bool
+	}{
+		{"api", "uploads", "read/write", true},
+		{"./config", "api", "read", false},
+		{"db_password", "api", "read", false},
+		{"api_settings", "api", "read", false},
+		{"db", "db (volume)", "read/write", true},
+		{"db_password", "db", "read", false},
+	}
+	require.Len(t, flows, len(expectedFlows))
+	for i, e := range expectedFlows {
+		assert.Equal(t, e.source, flows[i].Source)
+		assert.Equal(t, e.target, flows[i].Target)
+		assert.Equal(t, e.name, flows[i].Name)
+		assert.Equal(t, e.bidirectional, flows[i].Bidirectional)
+		assert.False(t, flows[i].Inferred)
+	}
+}
+
+func TestBindName(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"/home/user/project/data", "./data"},
+		{"/home/user/project/conf/nginx.conf", "./conf/nginx.conf"},
+		{"/home/user/project", "."},
+		{"/var/run/docker.sock", "/var/run/docker.sock"},
+		{"/home/user/other", "/home/user/other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			assert.Equal(t, tt.expected, bindName("/home/user/project", tt.source))
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package kubernetes
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/threatcat-dev/threatcat/internal/common"
+	"github.com/threatcat-dev/threatcat/internal/dockercompose"
+)
+
+const defaultNamespace = "default"
+
+// KubernetesAnalyzer analyzes Kubernetes manifest files
+type KubernetesAnalyzer struct {
+	ManifestFilePath string
+	logger           *slog.Logger
+}
+
+// NewKubernetesAnalyzer creates a new instance of KubernetesAnalyzer
+func NewKubernetesAnalyzer(manifestFilePath string, logger *slog.Logger) *KubernetesAnalyzer {
+	return &KubernetesAnalyzer{
+		ManifestFilePath: manifestFilePath,
+		logger:           logger.With("package", "kubernetes", "component", "KubernetesAnalyzer"),
+	}
+}
+
+// Analyze analyzes the given manifests and returns a threat model.
+// Workloads become assets, namespaces become trust boundaries. Ingresses become webserver assets
+// with dataflows to the workloads that are selected by the services referenced in their backends.
+func (a *KubernetesAnalyzer) Analyze(manifests *Manifests, imageMap dockercompose.DockerImageMap) (*common.ThreatModel, error) {
+	if imageMap == nil {
+		return nil, fmt.Errorf("no handler for the docker image analysis was given")
+	}
+	if manifests == nil {
+		return nil, fmt.Errorf("no manifests to analyze were given")
+	}
+
+	model := common.EmptyThreatModel()
+	a.logger.Debug("Beginning kubernetes analysis", "workloadCount", len(manifests.Workloads))
+
+	for _, workload := range manifests.Workloads {
+		namespace := namespaceOf(workload.Metadata)
+		asset := common.Asset{
+			ID:          a.workloadID(workload),
+			DisplayName: workload.Metadata.Name,
+			Type:        a.workloadAssetType(workload, imageMap),
+			Source:      common.DataSourceKubernetes,
+			Extra: map[string]any{
+				"KubernetesKind":      workload.Kind,
+				"KubernetesNamespace": namespace,
+			},
+		}
+
+		if services := a.selectingServices(workload, manifests.Services); len(services) > 0 {
+			asset.Extra["KubernetesServices"] = services
+		}
+
+		a.logger.Debug("Created a new instance of Asset for kubernetes workload", "kind", workload.Kind, "name", workload.Metadata.Name, "asset", asset)
+		model.Assets = append(model.Assets, asset)
+		a.addToNamespaceBoundary(&model, namespace, asset.ID)
+	}
+
+	for _, ingress := range manifests.Ingresses {
+		namespace := namespaceOf(ingress.Metadata)
+		asset := common.Asset{
+			ID:          common.GenerateIDHash(a.ManifestFilePath, resourceKey("Ingress", namespace, ingress.Metadata.Name)),
+			DisplayName: ingress.Metadata.Name,
+			Type:        common.AssetTypeWebserver,
+			Source:      common.DataSourceKubernetes,
+			Extra: map[string]any{
+				"KubernetesKind":      "Ingress",
+				"KubernetesNamespace": namespace,
+			},
+		}
+		model.Assets = append(model.Assets, asset)
+		a.addToNamespaceBoundary(&model, namespace, asset.ID)
+
+		model.DataFlows = append(model.DataFlows, a.ingressDataFlows(ingress, manifests)...)
+	}
+
+	a.logger.Debug("Kubernetes analysis finished", "assetCount", len(model.Assets), "boundaryCount", len(model.Boundaries), "dataflowCount", len(model.DataFlows))
+	return &model, nil
+}
+
+// workloadID generates a unique ID for a workload by hashing the file path and the resource key
+func (a *KubernetesAnalyzer) workloadID(workload Workload) string {
+	return common.GenerateIDHash(a.ManifestFilePath, resourceKey(workload.Kind, namespaceOf(workload.Metadata), workload.Metadata.Name))
+}
+
+// workloadAssetType determines the asset type of a workload based on its container images.
+// The first container with a known image determines the type. Init containers are not considered,
+// as they do not describe the purpose of the workload.
+func (a *KubernetesAnalyzer) workloadAssetType(workload Workload, imageMap dockercompose.DockerImageMap) common.AssetType {
+	for _, container := range workload.Spec.Template.Spec.Containers {
+		assetType := imageMap.DetermineAssetType(container.Image, a.logger)
+		if assetType != common.AssetTypeUnknown {
+			return assetType
+		}
+	}
+	return common.AssetTypeUnknown
+}
+
+// selectingServices returns the names of all services in the namespace of the workload
+// whose selector matches the pod template labels of the workload.
+func (a *KubernetesAnalyzer) selectingServices(workload Workload, services []Service) []string {
+	names := make([]string, 0)
+	for _, service := range services {
+		if namespaceOf(service.Metadata) != namespaceOf(workload.Metadata) {
+			continue
+		}
+		if selectorMatches(service.Spec.Selector, workload.Spec.Template.Metadata.Labels) {
+			names = append(names, service.Metadata.Name)
+		}
+	}
+	return names
+}
+
+// addToNamespaceBoundary adds the asset to the trust boundary of the namespace.
+// The boundary is created if it does not exist yet.
+func (a *KubernetesAnalyzer) addToNamespaceBoundary(model *common.ThreatModel, namespace, assetID string) {
+	boundaryID := common.GenerateIDHash(a.ManifestFilePath, resourceKey("Namespace", "", namespace))
+	index := slices.IndexFunc(model.Boundaries, func(b common.TrustBoundary) bool {
+		return b.ID == boundaryID
+	})
+	if index < 0 {
+		model.Boundaries = append(model.Boundaries, common.TrustBoundary{
+			ID:              boundaryID,
+			DisplayName:     namespace,
+			ContainedAssets: []string{},
+			Source:          common.DataSourceKubernetes,
+			Extra: map[string]any{
+				"initial-description": "Kubernetes namespace",
+			},
+		})
+		index = len(model.Boundaries) - 1
+	}
+	model.Boundaries[index].ContainedAssets = append(model.Boundaries[index].ContainedAssets, assetID)
+}
+
+// ingressDataFlows creates a dataflow from the ingress to each workload that is selected
+// by a service referenced in the ingress backends.
+func (a *KubernetesAnalyzer) ingressDataFlows(ingress Ingress, manifests *Manifests) []common.DataFlow {
+	namespace := namespaceOf(ingress.Metadata)
+	protocol := "http"
+	if len(ingress.Spec.TLS) > 0 {
+		protocol = "https"
+	}
+
+	// collect the referenced service names in a deterministic order without duplicates
+	backendServices := make([]string, 0)
+	addBackend := func(backend *IngressBackend) {
+		if backend == nil || backend.Service == nil || slices.Contains(backendServices, backend.Service.Name) {
+			return
+		}
+		backendServices = append(backendServices, backend.Service.Name)
+	}
+	addBackend(ingress.Spec.DefaultBackend)
+	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			addBackend(&path.Backend)
+		}
+	}
+
+	flows := make([]common.DataFlow, 0)
+	for _, serviceName := range backendServices {
+		serviceIdx := slices.IndexFunc(manifests.Services, func(s Service) bool {
+			return s.Metadata.Name == serviceName && namespaceOf(s.Metadata) == namespace
+		})
+		if serviceIdx < 0 {
+			a.logger.Warn("Ingress references a service that is not part of the manifests", "ingress", ingress.Metadata.Name, "service", serviceName)
+			continue
+		}
+		service := manifests.Services[serviceIdx]
+
+		for _, workload := range manifests.Workloads {
+			if namespaceOf(workload.Metadata) != namespace || !selectorMatches(service.Spec.Selector, workload.Spec.Template.Metadata.Labels) {
+				continue
+			}
+			name := fmt.Sprintf("%s via %s", ingress.Metadata.Name, serviceName)
+			flows = append(flows, common.DataFlow{
+				ID:            common.GenerateIDHash(a.ManifestFilePath, resourceKey("Ingress", namespace, ingress.Metadata.Name)+"->"+a.workloadID(workload)),
+				Name:          name,
+				Protocol:      protocol,
+				Encrypted:     protocol == "https",
+				PublicNetwork: false,
+				Source:        ingress.Metadata.Name,
+				Target:        workload.Metadata.Name,
+				DataSource:    common.DataSourceKubernetes,
+			})
+		}
+	}
+	return flows
+}
+
+// selectorMatches checks if all key value pairs of the selector are contained in the labels.
+// An empty selector matches nothing, as a service without selector has manually managed endpoints.
+func selectorMatches(selector map[string]string, labels map[string]string) bool {
+	if len(selector) == 0 {
+		return false
+	}
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// namespaceOf returns the namespace of the resource. Resources without namespace belong to the default namespace.
+func namespaceOf(meta ObjectMeta) string {
+	if meta.Namespace == "" {
+		return defaultNamespace
+	}
+	return meta.Namespace
+}
+
+// resourceKey builds a key that identifies a resource within a manifest file
+func resourceKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
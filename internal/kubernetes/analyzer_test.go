@@ -0,0 +1,97 @@
+package kubernetes
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+	"github.com/threatcat-dev/threatcat/internal/dockercompose"
+)
+
+// TestAnalyzer tests the Analyze method of KubernetesAnalyzer with the test manifest file
+func TestAnalyzer(t *testing.T) {
+	const filePath = "testdata/manifests-for-test.yml"
+
+	imageMap, err := dockercompose.NewDockerImageMap("")
+	require.NoError(t, err)
+
+	manifests, err := NewKubernetesParser(filePath, slog.Default()).Parse()
+	require.NoError(t, err)
+
+	model, err := NewKubernetesAnalyzer(filePath, slog.Default()).Analyze(manifests, imageMap)
+	require.NoError(t, err)
+
+	expectedAssets := []struct {
+		displayName string
+		assetType   common.AssetType
+		namespace   string
+	}{
+		{"web", common.AssetTypeWebserver, "shop"},
+		{"db", common.AssetTypeDatabase, "shop"},
+		{"log-agent", common.AssetTypeInfrastructure, "default"},
+		{"shop-ingress", common.AssetTypeWebserver, "shop"},
+	}
+
+	require.Len(t, model.Assets, len(expectedAssets))
+	for i, expected := range expectedAssets {
+		asset := model.Assets[i]
+		assert.Equal(t, expected.displayName, asset.DisplayName)
+		assert.Equal(t, expected.assetType, asset.Type)
+		assert.Equal(t, common.DataSourceKubernetes, asset.Source)
+		assert.Equal(t, expected.namespace, asset.Extra["KubernetesNamespace"])
+		assert.Len(t, asset.ID, common.MaxIDHashLength)
+	}
+	assert.Equal(t, []string{"web-svc"}, model.Assets[0].Extra["KubernetesServices"])
+
+	require.Len(t, model.Boundaries, 2)
+	assert.Equal(t, "shop", model.Boundaries[0].DisplayName)
+	assert.ElementsMatch(t, []string{model.Assets[0].ID, model.Assets[1].ID, model.Assets[3].ID}, model.Boundaries[0].ContainedAssets)
+	assert.Equal(t, "default", model.Boundaries[1].DisplayName)
+	assert.Equal(t, []string{model.Assets[2].ID}, model.Boundaries[1].ContainedAssets)
+
+	// the backend referencing a missing service is skipped
+	require.Len(t, model.DataFlows, 1)
+	flow := model.DataFlows[0]
+	assert.Equal(t, "shop-ingress", flow.Source)
+	assert.Equal(t, "web", flow.Target)
+	assert.Equal(t, "https", flow.Protocol)
+	assert.True(t, flow.Encrypted)
+	assert.Len(t, flow.ID, common.MaxIDHashLength)
+}
+
+// TestAnalyzerStableIDs ensures that IDs only depend on file path and resource identity
+func TestAnalyzerStableIDs(t *testing.T) {
+	imageMap, err := dockercompose.NewDockerImageMap("")
+	require.NoError(t, err)
+
+	manifests := &Manifests{Workloads: []Workload{
+		{Kind: "Deployment", Metadata: ObjectMeta{Name: "api"}},
+		{Kind: "Deployment", Metadata: ObjectMeta{Name: "api", Namespace: "other"}},
+	}}
+
+	first, err := NewKubernetesAnalyzer("a.yml", slog.Default()).Analyze(manifests, imageMap)
+	require.NoError(t, err)
+	second, err := NewKubernetesAnalyzer("a.yml", slog.Default()).Analyze(manifests, imageMap)
+	require.NoError(t, err)
+
+	assert.Equal(t, first.Assets[0].ID, second.Assets[0].ID)
+	assert.NotEqual(t, first.Assets[0].ID, first.Assets[1].ID, "same name in different namespaces must not collide")
+	assert.Equal(t, common.AssetTypeUnknown, first.Assets[0].Type)
+}
+
+func TestAnalyzerWithNoDockerImageMap(t *testing.T) {
+	_, err := NewKubernetesAnalyzer("testdata/manifests-for-test.yml", slog.Default()).Analyze(&Manifests{}, nil)
+	assert.Error(t, err, "Expected an error when DockerImageMap is nil")
+}
+
+func TestSelectorMatches(t *testing.T) {
+	labels := map[string]string{"app": "web", "tier": "frontend"}
+
+	assert.True(t, selectorMatches(map[string]string{"app": "web"}, labels))
+	assert.True(t, selectorMatches(map[string]string{"app": "web", "tier": "frontend"}, labels))
+	assert.False(t, selectorMatches(map[string]string{"app": "db"}, labels))
+	assert.False(t, selectorMatches(map[string]string{"app": "web", "env": "prod"}, labels))
+	assert.False(t, selectorMatches(nil, labels), "empty selectors do not select pods")
+}
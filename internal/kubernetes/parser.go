@@ -0,0 +1,224 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KubernetesParser parses multi-document Kubernetes manifest files
+type KubernetesParser struct {
+	filePath string
+	logger   *slog.Logger
+}
+
+// NewKubernetesParser creates a new instance of KubernetesParser
+func NewKubernetesParser(filePath string, logger *slog.Logger) *KubernetesParser {
+	return &KubernetesParser{
+		filePath: filePath,
+		logger:   logger.With("package", "kubernetes", "component", "KubernetesParser"),
+	}
+}
+
+// Manifests holds all resources of a manifest file that are relevant for the analysis.
+// Resources of other kinds are skipped while parsing.
+type Manifests struct {
+	Workloads []Workload
+	Services  []Service
+	Ingresses []Ingress
+}
+
+// Workload represents a Deployment, StatefulSet or DaemonSet
+type Workload struct {
+	Kind     string
+	Metadata ObjectMeta
+	Spec     WorkloadSpec
+}
+
+// Service represents a Kubernetes Service
+type Service struct {
+	Metadata ObjectMeta
+	Spec     ServiceSpec
+}
+
+// Ingress represents a Kubernetes Ingress
+type Ingress struct {
+	Metadata ObjectMeta
+	Spec     IngressSpec
+}
+
+type ObjectMeta struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace"`
+	Labels    map[string]string `yaml:"labels"`
+}
+
+type WorkloadSpec struct {
+	Template PodTemplateSpec `yaml:"template"`
+}
+
+type PodTemplateSpec struct {
+	Metadata ObjectMeta `yaml:"metadata"`
+	Spec     PodSpec    `yaml:"spec"`
+}
+
+type PodSpec struct {
+	Containers     []Container `yaml:"containers"`
+	InitContainers []Container `yaml:"initContainers"`
+}
+
+type Container struct {
+	Name  string          `yaml:"name"`
+	Image string          `yaml:"image"`
+	Ports []ContainerPort `yaml:"ports"`
+}
+
+type ContainerPort struct {
+	Name          string `yaml:"name"`
+	ContainerPort int    `yaml:"containerPort"`
+	Protocol      string `yaml:"protocol"`
+}
+
+type ServiceSpec struct {
+	Type     string            `yaml:"type"`
+	Selector map[string]string `yaml:"selector"`
+	Ports    []ServicePort     `yaml:"ports"`
+}
+
+type ServicePort struct {
+	Name     string `yaml:"name"`
+	Port     int    `yaml:"port"`
+	Protocol string `yaml:"protocol"`
+}
+
+type IngressSpec struct {
+	IngressClassName string          `yaml:"ingressClassName"`
+	DefaultBackend   *IngressBackend `yaml:"defaultBackend"`
+	TLS              []IngressTLS    `yaml:"tls"`
+	Rules            []IngressRule   `yaml:"rules"`
+}
+
+type IngressTLS struct {
+	Hosts      []string `yaml:"hosts"`
+	SecretName string   `yaml:"secretName"`
+}
+
+type IngressRule struct {
+	Host string           `yaml:"host"`
+	HTTP *IngressRuleHTTP `yaml:"http"`
+}
+
+type IngressRuleHTTP struct {
+	Paths []IngressPath `yaml:"paths"`
+}
+
+type IngressPath struct {
+	Path    string         `yaml:"path"`
+	Backend IngressBackend `yaml:"backend"`
+}
+
+type IngressBackend struct {
+	Service *IngressServiceBackend `yaml:"service"`
+}
+
+type IngressServiceBackend struct {
+	Name string `yaml:"name"`
+}
+
+// resourceHeader is used to determine the kind of a document before decoding its spec
+type resourceHeader struct {
+	APIVersion string      `yaml:"apiVersion"`
+	Kind       string      `yaml:"kind"`
+	Metadata   ObjectMeta  `yaml:"metadata"`
+	Spec       yaml.Node   `yaml:"spec"`
+	Items      []yaml.Node `yaml:"items"`
+}
+
+// Parse reads all YAML documents of the manifest file and collects the relevant resources.
+func (kp *KubernetesParser) Parse() (*Manifests, error) {
+	kp.logger.Debug("Opening manifest file for parsing", "filePath", kp.filePath)
+	file, err := os.Open(kp.filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	manifests := &Manifests{}
+	decoder := yaml.NewDecoder(file)
+	for docIdx := 0; ; docIdx++ {
+		var header resourceHeader
+		err := decoder.Decode(&header)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode document %d of manifest file: %w", docIdx, err)
+		}
+
+		if err := kp.addResource(manifests, header); err != nil {
+			return nil, fmt.Errorf("failed to decode document %d of manifest file: %w", docIdx, err)
+		}
+	}
+
+	kp.logger.Info("Successfully parsed manifest file", "workloads", len(manifests.Workloads), "services", len(manifests.Services), "ingresses", len(manifests.Ingresses))
+	return manifests, nil
+}
+
+// addResource decodes the spec of a single resource and adds it to the manifests.
+// Lists (e.g. the output of 'kubectl get -o yaml') are unpacked recursively.
+func (kp *KubernetesParser) addResource(manifests *Manifests, header resourceHeader) error {
+	logger := kp.logger.With("kind", header.Kind, "name", header.Metadata.Name)
+
+	switch header.Kind {
+	case "":
+		// empty documents, e.g. a trailing '---'
+		return nil
+	case "List":
+		for _, item := range header.Items {
+			var itemHeader resourceHeader
+			if err := item.Decode(&itemHeader); err != nil {
+				return err
+			}
+			if err := kp.addResource(manifests, itemHeader); err != nil {
+				return err
+			}
+		}
+	case "Deployment", "StatefulSet", "DaemonSet":
+		var spec WorkloadSpec
+		if err := decodeSpec(header.Spec, &spec); err != nil {
+			return err
+		}
+		manifests.Workloads = append(manifests.Workloads, Workload{Kind: header.Kind, Metadata: header.Metadata, Spec: spec})
+	case "Service":
+		var spec ServiceSpec
+		if err := decodeSpec(header.Spec, &spec); err != nil {
+			return err
+		}
+		manifests.Services = append(manifests.Services, Service{Metadata: header.Metadata, Spec: spec})
+	case "Ingress":
+		var spec IngressSpec
+		if err := decodeSpec(header.Spec, &spec); err != nil {
+			return err
+		}
+		manifests.Ingresses = append(manifests.Ingresses, Ingress{Metadata: header.Metadata, Spec: spec})
+	default:
+		logger.Debug("Resource kind is not relevant for the analysis. Skipping.")
+		return nil
+	}
+
+	logger.Debug("Parsed resource")
+	return nil
+}
+
+// decodeSpec decodes the spec node into the given struct.
+// A missing spec leaves the struct at its zero value.
+func decodeSpec(node yaml.Node, spec any) error {
+	if node.Kind == 0 {
+		return nil
+	}
+	return node.Decode(spec)
+}
@@ -0,0 +1,72 @@
+package kubernetes
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Confirm that all relevant resources of a multi-document manifest are parsed
+func TestParser_ParseValidManifest(t *testing.T) {
+	parser := NewKubernetesParser("testdata/manifests-for-test.yml", slog.Default())
+	manifests, err := parser.Parse()
+	require.NoError(t, err)
+
+	require.Len(t, manifests.Workloads, 3)
+	assert.Equal(t, "Deployment", manifests.Workloads[0].Kind)
+	assert.Equal(t, "web", manifests.Workloads[0].Metadata.Name)
+	assert.Equal(t, "shop", manifests.Workloads[0].Metadata.Namespace)
+	assert.Equal(t, "nginx:1.27", manifests.Workloads[0].Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, map[string]string{"app": "web"}, manifests.Workloads[0].Spec.Template.Metadata.Labels)
+	assert.Equal(t, "StatefulSet", manifests.Workloads[1].Kind)
+	assert.Equal(t, "DaemonSet", manifests.Workloads[2].Kind)
+
+	require.Len(t, manifests.Services, 1)
+	assert.Equal(t, map[string]string{"app": "web"}, manifests.Services[0].Spec.Selector)
+
+	require.Len(t, manifests.Ingresses, 1)
+	assert.Len(t, manifests.Ingresses[0].Spec.TLS, 1)
+	assert.Equal(t, "web-svc", manifests.Ingresses[0].Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name)
+}
+
+// Confirm that resources wrapped in a List are unpacked
+func TestParser_ParseList(t *testing.T) {
+	content := `apiVersion: v1
+kind: List
+items:
+  - apiVersion: apps/v1
+    kind: Deployment
+    metadata:
+      name: api
+  - apiVersion: v1
+    kind: Service
+    metadata:
+      name: api
+`
+	fpath := filepath.Join(t.TempDir(), "list.yml")
+	require.NoError(t, os.WriteFile(fpath, []byte(content), 0600))
+
+	manifests, err := NewKubernetesParser(fpath, slog.Default()).Parse()
+	require.NoError(t, err)
+	assert.Len(t, manifests.Workloads, 1)
+	assert.Len(t, manifests.Services, 1)
+}
+
+// Confirm parser rejects non existing files
+func TestParser_PathToNonExistingFileWillFail(t *testing.T) {
+	_, err := NewKubernetesParser("non_existing_file.yml", slog.Default()).Parse()
+	assert.Error(t, err)
+}
+
+// Confirm parser rejects malformed documents
+func TestParser_MalformedDocumentWillFail(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "broken.yml")
+	require.NoError(t, os.WriteFile(fpath, []byte("kind: Deployment\nspec: [unclosed\n"), 0600))
+
+	_, err := NewKubernetesParser(fpath, slog.Default()).Parse()
+	assert.Error(t, err)
+}
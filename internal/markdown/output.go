@@ -0,0 +1,221 @@
+package markdown
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// MarkdownOutput writes a threat model as a Markdown report with tables of its threats, assets, dataflows and trust boundaries
+type MarkdownOutput struct {
+	OutputPath string
+	// Title is the heading of the report
+	Title  string
+	cl     changelog
+	logger *slog.Logger
+}
+
+type changelog interface {
+	AddEntry(string)
+}
+
+func NewMarkdownOutput(outputPath string, cl changelog, logger *slog.Logger) *MarkdownOutput {
+	return &MarkdownOutput{
+		OutputPath: outputPath,
+		Title:      "Threat Model Report",
+		cl:         cl,
+		logger:     logger.With("package", "markdown", "component", "MarkdownOutput"),
+	}
+}
+
+// Generate writes the report. Existing files are overwritten.
+func (mo *MarkdownOutput) Generate(model *common.ThreatModel) error {
+	mo.logger.Debug("Generating Markdown report")
+
+	err := os.MkdirAll(filepath.Dir(mo.OutputPath), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(mo.OutputPath, []byte(mo.report(model)), 0644)
+	if err != nil {
+		return err
+	}
+
+	mo.cl.AddEntry(fmt.Sprintf("Markdown report has been generated with %d threats", len(elementThreats(model))))
+	mo.logger.Debug("Markdown report has been written to file", "filePath", mo.OutputPath)
+	return nil
+}
+
+// elementThreat is a threat with the name of the asset or dataflow it belongs to
+type elementThreat struct {
+	element string
+	threat  common.Threat
+}
+
+// elementThreats returns the threats of all assets and dataflows, the most severe first
+func elementThreats(model *common.ThreatModel) []elementThreat {
+	threats := make([]elementThreat, 0)
+	for _, asset := range model.Assets {
+		for _, threat := range asset.Threats {
+			threats = append(threats, elementThreat{element: asset.DisplayName, threat: threat})
+		}
+	}
+	for _, dataflow := range model.DataFlows {
+		for _, threat := range dataflow.Threats {
+			threats = append(threats, elementThreat{element: dataflow.Name, threat: threat})
+		}
+	}
+	slices.SortStableFunc(threats, func(a, b elementThreat) int {
+		return cmp.Compare(severityRank(b.threat.Severity), severityRank(a.threat.Severity))
+	})
+	return threats
+}
+
+func (mo *MarkdownOutput) report(model *common.ThreatModel) string {
+	threats := elementThreats(model)
+	open := 0
+	for _, t := range threats {
+		if t.threat.Status == common.Open {
+			open++
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", mo.Title)
+	b.WriteString("This report is auto generated by threatcat.\n\n")
+
+	b.WriteString("## Summary\n\n")
+	writeTable(&b, []string{"Element", "Count"}, [][]string{
+		{"Assets", fmt.Sprint(len(model.Assets))},
+		{"Dataflows", fmt.Sprint(len(model.DataFlows))},
+		{"Trust boundaries", fmt.Sprint(len(model.Boundaries))},
+		{"Threats", fmt.Sprint(len(threats))},
+		{"Open threats", fmt.Sprint(open)},
+	})
+
+	b.WriteString("## Threats\n\n")
+	if len(threats) == 0 {
+		b.WriteString("No threats have been found.\n\n")
+	} else {
+		rows := make([][]string, 0, len(threats))
+		for _, t := range threats {
+			rows = append(rows, []string{t.element, t.threat.Title, threatType(t.threat.Type), t.threat.Severity,
+				common.StatusString(t.threat.Status), t.threat.Description, t.threat.Mitigation})
+		}
+		writeTable(&b, []string{"Element", "Threat", "Type", "Severity", "Status", "Description", "Mitigation"}, rows)
+	}
+
+	b.WriteString("## Assets\n\n")
+	rows := make([][]string, 0, len(model.Assets))
+	for _, asset := range model.Assets {
+		boundaries := make([]string, 0)
+		for _, boundary := range model.Boundaries {
+			if slices.Contains(boundary.ContainedAssets, asset.ID) {
+				boundaries = append(boundaries, boundary.DisplayName)
+			}
+		}
+		rows = append(rows, []string{asset.DisplayName, assetTypes[asset.Type], asset.Source.ShortString(),
+			strings.Join(boundaries, ", "), yesNo(asset.StoresCredentials), fmt.Sprint(len(asset.Threats))})
+	}
+	writeTable(&b, []string{"Asset", "Type", "Source", "Trust boundaries", "Stores credentials", "Threats"}, rows)
+
+	b.WriteString("## Dataflows\n\n")
+	rows = make([][]string, 0, len(model.DataFlows))
+	for _, dataflow := range model.DataFlows {
+		direction := "→"
+		if dataflow.Bidirectional {
+			direction = "↔"
+		}
+		rows = append(rows, []string{dataflow.Name, fmt.Sprintf("%s %s %s", dataflow.Source, direction, dataflow.Target),
+			dataflow.Protocol, yesNo(dataflow.Encrypted), yesNo(dataflow.PublicNetwork), fmt.Sprint(len(dataflow.Threats))})
+	}
+	writeTable(&b, []string{"Dataflow", "Endpoints", "Protocol", "Encrypted", "Public network", "Threats"}, rows)
+
+	b.WriteString("## Trust Boundaries\n\n")
+	names := make(map[string]string, len(model.Assets)) // asset ID -> display name
+	for _, asset := range model.Assets {
+		names[asset.ID] = asset.DisplayName
+	}
+	rows = make([][]string, 0, len(model.Boundaries))
+	for _, boundary := range model.Boundaries {
+		contained := make([]string, 0, len(boundary.ContainedAssets))
+		for _, id := range boundary.ContainedAssets {
+			if name, ok := names[id]; ok {
+				contained = append(contained, name)
+			}
+		}
+		rows = append(rows, []string{boundary.DisplayName, boundary.Source.ShortString(), strings.Join(contained, ", ")})
+	}
+	writeTable(&b, []string{"Trust boundary", "Source", "Assets"}, rows)
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// writeTable writes a Markdown table followed by an empty line. Tables without rows are written as a note.
+func writeTable(b *strings.Builder, header []string, rows [][]string) {
+	if len(rows) == 0 {
+		b.WriteString("None.\n\n")
+		return
+	}
+	b.WriteString("| " + strings.Join(header, " | ") + " |\n")
+	b.WriteString("|" + strings.Repeat(" --- |", len(header)) + "\n")
+	for _, row := range rows {
+		cells := make([]string, 0, len(row))
+		for _, cell := range row {
+			cells = append(cells, escapeCell(cell))
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	b.WriteString("\n")
+}
+
+// escapeCell keeps a text in a single table cell by escaping pipes and replacing line breaks
+func escapeCell(text string) string {
+	text = strings.ReplaceAll(strings.TrimSpace(text), "|", "\\|")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.ReplaceAll(text, "\n", "<br>")
+}
+
+var assetTypes = map[common.AssetType]string{
+	common.AssetTypeUnknown:        "Unknown",
+	common.AssetTypeApplication:    "Application",
+	common.AssetTypeDatabase:       "Database",
+	common.AssetTypeWebserver:      "Webserver",
+	common.AssetTypeInfrastructure: "Infrastructure",
+	common.AssetTypeExternalEntity: "External entity",
+}
+
+func threatType(threatType common.ThreatType) string {
+	if threatType == common.ThreatTypeUnknown {
+		return "Unknown"
+	}
+	return common.TypeString(threatType)
+}
+
+// severityRank orders the severities of Threat Dragon, unknown severities rank lowest
+func severityRank(severity string) int {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return 4
+	case "high":
+		return 3
+	case "medium":
+		return 2
+	case "low":
+		return 1
+	}
+	return 0
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
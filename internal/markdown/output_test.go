@@ -0,0 +1,89 @@
+package markdown
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// recordingChangelog keeps all entries for assertions
+type recordingChangelog struct {
+	entries *[]string
+}
+
+func (rc recordingChangelog) AddEntry(entry string) {
+	*rc.entries = append(*rc.entries, entry)
+}
+
+func testModel() *common.ThreatModel {
+	return &common.ThreatModel{
+		Assets: []common.Asset{
+			{ID: "web", DisplayName: "web", Type: common.AssetTypeWebserver, Source: common.DataSourceDockerCompose,
+				Threats: []common.Threat{
+					{Title: "Unpinned image", Type: common.Tampering, Severity: "Low", Status: common.Open, Mitigation: "Pin the image by digest"},
+				}},
+			{ID: "db", DisplayName: "db", Type: common.AssetTypeDatabase, Source: common.DataSourceDockerCompose, StoresCredentials: true,
+				Threats: []common.Threat{
+					{Title: "Plaintext secret", Type: common.InformationDisclosure, Severity: "High", Status: common.Mitigated,
+						Description: "POSTGRES_PASSWORD=se***\nis set in plain text | env", Mitigation: "Use a secret"},
+				}},
+		},
+		DataFlows: []common.DataFlow{
+			{ID: "1", Name: "sql", Source: "web", Target: "db", Protocol: "postgresql", Bidirectional: true,
+				Threats: []common.Threat{{Title: "Unencrypted queries", Type: common.InformationDisclosure, Severity: "Medium"}}},
+		},
+		Boundaries: []common.TrustBoundary{
+			{ID: "backend", DisplayName: "backend", ContainedAssets: []string{"web", "db", "gone"}, Source: common.DataSourceDockerCompose},
+		},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	const path = "testdata/testoutput_report.md"
+	defer os.Remove(path)
+
+	entries := []string{}
+	output := NewMarkdownOutput(path, recordingChangelog{entries: &entries}, slog.Default())
+	require.NoError(t, output.Generate(testModel()))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	report := string(content)
+
+	assert.True(t, strings.HasPrefix(report, "# Threat Model Report\n"))
+	assert.True(t, strings.HasSuffix(report, "|\n"))
+	assert.Contains(t, report, "| Threats | 3 |\n| Open threats | 2 |\n")
+	assert.Contains(t, entries, "Markdown report has been generated with 3 threats")
+
+	// threats are ordered by severity, their cells keep the table intact
+	high := strings.Index(report, "| db | Plaintext secret |")
+	medium := strings.Index(report, "| sql | Unencrypted queries |")
+	low := strings.Index(report, "| web | Unpinned image |")
+	assert.True(t, high > 0 && high < medium && medium < low)
+	assert.Contains(t, report, "| db | Plaintext secret | Information disclosure | High | Mitigated | POSTGRES_PASSWORD=se***<br>is set in plain text \\| env | Use a secret |\n")
+
+	assert.Contains(t, report, "| db | Database | Docker Compose | backend | yes | 1 |\n")
+	assert.Contains(t, report, "| sql | web ↔ db | postgresql | no | no | 1 |\n")
+	assert.Contains(t, report, "| backend | Docker Compose | web, db |\n")
+}
+
+func TestGenerate_Empty(t *testing.T) {
+	const path = "testdata/testoutput_report_empty.md"
+	defer os.Remove(path)
+
+	entries := []string{}
+	output := NewMarkdownOutput(path, recordingChangelog{entries: &entries}, slog.Default())
+	output.Title = "Empty"
+	model := common.EmptyThreatModel()
+	require.NoError(t, output.Generate(&model))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(content), "# Empty\n")
+	assert.Contains(t, string(content), "## Threats\n\nNo threats have been found.\n")
+	assert.Contains(t, string(content), "## Assets\n\nNone.\n")
+}
@@ -59,7 +59,7 @@ func (mm *ModelMerger) mergeModels(models []common.ThreatModel) common.ThreatMod
 		for _, asset := range model.Assets {
 			assetMap[asset.ID] = append(assetMap[asset.ID], asset)
 		}
-		for _, dataflow := range model.DataFlows {
+		for _, dataflow := range mm.withoutInferredDuplicates(model.DataFlows, models) {
 			dataflowMap[dataflow.ID] = append(dataflowMap[dataflow.ID], dataflow)
 		}
 		for _, boundary := range model.Boundaries {
@@ -96,7 +96,7 @@ func (mm *ModelMerger) mergeModels(models []common.ThreatModel) common.ThreatMod
 		if len(boundaries) > 1 {
 			logger.Debug("Multiple TrustBoundary instaces for this ID. Merging.", "bondaryCount", len(boundaries))
 			mergedBoundaries = append(mergedBoundaries, mergeableBoundaries(boundaries).merge(mergedAssets, logger))
-		} else if boundaries[0].Source != common.DataSourceThreatDragon {
+		} else if common.GetOr(boundaries[0].Extra, "IsGeneratedByUser", false) || boundaries[0].Source != common.DataSourceThreatDragon {
 			logger.Debug("Boundary was found in a single original source. Keeping.")
 			mergedBoundaries = append(mergedBoundaries, boundaries[0])
 		} else {
@@ -113,15 +113,23 @@ func (mm *ModelMerger) mergeModels(models []common.ThreatModel) common.ThreatMod
 	mergedDataflows := make([]common.DataFlow, 0, len(dataflowMap))
 	for _, dataflows := range dataflowMap {
 		logger := mm.logger.With("dataflows[0].ID", dataflows[0].ID)
+		// same conditions as for assets: dataflows of a single source are kept, unless they were
+		// generated by the tool into a Threat Dragon model and are no longer found in their original source.
 		if len(dataflows) > 1 {
-			// TODO: Implement
-			logger.Error("Not yet implemented")
-			panic("not yet implemented")
-		} else {
-			// TODO: Don't just keep the single dataflow. Reference asset logic above
+			logger.Debug("Multiple DataFlow instances for this ID. Merging.", "dataflowCount", len(dataflows))
+			mergedDataflows = append(mergedDataflows, mergeableDataflows(dataflows).merge(logger, mm.cl))
+		} else if common.GetOr(dataflows[0].Extra, "IsGeneratedByUser", false) || dataflows[0].DataSource != common.DataSourceThreatDragon {
+			logger.Debug("Dataflow was found in a single original source. Keeping.")
 			mergedDataflows = append(mergedDataflows, dataflows[0])
+		} else {
+			logger.Debug("Dataflow was no longer found in its original source. Removing.")
+			mm.cl.AddEntry(fmt.Sprintf("Removed dataflow '%s' that was no longer found in its original source.", dataflows[0].Name))
 		}
 	}
+	// sort merged dataflows by ID to provide deterministic order of items (ascending order)
+	slices.SortFunc(mergedDataflows, func(a, b common.DataFlow) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
 	// sort merged boundaries by ID to provide deterministic oder of items (ascending order)
 	slices.SortFunc(mergedBoundaries, func(a, b common.TrustBoundary) int {
 		return cmp.Compare(a.ID, b.ID)
@@ -138,6 +146,30 @@ func (mm *ModelMerger) mergeModels(models []common.ThreatModel) common.ThreatMod
 	}
 }
 
+// withoutInferredDuplicates removes the inferred dataflows between two assets that an explicit dataflow of an
+// analyzed source already connects. The explicit dataflow has another ID, so both would be kept otherwise.
+// Dataflows read from Threat Dragon are not considered explicit here, as they can be copies of inferred dataflows.
+func (mm *ModelMerger) withoutInferredDuplicates(dataflows []common.DataFlow, models []common.ThreatModel) []common.DataFlow {
+	connects := func(a, b common.DataFlow) bool {
+		return (a.Source == b.Source && a.Target == b.Target) || (a.Source == b.Target && a.Target == b.Source)
+	}
+
+	result := make([]common.DataFlow, 0, len(dataflows))
+	for _, dataflow := range dataflows {
+		duplicate := dataflow.Inferred && slices.ContainsFunc(models, func(model common.ThreatModel) bool {
+			return slices.ContainsFunc(model.DataFlows, func(explicit common.DataFlow) bool {
+				return !explicit.Inferred && explicit.DataSource != common.DataSourceThreatDragon && connects(explicit, dataflow)
+			})
+		})
+		if duplicate {
+			mm.logger.Debug("Inferred dataflow is declared explicitly in another source. Removing.", "dataflow", dataflow.Name, "source", dataflow.Source, "target", dataflow.Target)
+			continue
+		}
+		result = append(result, dataflow)
+	}
+	return result
+}
+
 func (mm *ModelMerger) mergeModelExtras(models []common.ThreatModel) map[string]any {
 	extraMap := make(map[string]any)
 	for _, model := range models {
@@ -155,7 +187,7 @@ type mergeableAssets []common.Asset
 // merge merges multiple assets into one.
 // It panics if there are no assets to merge.
 // If only one asset is provided, it returns that asset.
-// It merges the display name, type, source, and extra data of the assets.
+// It merges the display name, type, source, extra data and credential flag of the assets.
 func (ma mergeableAssets) merge(logger *slog.Logger, cl changelog) common.Asset {
 	logger.Debug("Merging assets")
 	if len(ma) == 0 {
@@ -166,12 +198,13 @@ func (ma mergeableAssets) merge(logger *slog.Logger, cl changelog) common.Asset
 	}
 
 	mergedAsset := common.Asset{
-		ID:          ma[0].ID,
-		DisplayName: ma.displayName(logger),
-		Type:        ma.assetType(logger),
-		Threats:     ma.threats(logger, cl),
-		Source:      common.DataSourceMerged,
-		Extra:       ma.extra(logger),
+		ID:                ma[0].ID,
+		DisplayName:       ma.displayName(logger),
+		Type:              ma.assetType(logger),
+		Threats:           ma.threats(logger, cl),
+		Source:            common.DataSourceMerged,
+		Extra:             ma.extra(logger),
+		StoresCredentials: ma.storesCredentials(logger),
 	}
 	logger.Debug("Successfully merged asstets", "mergedAsset", mergedAsset)
 	return mergedAsset
@@ -180,13 +213,21 @@ func (ma mergeableAssets) merge(logger *slog.Logger, cl changelog) common.Asset
 // displayName() returns the display name of the merged asset.
 // It uses the following priority order:
 // 1. The display name of an asset with source DataSourceThreatDragon
-// 2. The display name of an asset with source DataSourceDockerCompose
-// 3. The display name of an asset with source DataSourceUnknown
+// 2. The display name of an asset with source DataSourceThreagile
+// 3. The display name of an asset with source DataSourceOTM
+// 4. The display name of an asset with source DataSourceDockerCompose
+// 5. The display name of an asset with source DataSourceKubernetes
+// 6. The display name of an asset with source DataSourceTerraform
+// 7. The display name of an asset with source DataSourceUnknown
 func (ma mergeableAssets) displayName(logger *slog.Logger) string {
 	priority := []common.DataSource{
 		common.DataSourceMerged,
 		common.DataSourceThreatDragon,
+		common.DataSourceThreagile,
+		common.DataSourceOTM,
 		common.DataSourceDockerCompose,
+		common.DataSourceKubernetes,
+		common.DataSourceTerraform,
 		common.DataSourceUnknown,
 	}
 
@@ -208,11 +249,19 @@ func (ma mergeableAssets) displayName(logger *slog.Logger) string {
 // assetType() returns the type of the merged asset.
 // It uses the following priority order:
 // 1. The type of an asset with source DataSourceDockerCompose
-// 2. The type of an asset with source DataSourceThreatDragon
-// 3. The type of an asset with source DataSourceUnknown
+// 2. The type of an asset with source DataSourceKubernetes
+// 3. The type of an asset with source DataSourceTerraform
+// 4. The type of an asset with source DataSourceThreagile
+// 5. The type of an asset with source DataSourceOTM
+// 6. The type of an asset with source DataSourceThreatDragon
+// 7. The type of an asset with source DataSourceUnknown
 func (ma mergeableAssets) assetType(logger *slog.Logger) common.AssetType {
 	priority := []common.DataSource{
 		common.DataSourceDockerCompose,
+		common.DataSourceKubernetes,
+		common.DataSourceTerraform,
+		common.DataSourceThreagile,
+		common.DataSourceOTM,
 		common.DataSourceThreatDragon,
 		common.DataSourceUnknown,
 	}
@@ -230,6 +279,47 @@ func (ma mergeableAssets) assetType(logger *slog.Logger) common.AssetType {
 	return common.AssetTypeUnknown
 }
 
+// storesCredentials() returns whether the merged asset stores credentials.
+// The value of an asset created by the user in ThreatDragon wins. Otherwise it uses the following priority order:
+// 1. The value of an asset with source DataSourceMerged
+// 2. The value of an asset with source DataSourceDockerCompose
+// 3. The value of an asset with source DataSourceKubernetes
+// 4. The value of an asset with source DataSourceTerraform
+// 5. The value of an asset with source DataSourceThreagile
+// 6. The value of an asset with source DataSourceOTM
+// 7. The value of an asset with source DataSourceThreatDragon
+// 8. The value of an asset with source DataSourceUnknown
+func (ma mergeableAssets) storesCredentials(logger *slog.Logger) bool {
+	for _, asset := range ma {
+		if asset.Source == common.DataSourceThreatDragon && common.GetOr(asset.Extra, "IsGeneratedByUser", false) {
+			logger.Debug("Found credential flag of user created asset", "storesCredentials", asset.StoresCredentials)
+			return asset.StoresCredentials
+		}
+	}
+
+	priority := []common.DataSource{
+		common.DataSourceMerged,
+		common.DataSourceDockerCompose,
+		common.DataSourceKubernetes,
+		common.DataSourceTerraform,
+		common.DataSourceThreagile,
+		common.DataSourceOTM,
+		common.DataSourceThreatDragon,
+		common.DataSourceUnknown,
+	}
+
+	for _, p := range priority {
+		for _, asset := range ma {
+			if asset.Source == p {
+				logger.Debug("Found credential flag", "source", p, "storesCredentials", asset.StoresCredentials)
+				return asset.StoresCredentials
+			}
+		}
+	}
+
+	return false
+}
+
 // extra() returns the extra data of the merged asset.
 // It merges the extra data maps into one.
 func (ma mergeableAssets) extra(logger *slog.Logger) map[string]any {
@@ -250,16 +340,24 @@ func (ma mergeableAssets) extra(logger *slog.Logger) map[string]any {
 // 2. priority source DataSourceDockerCompose
 // 3. priority source DataSourceUnknown
 func (ma mergeableAssets) threats(logger *slog.Logger, cl changelog) []common.Threat {
+	threats := make([]common.Threat, 0)
+	for _, asset := range ma {
+		threats = append(threats, asset.Threats...)
+	}
+	return mergeThreats(threats, logger, cl)
+}
+
+// mergeThreats merges the threats of all sources of an asset or dataflow by their ID.
+// Threats that were generated by the tool and are only left in Threat Dragon are marked as mitigated.
+func mergeThreats(threats []common.Threat, logger *slog.Logger, cl changelog) []common.Threat {
 
 	var threatsToReturn []common.Threat
 	var idMap = make(map[string][]common.Threat)
 
-	for _, asset := range ma {
-		for _, threat := range asset.Threats {
-			// only consider supported valid threats for merging
-			if threat.ModelType != common.NotSupported && threat.Type != common.ThreatTypeUnknown {
-				idMap[threat.ID] = append(idMap[threat.ID], threat)
-			}
+	for _, threat := range threats {
+		// only consider supported valid threats for merging
+		if threat.ModelType != common.NotSupported && threat.Type != common.ThreatTypeUnknown {
+			idMap[threat.ID] = append(idMap[threat.ID], threat)
 		}
 	}
 
@@ -305,11 +403,12 @@ func (mb mergeableBoundaries) merge(mergedAssets []common.Asset, logger *slog.Lo
 	}
 
 	mergedBoundary := common.TrustBoundary{
-		ID:              mb[0].ID,
-		DisplayName:     mb.displayName(logger),
-		Source:          common.DataSourceMerged,
-		ContainedAssets: mb.containedAssets(mergedAssets, logger),
-		Extra:           mb.extra(logger),
+		ID:                mb[0].ID,
+		DisplayName:       mb.displayName(logger),
+		Source:            common.DataSourceMerged,
+		ContainedAssets:   mb.containedAssets(mergedAssets, logger),
+		CrossingDataFlows: mb.crossingDataFlows(logger),
+		Extra:             mb.extra(logger),
 	}
 
 	logger.Debug("Successfully merged boundaries", "mergedBoundary", mergedBoundary)
@@ -319,12 +418,20 @@ func (mb mergeableBoundaries) merge(mergedAssets []common.Asset, logger *slog.Lo
 // displayName() returns the display name of the merged trust boundary.
 // It uses the following priority order:
 // 1. The display name of a boundary with source DataSourceThreatDragon
-// 2. The display name of a boundary with source DataSourceDockerCompose
-// 3. The display name of a boundary with source DataSourceUnknown
+// 2. The display name of a boundary with source DataSourceThreagile
+// 3. The display name of a boundary with source DataSourceOTM
+// 4. The display name of a boundary with source DataSourceDockerCompose
+// 5. The display name of a boundary with source DataSourceKubernetes
+// 6. The display name of a boundary with source DataSourceTerraform
+// 7. The display name of a boundary with source DataSourceUnknown
 func (mb mergeableBoundaries) displayName(logger *slog.Logger) string {
 	priority := []common.DataSource{
 		common.DataSourceThreatDragon,
+		common.DataSourceThreagile,
+		common.DataSourceOTM,
 		common.DataSourceDockerCompose,
+		common.DataSourceKubernetes,
+		common.DataSourceTerraform,
 		common.DataSourceUnknown,
 	}
 
@@ -368,6 +475,20 @@ func (mb mergeableBoundaries) containedAssets(mergedAssets []common.Asset, logge
 	return containedAssets
 }
 
+// crossingDataFlows merges the ID lists of the dataflows that cross the boundary
+func (mb mergeableBoundaries) crossingDataFlows(logger *slog.Logger) []string {
+	crossing := make([]string, 0)
+	for _, boundary := range mb {
+		for _, id := range boundary.CrossingDataFlows {
+			if !slices.Contains(crossing, id) {
+				crossing = append(crossing, id)
+			}
+		}
+	}
+	logger.Debug("Successfully merged crossing dataflows", "count", len(crossing))
+	return crossing
+}
+
 // extra() returns the extra data of the merged boundary.
 // It merges the extra data maps into one.
 func (mb mergeableBoundaries) extra(logger *slog.Logger) map[string]any {
@@ -381,3 +502,174 @@ func (mb mergeableBoundaries) extra(logger *slog.Logger) map[string]any {
 	logger.Debug("Successfully merged extras")
 	return extraMap
 }
+
+// this type alias has been defined to seperate dataflow merging logic into methods for this type.
+type mergeableDataflows []common.DataFlow
+
+// dataflowNamePriority is the priority order for the name and the endpoints of merged dataflows.
+// Like the display names of assets, names edited in Threat Dragon win.
+var dataflowNamePriority = []common.DataSource{
+	common.DataSourceMerged,
+	common.DataSourceThreatDragon,
+	common.DataSourceThreagile,
+	common.DataSourceOTM,
+	common.DataSourceDockerCompose,
+	common.DataSourceKubernetes,
+	common.DataSourceTerraform,
+	common.DataSourceUnknown,
+}
+
+// dataflowPropertyPriority is the priority order for the technical properties of merged dataflows.
+// Like the types of assets, they are taken from the analyzed sources first. See propertySource.
+var dataflowPropertyPriority = []common.DataSource{
+	common.DataSourceDockerCompose,
+	common.DataSourceKubernetes,
+	common.DataSourceTerraform,
+	common.DataSourceThreagile,
+	common.DataSourceOTM,
+	common.DataSourceUnknown,
+	common.DataSourceMerged,
+	common.DataSourceThreatDragon,
+}
+
+// merge merges multiple dataflows into one.
+// It panics if there are no dataflows to merge.
+// If only one dataflow is provided, it returns that dataflow.
+// Name and endpoints are selected with dataflowNamePriority. Protocol, encryption, public network and
+// direction are taken together from the dataflow selected by propertySource.
+// Explicit dataflows always rank above inferred ones. Conflicting values are written to the changelog.
+func (md mergeableDataflows) merge(logger *slog.Logger, cl changelog) common.DataFlow {
+	logger.Debug("Merging dataflows")
+	if len(md) == 0 {
+		panic("No dataflows to merge")
+	} else if len(md) == 1 {
+		logger.Debug("Only one dataflow to merge. Returning directly.")
+		return md[0]
+	}
+
+	named := md.sorted(dataflowNamePriority)
+	properties := md.sorted(dataflowPropertyPriority)
+	chosen := properties.propertySource(logger)
+	name := selectField(named, "name", named[0].Name, func(df common.DataFlow) string { return df.Name }, logger, cl)
+
+	mergedDataflow := common.DataFlow{
+		ID:            md[0].ID,
+		Name:          name,
+		Source:        selectField(named, "source", name, func(df common.DataFlow) string { return df.Source }, logger, cl),
+		Target:        selectField(named, "target", name, func(df common.DataFlow) string { return df.Target }, logger, cl),
+		Protocol:      fieldOf(properties, chosen, "protocol", name, func(df common.DataFlow) string { return df.Protocol }, logger, cl),
+		Encrypted:     fieldOf(properties, chosen, "encryption", name, func(df common.DataFlow) bool { return df.Encrypted }, logger, cl),
+		PublicNetwork: fieldOf(properties, chosen, "public network", name, func(df common.DataFlow) bool { return df.PublicNetwork }, logger, cl),
+		Bidirectional: fieldOf(properties, chosen, "direction", name, func(df common.DataFlow) bool { return df.Bidirectional }, logger, cl),
+		Threats:       md.threats(logger, cl),
+		Inferred:      md.inferred(),
+		DataSource:    common.DataSourceMerged,
+		Extra:         md.extra(logger),
+	}
+	logger.Debug("Successfully merged dataflows", "mergedDataflow", mergedDataflow)
+	return mergedDataflow
+}
+
+// sorted returns the explicit dataflows ordered by the priority of their sources, followed by the inferred ones.
+// Dataflows of sources that are not part of the priority order are put last.
+func (md mergeableDataflows) sorted(priority []common.DataSource) mergeableDataflows {
+	rank := func(df common.DataFlow) int {
+		if index := slices.Index(priority, df.DataSource); index >= 0 {
+			return index
+		}
+		return len(priority)
+	}
+	sorted := slices.Clone(md)
+	slices.SortStableFunc(sorted, func(a, b common.DataFlow) int {
+		if a.Inferred != b.Inferred {
+			if b.Inferred {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(rank(a), rank(b))
+	})
+	return sorted
+}
+
+// propertySource returns the index of the dataflow whose protocol, encryption, public network and direction are used.
+// The properties are never combined from several dataflows, as a property that is not set, e.g. an unencrypted
+// connection, could not be told apart from a missing one.
+// A dataflow drawn by the user in Threat Dragon wins. Otherwise the first dataflow that does not come from
+// Threat Dragon is used, which is an explicit dataflow of the analyzers if there is one.
+// The dataflows have to be sorted with dataflowPropertyPriority.
+func (md mergeableDataflows) propertySource(logger *slog.Logger) int {
+	for i, df := range md {
+		if df.DataSource == common.DataSourceThreatDragon && common.GetOr(df.Extra, "IsGeneratedByUser", false) {
+			logger.Debug("Using the properties of the dataflow drawn in Threat Dragon", "name", df.Name)
+			return i
+		}
+	}
+	for i, df := range md {
+		if df.DataSource != common.DataSourceThreatDragon {
+			logger.Debug("Using the properties of the dataflow", "name", df.Name, "source", df.DataSource.ShortString())
+			return i
+		}
+	}
+	return 0
+}
+
+// selectField returns the first value that is set, in the order of priority. Inferred dataflows are only
+// considered if there is no explicit one. If no dataflow sets the field, the value of the first one is used.
+func selectField[T comparable](sorted mergeableDataflows, field, name string, get func(common.DataFlow) T, logger *slog.Logger, cl changelog) T {
+	var zero T
+	chosen := 0
+	for i, df := range sorted {
+		if df.Inferred && !sorted[0].Inferred {
+			break
+		}
+		if get(df) != zero {
+			chosen = i
+			break
+		}
+	}
+	return fieldOf(sorted, chosen, field, name, get, logger, cl)
+}
+
+// fieldOf returns the value of the chosen dataflow.
+// If other sources have a different value, the decision is logged and added to the changelog.
+func fieldOf[T comparable](sorted mergeableDataflows, chosen int, field, name string, get func(common.DataFlow) T, logger *slog.Logger, cl changelog) T {
+	selected := get(sorted[chosen])
+	for i, df := range sorted {
+		if i != chosen && get(df) != selected {
+			logger.Debug("Dataflow sources have different values. Using the value of the source with the highest priority.",
+				"field", field, "selected", selected, "source", sorted[chosen].DataSource.ShortString(), "discarded", get(df))
+			cl.AddEntry(fmt.Sprintf("Dataflow '%s' has a different %s in its sources. Using '%v' from %s instead of '%v' from %s.",
+				name, field, selected, sorted[chosen].DataSource.ShortString(), get(df), df.DataSource.ShortString()))
+		}
+	}
+	return selected
+}
+
+// threats() returns the threats of the merged dataflow.
+// They are merged like the threats of assets.
+func (md mergeableDataflows) threats(logger *slog.Logger, cl changelog) []common.Threat {
+	threats := make([]common.Threat, 0)
+	for _, df := range md {
+		threats = append(threats, df.Threats...)
+	}
+	return mergeThreats(threats, logger, cl)
+}
+
+// inferred() returns whether the merged dataflow is inferred.
+// It is only inferred if none of the sources declares it explicitly.
+func (md mergeableDataflows) inferred() bool {
+	return !slices.ContainsFunc(md, func(df common.DataFlow) bool { return !df.Inferred })
+}
+
+// extra() returns the extra data of the merged dataflow.
+// It merges the extra data maps into one.
+func (md mergeableDataflows) extra(logger *slog.Logger) map[string]any {
+	extraMap := make(map[string]any)
+	logger.Debug("Created extra map")
+	for _, df := range md {
+		maps.Copy(extraMap, df.Extra)
+	}
+	logger.Debug("Successfully merged extras")
+	return extraMap
+}
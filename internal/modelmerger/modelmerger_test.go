@@ -85,6 +85,14 @@ func TestAssetType(t *testing.T) {
 			},
 			expected: common.AssetTypeApplication,
 		},
+		{
+			name: "Kubernetes has priority over ThreatDragon",
+			assets: mergeableAssets{
+				{Type: common.AssetTypeApplication, Source: common.DataSourceThreatDragon},
+				{Type: common.AssetTypeDatabase, Source: common.DataSourceKubernetes},
+			},
+			expected: common.AssetTypeDatabase,
+		},
 		{
 			name: "Unknown used if no preferred source",
 			assets: mergeableAssets{
@@ -159,6 +167,56 @@ func TestExtraMerge(t *testing.T) {
 	}
 }
 
+func TestStoresCredentialsMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		assets   mergeableAssets
+		expected bool
+	}{
+		{
+			name:     "No asset stores credentials",
+			assets:   mergeableAssets{{Source: common.DataSourceDockerCompose}, {Source: common.DataSourceThreatDragon}},
+			expected: false,
+		},
+		{
+			name:     "One asset stores credentials",
+			assets:   mergeableAssets{{Source: common.DataSourceDockerCompose, StoresCredentials: true}, {Source: common.DataSourceThreatDragon}},
+			expected: true,
+		},
+		{
+			name: "Analyzer wins over tool generated ThreatDragon asset",
+			assets: mergeableAssets{
+				{Source: common.DataSourceThreatDragon, Extra: map[string]any{"IsGeneratedByUser": false}},
+				{Source: common.DataSourceDockerCompose, StoresCredentials: true},
+			},
+			expected: true,
+		},
+		{
+			name: "User created ThreatDragon asset clears the flag",
+			assets: mergeableAssets{
+				{Source: common.DataSourceDockerCompose, StoresCredentials: true},
+				{Source: common.DataSourceThreatDragon, Extra: map[string]any{"IsGeneratedByUser": true}},
+			},
+			expected: false,
+		},
+		{
+			name: "User created ThreatDragon asset sets the flag",
+			assets: mergeableAssets{
+				{Source: common.DataSourceDockerCompose},
+				{Source: common.DataSourceThreatDragon, StoresCredentials: true, Extra: map[string]any{"IsGeneratedByUser": true}},
+			},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.assets.storesCredentials(slog.Default()))
+			assert.Equal(t, tt.expected, tt.assets.merge(slog.Default(), dummyChangelog{}).StoresCredentials)
+		})
+	}
+}
+
 func TestMerge(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -496,3 +554,253 @@ func TestGenerate_DoNotMitigateOriginalNotMissingThreats(t *testing.T) {
 		}
 	}
 }
+
+type recordingChangelog struct {
+	entries *[]string
+}
+
+func (rc recordingChangelog) AddEntry(entry string) {
+	*rc.entries = append(*rc.entries, entry)
+}
+
+func TestMergeableDataflows_Merge(t *testing.T) {
+	tdFlow := common.DataFlow{
+		ID: "f", Name: "Renamed in TD", Source: "Web", Target: "Database", Protocol: "http",
+		Encrypted: false, DataSource: common.DataSourceThreatDragon, Extra: map[string]any{"ThreatDragonDiagramCellIdx": "0-3", "IsGeneratedByUser": true},
+	}
+	dcFlow := common.DataFlow{
+		ID: "f", Name: "Flow1", Source: "web", Target: "db", Protocol: "postgresql",
+		Encrypted: true, Inferred: true, DataSource: common.DataSourceDockerCompose,
+	}
+
+	entries := []string{}
+	merged := mergeableDataflows{tdFlow, dcFlow}.merge(slog.Default(), recordingChangelog{&entries})
+
+	assert.Equal(t, "f", merged.ID)
+	assert.Equal(t, "Renamed in TD", merged.Name) // priority: ThreatDragon
+	assert.Equal(t, "Web", merged.Source)         // priority: ThreatDragon
+	assert.Equal(t, "Database", merged.Target)    // priority: ThreatDragon
+	assert.Equal(t, "http", merged.Protocol)      // the flow drawn in Threat Dragon wins
+	assert.False(t, merged.Encrypted)             // the flow drawn in Threat Dragon wins
+	assert.False(t, merged.Inferred, "a dataflow drawn in Threat Dragon is not inferred")
+	assert.Equal(t, common.DataSourceMerged, merged.DataSource)
+	assert.Equal(t, map[string]any{"ThreatDragonDiagramCellIdx": "0-3", "IsGeneratedByUser": true}, merged.Extra)
+
+	// name, source, target, protocol and encryption differ
+	assert.Len(t, entries, 5)
+	assert.Contains(t, entries, "Dataflow 'Renamed in TD' has a different protocol in its sources. Using 'http' from Threat Dragon instead of 'postgresql' from Docker Compose.")
+}
+
+func TestMergeableDataflows_MergeProperties(t *testing.T) {
+	tests := []struct {
+		name      string
+		tdFlow    common.DataFlow
+		drawn     bool
+		dcFlow    common.DataFlow
+		protocol  string
+		encrypted bool
+		public    bool
+		bidir     bool
+	}{
+		{
+			name:      "Values of a flow drawn in Threat Dragon are kept",
+			tdFlow:    common.DataFlow{Protocol: "https", Encrypted: true, PublicNetwork: true, Bidirectional: true},
+			drawn:     true,
+			dcFlow:    common.DataFlow{},
+			protocol:  "https",
+			encrypted: true,
+			public:    true,
+			bidir:     true,
+		},
+		{
+			name:     "Values cleared in a flow drawn in Threat Dragon stay cleared",
+			tdFlow:   common.DataFlow{Protocol: "http"},
+			drawn:    true,
+			dcFlow:   common.DataFlow{Protocol: "grpc", Encrypted: true, PublicNetwork: true, Bidirectional: true},
+			protocol: "http",
+		},
+		{
+			name:     "Values of an explicit analyzer flow replace a generated flow, also if they are not set",
+			tdFlow:   common.DataFlow{Protocol: "https", Encrypted: true, PublicNetwork: true, Bidirectional: true},
+			dcFlow:   common.DataFlow{Protocol: "http"},
+			protocol: "http",
+		},
+		{
+			name:      "Values of an inferred analyzer flow replace a generated flow",
+			tdFlow:    common.DataFlow{Protocol: "https"},
+			dcFlow:    common.DataFlow{Protocol: "http", Encrypted: true, PublicNetwork: true, Inferred: true},
+			protocol:  "http",
+			encrypted: true,
+			public:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.tdFlow.ID, tt.tdFlow.DataSource = "f", common.DataSourceThreatDragon
+			tt.tdFlow.Extra = map[string]any{"IsGeneratedByUser": tt.drawn}
+			tt.dcFlow.ID, tt.dcFlow.DataSource = "f", common.DataSourceDockerCompose
+
+			merged := mergeableDataflows{tt.tdFlow, tt.dcFlow}.merge(slog.Default(), dummyChangelog{})
+			assert.Equal(t, tt.protocol, merged.Protocol)
+			assert.Equal(t, tt.encrypted, merged.Encrypted)
+			assert.Equal(t, tt.public, merged.PublicNetwork)
+			assert.Equal(t, tt.bidir, merged.Bidirectional)
+		})
+	}
+}
+
+func TestMergeableDataflows_MergeExplicitBeforeInferred(t *testing.T) {
+	inferred := common.DataFlow{ID: "f", Name: "Guess", Protocol: "https", Encrypted: true, Inferred: true, DataSource: common.DataSourceDockerCompose}
+	explicit := common.DataFlow{ID: "f", Name: "Declared", Protocol: "http", DataSource: common.DataSourceUnknown}
+
+	merged := mergeableDataflows{inferred, explicit}.merge(slog.Default(), dummyChangelog{})
+	assert.Equal(t, "Declared", merged.Name)
+	assert.Equal(t, "http", merged.Protocol)
+	assert.False(t, merged.Encrypted)
+	assert.False(t, merged.Inferred)
+}
+
+func TestMergeableDataflows_MergeWithoutConflicts(t *testing.T) {
+	flow := common.DataFlow{ID: "f", Name: "Flow1", Source: "web", Target: "db", Protocol: "http"}
+	tdFlow, dcFlow := flow, flow
+	tdFlow.DataSource = common.DataSourceThreatDragon
+	dcFlow.DataSource = common.DataSourceDockerCompose
+	dcFlow.Inferred = true
+	tdFlow.Inferred = true
+
+	entries := []string{}
+	merged := mergeableDataflows{dcFlow, tdFlow}.merge(slog.Default(), recordingChangelog{&entries})
+
+	assert.Empty(t, entries)
+	assert.True(t, merged.Inferred)
+	assert.Equal(t, "Flow1", merged.Name)
+}
+
+func TestModelMerger_MergeDataflows(t *testing.T) {
+	models := []common.ThreatModel{
+		{
+			DataFlows: []common.DataFlow{
+				{ID: "both", Name: "Both", Source: "a", Target: "b", DataSource: common.DataSourceThreatDragon, Extra: map[string]any{"IsGeneratedByUser": false}},
+				{ID: "vanished", Name: "Vanished", Source: "a", Target: "b", DataSource: common.DataSourceThreatDragon, Extra: map[string]any{"IsGeneratedByUser": false}},
+				{ID: "drawn", Name: "Drawn", Source: "a", Target: "b", DataSource: common.DataSourceThreatDragon, Extra: map[string]any{"IsGeneratedByUser": true}},
+			},
+		},
+		{
+			DataFlows: []common.DataFlow{
+				{ID: "both", Name: "Both", Source: "a", Target: "b", Protocol: "https", DataSource: common.DataSourceDockerCompose},
+				{ID: "compose", Name: "Compose", Source: "b", Target: "a", DataSource: common.DataSourceDockerCompose},
+			},
+		},
+		{
+			DataFlows: []common.DataFlow{
+				{ID: "yaml", Name: "Yaml", Source: "a", Target: "c"},
+			},
+		},
+	}
+
+	entries := []string{}
+	merged := NewModelMerger(recordingChangelog{&entries}, slog.Default()).Merge(models)
+
+	ids := make([]string, 0, len(merged.DataFlows))
+	for _, flow := range merged.DataFlows {
+		ids = append(ids, flow.ID)
+	}
+	// sorted by ID, the vanished tool-generated dataflow is removed
+	assert.Equal(t, []string{"both", "compose", "drawn", "yaml"}, ids)
+	assert.Equal(t, "https", merged.DataFlows[0].Protocol)
+	assert.Equal(t, common.DataSourceMerged, merged.DataFlows[0].DataSource)
+	assert.Contains(t, entries, "Removed dataflow 'Vanished' that was no longer found in its original source.")
+}
+
+func TestModelMerger_MergeInferredDuplicates(t *testing.T) {
+	models := []common.ThreatModel{
+		{
+			DataFlows: []common.DataFlow{
+				// copy of the inferred dataflow from an earlier run
+				{ID: "inferred", Name: "web to db", Source: "web", Target: "db", DataSource: common.DataSourceThreatDragon, Extra: map[string]any{"IsGeneratedByUser": false}},
+			},
+		},
+		{
+			DataFlows: []common.DataFlow{
+				{ID: "inferred", Name: "web to db", Source: "web", Target: "db", Protocol: "postgresql", Inferred: true, DataSource: common.DataSourceDockerCompose},
+				{ID: "cache", Name: "web to cache", Source: "web", Target: "cache", Inferred: true, DataSource: common.DataSourceDockerCompose},
+			},
+		},
+		{
+			DataFlows: []common.DataFlow{
+				{ID: "yaml", Name: "Orders", Source: "db", Target: "web", Protocol: "postgresql"},
+			},
+		},
+	}
+
+	merged := NewModelMerger(dummyChangelog{}, slog.Default()).Merge(models)
+
+	ids := make([]string, 0, len(merged.DataFlows))
+	for _, flow := range merged.DataFlows {
+		ids = append(ids, flow.ID)
+	}
+	// the explicit dataflow replaces the inferred one between the same assets
+	assert.Equal(t, []string{"cache", "yaml"}, ids)
+}
+
+func TestModelMerger_MergeBoundaries(t *testing.T) {
+	models := []common.ThreatModel{
+		{
+			Assets: []common.Asset{
+				{ID: "web", DisplayName: "web", Source: common.DataSourceThreatDragon, Extra: map[string]any{"IsGeneratedByUser": false}},
+				{ID: "db", DisplayName: "db", Source: common.DataSourceThreatDragon, Extra: map[string]any{"IsGeneratedByUser": false}},
+			},
+			Boundaries: []common.TrustBoundary{
+				{ID: "both", DisplayName: "Both", ContainedAssets: []string{"web"}, Source: common.DataSourceThreatDragon, Extra: map[string]any{"IsGeneratedByUser": false}},
+				{ID: "vanished", DisplayName: "Vanished", Source: common.DataSourceThreatDragon, Extra: map[string]any{"IsGeneratedByUser": false}},
+				{ID: "drawn", DisplayName: "Drawn", Source: common.DataSourceThreatDragon, Extra: map[string]any{"IsGeneratedByUser": true}},
+			},
+		},
+		{
+			Assets: []common.Asset{
+				{ID: "web", DisplayName: "web", Source: common.DataSourceDockerCompose},
+				{ID: "db", DisplayName: "db", Source: common.DataSourceDockerCompose},
+			},
+			Boundaries: []common.TrustBoundary{
+				{ID: "both", DisplayName: "Both", ContainedAssets: []string{"db"}, Source: common.DataSourceDockerCompose},
+				{ID: "compose", DisplayName: "Compose", Source: common.DataSourceDockerCompose},
+			},
+		},
+	}
+
+	entries := []string{}
+	merged := NewModelMerger(recordingChangelog{&entries}, slog.Default()).Merge(models)
+
+	ids := make([]string, 0, len(merged.Boundaries))
+	for _, boundary := range merged.Boundaries {
+		ids = append(ids, boundary.ID)
+	}
+	// the boundary drawn by the user is kept, the vanished tool-generated boundary is removed
+	assert.Equal(t, []string{"both", "compose", "drawn"}, ids)
+	assert.ElementsMatch(t, []string{"web", "db"}, merged.Boundaries[0].ContainedAssets)
+	assert.Contains(t, entries, "Removed trust boundary 'Vanished' that was no longer found in its original source.")
+}
+
+func TestMergeableDataflows_Threats(t *testing.T) {
+	tdFlow := common.DataFlow{ID: "f", DataSource: common.DataSourceThreatDragon, Threats: []common.Threat{
+		{ID: "threat1", Title: "Kept", Type: common.Tampering, ModelType: common.STRIDE, Source: common.DataSourceThreatDragon, Status: common.Open},
+		{ID: "threat2", Title: "Vanished", Type: common.Tampering, ModelType: common.STRIDE, Source: common.DataSourceThreatDragon, Status: common.Open},
+		{ID: "threat3", Title: "Drawn", Type: common.Tampering, ModelType: common.STRIDE, Source: common.DataSourceThreatDragon, Status: common.Open, IsGeneratedByUser: true},
+	}}
+	dcFlow := common.DataFlow{ID: "f", DataSource: common.DataSourceDockerCompose, Threats: []common.Threat{
+		{ID: "threat1", Title: "Kept", Type: common.Tampering, ModelType: common.STRIDE, Source: common.DataSourceDockerCompose, Status: common.Open},
+	}}
+
+	merged := mergeableDataflows{tdFlow, dcFlow}.merge(slog.Default(), dummyChangelog{})
+
+	statuses := make(map[string]common.Status)
+	for _, threat := range merged.Threats {
+		statuses[threat.ID] = threat.Status
+	}
+	assert.Equal(t, map[string]common.Status{
+		"threat1": common.Open,
+		"threat2": common.Mitigated,
+		"threat3": common.Open,
+	}, statuses)
+}
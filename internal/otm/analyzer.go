@@ -0,0 +1,249 @@
+package otm
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// OTMAnalyzer analyzes OTM documents
+type OTMAnalyzer struct {
+	ModelFilePath string
+	logger        *slog.Logger
+}
+
+// NewOTMAnalyzer creates a new instance of OTMAnalyzer
+func NewOTMAnalyzer(modelFilePath string, logger *slog.Logger) *OTMAnalyzer {
+	return &OTMAnalyzer{
+		ModelFilePath: modelFilePath,
+		logger:        logger.With("package", "otm", "component", "OTMAnalyzer"),
+	}
+}
+
+// Analyze analyzes the given OTM document and returns a threat model.
+// Components become assets, dataflows become dataflows and trust zones become trust boundaries that contain the
+// components of their nested zones as well. The threat instances of components and dataflows become their threats.
+func (a *OTMAnalyzer) Analyze(document *Document) (*common.ThreatModel, error) {
+	if document == nil {
+		return nil, fmt.Errorf("no OTM document to analyze was given")
+	}
+
+	result := common.EmptyThreatModel()
+	a.logger.Debug("Beginning OTM analysis", "componentCount", len(document.Components))
+
+	threats := make(map[string]Threat, len(document.Threats))
+	for _, threat := range document.Threats {
+		threats[threat.ID] = threat
+	}
+	mitigations := make(map[string]Mitigation, len(document.Mitigations))
+	for _, mitigation := range document.Mitigations {
+		mitigations[mitigation.ID] = mitigation
+	}
+
+	assetIndex := make(map[string]int) // component ID -> index of the asset
+	for _, component := range document.Components {
+		componentType := component.Type
+		if componentType == "" {
+			componentType = common.GetOr(component.Attributes, "type", "")
+		}
+		asset := common.Asset{
+			ID:                a.id("component", component.ID),
+			DisplayName:       component.Name,
+			Type:              componentAssetType(componentType),
+			Source:            common.DataSourceOTM,
+			StoresCredentials: common.GetOr(component.Attributes, "storesCredentials", false),
+			Extra: map[string]any{
+				"OTMID":   component.ID,
+				"OTMType": componentType,
+			},
+		}
+		asset.Threats = a.threats(component.ID, component.Threats, threats, mitigations)
+		a.logger.Debug("Created a new instance of Asset for component", "id", component.ID, "asset", asset)
+		assetIndex[component.ID] = len(result.Assets)
+		result.Assets = append(result.Assets, asset)
+	}
+
+	for _, dataflow := range document.Dataflows {
+		source, sourceOK := assetIndex[dataflow.Source]
+		target, targetOK := assetIndex[dataflow.Destination]
+		if !sourceOK || !targetOK {
+			a.logger.Warn("Dataflow has an unknown source or destination and is skipped", "dataflow", dataflow.ID, "source", dataflow.Source, "destination", dataflow.Destination)
+			continue
+		}
+		result.DataFlows = append(result.DataFlows, common.DataFlow{
+			ID:            a.id("dataflow", dataflow.ID),
+			Name:          dataflow.Name,
+			Protocol:      common.GetOr(dataflow.Attributes, "protocol", ""),
+			Encrypted:     common.GetOr(dataflow.Attributes, "encrypted", false),
+			PublicNetwork: common.GetOr(dataflow.Attributes, "publicNetwork", false),
+			Source:        result.Assets[source].DisplayName,
+			Target:        result.Assets[target].DisplayName,
+			Bidirectional: dataflow.Bidirectional,
+			Threats:       a.threats(dataflow.ID, dataflow.Threats, threats, mitigations),
+			DataSource:    common.DataSourceOTM,
+			Extra: map[string]any{
+				"OTMID": dataflow.ID,
+			},
+		})
+	}
+
+	for _, zone := range document.TrustZones {
+		contained := make([]string, 0)
+		for _, component := range document.Components {
+			if zoneContains(zone.ID, componentZone(component, document), document.TrustZones) {
+				contained = append(contained, result.Assets[assetIndex[component.ID]].ID)
+			}
+		}
+		result.Boundaries = append(result.Boundaries, common.TrustBoundary{
+			ID:              a.id("trust-zone", zone.ID),
+			DisplayName:     zone.Name,
+			ContainedAssets: contained,
+			Source:          common.DataSourceOTM,
+			Extra: map[string]any{
+				"initial-description": zone.Description,
+				"OTMID":               zone.ID,
+				"OTMType":             zone.Type,
+				"OTMTrustRating":      zone.Risk.TrustRating,
+			},
+		})
+	}
+
+	a.logger.Debug("OTM analysis finished", "assetCount", len(result.Assets), "boundaryCount", len(result.Boundaries), "dataflowCount", len(result.DataFlows))
+	return &result, nil
+}
+
+// id generates a unique ID for an element of the model by hashing the file path, the kind and the OTM ID
+func (a *OTMAnalyzer) id(kind, otmID string) string {
+	return common.GenerateIDHash(a.ModelFilePath, fmt.Sprintf("%s:%s", kind, otmID))
+}
+
+// threats creates the threats of the threat instances of a component or a dataflow.
+// The mitigation of a threat is the description of its mitigations.
+func (a *OTMAnalyzer) threats(elementID string, instances []ThreatInstance, threats map[string]Threat, mitigations map[string]Mitigation) []common.Threat {
+	result := make([]common.Threat, 0, len(instances))
+	for _, instance := range instances {
+		threat, ok := threats[instance.Threat]
+		if !ok {
+			a.logger.Warn("Threat instance references an unknown threat and is skipped", "element", elementID, "threat", instance.Threat)
+			continue
+		}
+		texts := make([]string, 0, len(instance.Mitigations))
+		for _, reference := range instance.Mitigations {
+			mitigation, ok := mitigations[reference.Mitigation]
+			if !ok {
+				a.logger.Warn("Threat instance references an unknown mitigation", "element", elementID, "mitigation", reference.Mitigation)
+				continue
+			}
+			if mitigation.Description != "" {
+				texts = append(texts, mitigation.Description)
+			} else {
+				texts = append(texts, mitigation.Name)
+			}
+		}
+		id := a.id("threat", elementID+":"+threat.ID)
+		result = append(result, common.Threat{
+			InternalID:  id,
+			ID:          common.UUIDFormat(id),
+			Title:       threat.Name,
+			Status:      instanceStatus(instance.State),
+			Severity:    impactSeverity(threat.Risk.Impact),
+			Type:        categoryThreatType(threat.Categories),
+			Description: threat.Description,
+			Mitigation:  strings.Join(texts, "\n"),
+			ModelType:   common.STRIDE,
+			Source:      common.DataSourceOTM,
+			MapIndex:    -1,
+		})
+	}
+	return result
+}
+
+// componentZone returns the ID of the trust zone of a component. Components nested into other components are in
+// the zone of their outermost component.
+func componentZone(component Component, document *Document) string {
+	visited := map[string]bool{}
+	for component.Parent.TrustZone == "" && component.Parent.Component != "" && !visited[component.ID] {
+		visited[component.ID] = true
+		index := slices.IndexFunc(document.Components, func(c Component) bool { return c.ID == component.Parent.Component })
+		if index < 0 {
+			return ""
+		}
+		component = document.Components[index]
+	}
+	return component.Parent.TrustZone
+}
+
+// zoneContains checks if the zone is the given zone or nested into it
+func zoneContains(outerID, zoneID string, zones []TrustZone) bool {
+	visited := map[string]bool{}
+	for zoneID != "" && !visited[zoneID] {
+		if zoneID == outerID {
+			return true
+		}
+		visited[zoneID] = true
+		index := slices.IndexFunc(zones, func(z TrustZone) bool { return z.ID == zoneID })
+		if index < 0 || zones[index].Parent == nil {
+			return false
+		}
+		zoneID = zones[index].Parent.TrustZone
+	}
+	return false
+}
+
+// componentAssetType determines the asset type of a component by the words of its type
+func componentAssetType(componentType string) common.AssetType {
+	name := strings.ToLower(componentType)
+	contains := func(words ...string) bool {
+		return slices.ContainsFunc(words, func(word string) bool { return strings.Contains(name, word) })
+	}
+	switch {
+	case name == "" || name == "unknown":
+		return common.AssetTypeUnknown
+	case contains("external", "client", "browser", "actor", "device", "mobile"):
+		return common.AssetTypeExternalEntity
+	case contains("database", "db", "sql", "storage", "store", "bucket", "cache", "file"):
+		return common.AssetTypeDatabase
+	case contains("webserver", "web-server", "proxy", "load-balancer", "gateway", "cdn", "waf"):
+		return common.AssetTypeWebserver
+	case contains("infrastructure", "queue", "broker", "network", "vpc", "monitor", "registry", "vault"):
+		return common.AssetTypeInfrastructure
+	}
+	return common.AssetTypeApplication
+}
+
+// instanceStatus maps the state of a threat instance to a threat status. Unknown states are open.
+func instanceStatus(state string) common.Status {
+	switch strings.ToLower(state) {
+	case "mitigated":
+		return common.Mitigated
+	case "not-applicable", "notapplicable", "accepted":
+		return common.NotApplicable
+	}
+	return common.Open
+}
+
+// impactSeverity maps the impact of a threat from 0 to 100 to the severities of Threat Dragon
+func impactSeverity(impact float64) string {
+	switch {
+	case impact >= 75:
+		return "High"
+	case impact >= 50:
+		return "Medium"
+	}
+	return "Low"
+}
+
+// categoryThreatType returns the STRIDE threat type of the first category that names one
+func categoryThreatType(categories []string) common.ThreatType {
+	for _, category := range categories {
+		for threatType, name := range strideCategories {
+			if strings.EqualFold(strings.ReplaceAll(category, "-", " "), name) {
+				return threatType
+			}
+		}
+	}
+	return common.ThreatTypeUnknown
+}
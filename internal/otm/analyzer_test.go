@@ -0,0 +1,161 @@
+package otm
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+const testModelPath = "testdata/otm.json"
+
+func analyzeTestModel(t *testing.T) *common.ThreatModel {
+	t.Helper()
+	document, err := NewOTMParser(testModelPath, slog.Default()).Parse()
+	require.NoError(t, err)
+	result, err := NewOTMAnalyzer(testModelPath, slog.Default()).Analyze(document)
+	require.NoError(t, err)
+	return result
+}
+
+func findAsset(t *testing.T, model *common.ThreatModel, name string) common.Asset {
+	t.Helper()
+	for _, asset := range model.Assets {
+		if asset.DisplayName == name {
+			return asset
+		}
+	}
+	require.Failf(t, "asset not found", name)
+	return common.Asset{}
+}
+
+func findDataflow(t *testing.T, model *common.ThreatModel, name string) common.DataFlow {
+	t.Helper()
+	for _, dataflow := range model.DataFlows {
+		if dataflow.Name == name {
+			return dataflow
+		}
+	}
+	require.Failf(t, "dataflow not found", name)
+	return common.DataFlow{}
+}
+
+func TestAnalyze_Assets(t *testing.T) {
+	model := analyzeTestModel(t)
+	require.Len(t, model.Assets, 4)
+
+	tests := []struct {
+		name      string
+		assetType common.AssetType
+	}{
+		{"Customer Browser", common.AssetTypeExternalEntity},
+		{"Shop Backend", common.AssetTypeApplication},
+		{"Order Worker", common.AssetTypeApplication},
+		{"Orders DB", common.AssetTypeDatabase},
+	}
+	for _, tt := range tests {
+		asset := findAsset(t, model, tt.name)
+		assert.Equal(t, tt.assetType, asset.Type, tt.name)
+		assert.Equal(t, common.DataSourceOTM, asset.Source, tt.name)
+	}
+
+	db := findAsset(t, model, "Orders DB")
+	assert.Equal(t, common.GenerateIDHash(testModelPath, "component:orders"), db.ID)
+	assert.Equal(t, "orders", db.Extra["OTMID"])
+	assert.Equal(t, "postgresql-database", db.Extra["OTMType"])
+	assert.True(t, db.StoresCredentials)
+	assert.False(t, findAsset(t, model, "Shop Backend").StoresCredentials)
+}
+
+func TestAnalyze_Dataflows(t *testing.T) {
+	model := analyzeTestModel(t)
+	// the audit log targets a component that is not part of the document
+	require.Len(t, model.DataFlows, 2)
+
+	traffic := findDataflow(t, model, "Shop Traffic")
+	assert.Equal(t, "Customer Browser", traffic.Source)
+	assert.Equal(t, "Shop Backend", traffic.Target)
+	assert.Equal(t, "https", traffic.Protocol)
+	assert.True(t, traffic.Encrypted)
+	assert.True(t, traffic.PublicNetwork)
+	assert.True(t, traffic.Bidirectional)
+
+	queries := findDataflow(t, model, "Order Queries")
+	assert.Equal(t, "jdbc", queries.Protocol)
+	assert.False(t, queries.Encrypted)
+	assert.False(t, queries.Bidirectional)
+	assert.Equal(t, common.DataSourceOTM, queries.DataSource)
+}
+
+func TestAnalyze_Boundaries(t *testing.T) {
+	model := analyzeTestModel(t)
+	require.Len(t, model.Boundaries, 3)
+
+	ids := func(names ...string) []string {
+		result := make([]string, 0, len(names))
+		for _, name := range names {
+			result = append(result, findAsset(t, model, name).ID)
+		}
+		return result
+	}
+	internet, cloud, private := model.Boundaries[0], model.Boundaries[1], model.Boundaries[2]
+	assert.Equal(t, ids("Customer Browser"), internet.ContainedAssets)
+	assert.Equal(t, float64(10), internet.Extra["OTMTrustRating"])
+	// nested zones and nested components are part of the zone around them
+	assert.Equal(t, "Cloud", cloud.DisplayName)
+	assert.Equal(t, ids("Shop Backend", "Order Worker", "Orders DB"), cloud.ContainedAssets)
+	assert.Equal(t, "The VPC of the shop", cloud.Extra["initial-description"])
+	assert.Equal(t, "aws-vpc", cloud.Extra["OTMType"])
+	assert.Equal(t, ids("Shop Backend", "Order Worker", "Orders DB"), private.ContainedAssets)
+}
+
+func TestAnalyze_Threats(t *testing.T) {
+	model := analyzeTestModel(t)
+
+	threats := findAsset(t, model, "Shop Backend").Threats
+	require.Len(t, threats, 1)
+	threat := threats[0]
+	assert.Equal(t, "Missing Order Audit", threat.Title)
+	assert.Equal(t, common.Repudiation, threat.Type)
+	assert.Equal(t, "Medium", threat.Severity)
+	assert.Equal(t, common.Mitigated, threat.Status)
+	assert.Equal(t, "Order changes are not audited.", threat.Description)
+	assert.Equal(t, "Write an audit log entry for every order change.", threat.Mitigation)
+	assert.Equal(t, common.UUIDFormat(threat.InternalID), threat.ID)
+
+	threats = findDataflow(t, model, "Order Queries").Threats
+	require.Len(t, threats, 1)
+	assert.Equal(t, common.Tampering, threats[0].Type)
+	assert.Equal(t, "High", threats[0].Severity)
+	assert.Equal(t, common.Open, threats[0].Status)
+	assert.Empty(t, threats[0].Mitigation)
+}
+
+func TestAnalyze_NoModel(t *testing.T) {
+	_, err := NewOTMAnalyzer(testModelPath, slog.Default()).Analyze(nil)
+	assert.Error(t, err)
+}
+
+func TestComponentAssetType(t *testing.T) {
+	tests := []struct {
+		componentType string
+		expected      common.AssetType
+	}{
+		{"", common.AssetTypeUnknown},
+		{"external-entity", common.AssetTypeExternalEntity},
+		{"mobile-client", common.AssetTypeExternalEntity},
+		{"database", common.AssetTypeDatabase},
+		{"s3-bucket", common.AssetTypeDatabase},
+		{"webserver", common.AssetTypeWebserver},
+		{"api-gateway", common.AssetTypeWebserver},
+		{"infrastructure", common.AssetTypeInfrastructure},
+		{"message-queue", common.AssetTypeInfrastructure},
+		{"application", common.AssetTypeApplication},
+		{"microservice", common.AssetTypeApplication},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, componentAssetType(tt.componentType), tt.componentType)
+	}
+}
@@ -0,0 +1,96 @@
+package otm
+
+// Document is the root of an Open Threat Model file.
+// Only the parts of the OTM schema that Threatcat reads or writes are declared.
+type Document struct {
+	OTMVersion  string       `json:"otmVersion" yaml:"otmVersion"`
+	Project     Project      `json:"project" yaml:"project"`
+	TrustZones  []TrustZone  `json:"trustZones" yaml:"trustZones"`
+	Components  []Component  `json:"components" yaml:"components"`
+	Dataflows   []Dataflow   `json:"dataflows" yaml:"dataflows"`
+	Threats     []Threat     `json:"threats" yaml:"threats"`
+	Mitigations []Mitigation `json:"mitigations" yaml:"mitigations"`
+}
+
+type Project struct {
+	Name        string `json:"name" yaml:"name"`
+	ID          string `json:"id" yaml:"id"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+type TrustZone struct {
+	ID          string         `json:"id" yaml:"id"`
+	Name        string         `json:"name" yaml:"name"`
+	Type        string         `json:"type,omitempty" yaml:"type,omitempty"`
+	Description string         `json:"description,omitempty" yaml:"description,omitempty"`
+	Risk        TrustZoneRisk  `json:"risk" yaml:"risk"`
+	Parent      *Parent        `json:"parent,omitempty" yaml:"parent,omitempty"`
+	Attributes  map[string]any `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+type TrustZoneRisk struct {
+	TrustRating float64 `json:"trustRating" yaml:"trustRating"`
+}
+
+// Parent is the trust zone or the component an element lies in. Exactly one of the fields is set.
+type Parent struct {
+	TrustZone string `json:"trustZone,omitempty" yaml:"trustZone,omitempty"`
+	Component string `json:"component,omitempty" yaml:"component,omitempty"`
+}
+
+type Component struct {
+	ID          string           `json:"id" yaml:"id"`
+	Name        string           `json:"name" yaml:"name"`
+	Type        string           `json:"type,omitempty" yaml:"type,omitempty"`
+	Description string           `json:"description,omitempty" yaml:"description,omitempty"`
+	Parent      Parent           `json:"parent" yaml:"parent"`
+	Tags        []string         `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Threats     []ThreatInstance `json:"threats,omitempty" yaml:"threats,omitempty"`
+	Attributes  map[string]any   `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+type Dataflow struct {
+	ID            string           `json:"id" yaml:"id"`
+	Name          string           `json:"name" yaml:"name"`
+	Description   string           `json:"description,omitempty" yaml:"description,omitempty"`
+	Bidirectional bool             `json:"bidirectional" yaml:"bidirectional"`
+	Source        string           `json:"source" yaml:"source"`
+	Destination   string           `json:"destination" yaml:"destination"`
+	Tags          []string         `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Threats       []ThreatInstance `json:"threats,omitempty" yaml:"threats,omitempty"`
+	Attributes    map[string]any   `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+// ThreatInstance is a threat of the threats list that applies to a component or a dataflow
+type ThreatInstance struct {
+	Threat      string               `json:"threat" yaml:"threat"`
+	State       string               `json:"state" yaml:"state"`
+	Mitigations []MitigationInstance `json:"mitigations,omitempty" yaml:"mitigations,omitempty"`
+}
+
+type MitigationInstance struct {
+	Mitigation string `json:"mitigation" yaml:"mitigation"`
+	State      string `json:"state" yaml:"state"`
+}
+
+type Threat struct {
+	ID          string         `json:"id" yaml:"id"`
+	Name        string         `json:"name" yaml:"name"`
+	Description string         `json:"description,omitempty" yaml:"description,omitempty"`
+	Categories  []string       `json:"categories" yaml:"categories"`
+	Risk        ThreatRisk     `json:"risk" yaml:"risk"`
+	Attributes  map[string]any `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+// ThreatRisk rates the likelihood and the impact of a threat from 0 to 100
+type ThreatRisk struct {
+	Likelihood float64 `json:"likelihood" yaml:"likelihood"`
+	Impact     float64 `json:"impact" yaml:"impact"`
+}
+
+type Mitigation struct {
+	ID            string  `json:"id" yaml:"id"`
+	Name          string  `json:"name" yaml:"name"`
+	Description   string  `json:"description,omitempty" yaml:"description,omitempty"`
+	RiskReduction float64 `json:"riskReduction" yaml:"riskReduction"`
+}
@@ -0,0 +1,348 @@
+package otm
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/threatcat-dev/threatcat/internal/common"
+	"gopkg.in/yaml.v3"
+)
+
+// otmVersion is the version of the Open Threat Model format that is written
+const otmVersion = "0.2.0"
+
+// defaultZoneID is the ID of the trust zone of the components without a trust boundary,
+// as every component of an OTM document needs a parent
+const defaultZoneID = "other-assets"
+
+// trust ratings of the zones that Threatcat creates, from 0 (untrusted) to 100 (trusted)
+const (
+	defaultTrustRating  float64 = 50
+	externalTrustRating float64 = 10
+)
+
+type OTMOutput struct {
+	OutputPath string
+	// Title is the name of the project of the generated document
+	Title  string
+	cl     changelog
+	logger *slog.Logger
+}
+
+type changelog interface {
+	AddEntry(string)
+}
+
+func NewOTMOutput(outputPath string, cl changelog, logger *slog.Logger) *OTMOutput {
+	return &OTMOutput{
+		OutputPath: outputPath,
+		Title:      "new OTM Output",
+		cl:         cl,
+		logger:     logger.With("package", "otm", "component", "OTMOutput"),
+	}
+}
+
+// Generate writes the model as an OTM document. Files ending in .yaml or .yml are written as YAML, all others as JSON.
+// Existing files are overwritten.
+func (oo *OTMOutput) Generate(model *common.ThreatModel) error {
+	oo.logger.Debug("Generating OTM document")
+	oo.cl.AddEntry("Starting the OTM document generation from scratch.")
+
+	document := oo.buildDocument(model)
+	var content []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(oo.OutputPath)) {
+	case ".yaml", ".yml":
+		content, err = yaml.Marshal(document)
+	default:
+		content, err = json.MarshalIndent(document, "", "  ")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal OTM document: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(oo.OutputPath), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(oo.OutputPath, content, 0644)
+	if err != nil {
+		return err
+	}
+
+	oo.logger.Debug("OTM document has been written to file", "filePath", oo.OutputPath)
+	return nil
+}
+
+// buildDocument maps the assets to components, the trust boundaries to trust zones and the dataflows to dataflows.
+// The threats of assets and dataflows are listed once in the threats of the document and referenced by the elements.
+func (oo *OTMOutput) buildDocument(model *common.ThreatModel) *Document {
+	document := &Document{
+		OTMVersion: otmVersion,
+		Project: Project{
+			Name:        oo.Title,
+			ID:          slug(oo.Title),
+			Description: "this model is auto generated by threatcat",
+		},
+		TrustZones:  []TrustZone{},
+		Components:  []Component{},
+		Dataflows:   []Dataflow{},
+		Threats:     []Threat{},
+		Mitigations: []Mitigation{},
+	}
+
+	componentIDs := make(map[string]string, len(model.Assets)) // asset ID -> component ID
+	for _, asset := range model.Assets {
+		componentIDs[asset.ID] = common.GetOr(asset.Extra, "OTMID", asset.ID)
+	}
+
+	zoneIDs := oo.addTrustZones(document, model, componentIDs)
+	for _, asset := range model.Assets {
+		zone, ok := zoneIDs[asset.ID]
+		if !ok {
+			zone = oo.defaultZone(document)
+		}
+		component := Component{
+			ID:          componentIDs[asset.ID],
+			Name:        asset.DisplayName,
+			Type:        common.GetOr(asset.Extra, "OTMType", componentType(asset.Type)),
+			Description: fmt.Sprintf("%s, generated by Threatcat from %s", asset.DisplayName, asset.Source.ShortString()),
+			Parent:      Parent{TrustZone: zone},
+			Threats:     oo.addThreats(document, asset.Threats),
+			Attributes:  map[string]any{"source": asset.Source.ShortString()},
+		}
+		if asset.StoresCredentials {
+			component.Attributes["storesCredentials"] = true
+		}
+		document.Components = append(document.Components, component)
+	}
+
+	for _, dataflow := range model.DataFlows {
+		source := common.EndpointAsset(model.Assets, dataflow, dataflow.Source)
+		target := common.EndpointAsset(model.Assets, dataflow, dataflow.Target)
+		if source.ID == "" || target.ID == "" {
+			oo.logger.Warn("Dataflow has no component at one of its ends and is skipped", "dataflow", dataflow.Name)
+			oo.cl.AddEntry(fmt.Sprintf("Dataflow '%s' could not be added, because '%s' or '%s' was not found", dataflow.Name, dataflow.Source, dataflow.Target))
+			continue
+		}
+		flow := Dataflow{
+			ID:            common.GetOr(dataflow.Extra, "OTMID", dataflow.ID),
+			Name:          dataflow.Name,
+			Bidirectional: dataflow.Bidirectional,
+			Source:        componentIDs[source.ID],
+			Destination:   componentIDs[target.ID],
+			Threats:       oo.addThreats(document, dataflow.Threats),
+			Attributes: map[string]any{
+				"encrypted":     dataflow.Encrypted,
+				"publicNetwork": dataflow.PublicNetwork,
+			},
+		}
+		if dataflow.Protocol != "" {
+			flow.Attributes["protocol"] = dataflow.Protocol
+		}
+		document.Dataflows = append(document.Dataflows, flow)
+	}
+
+	oo.cl.AddEntry(fmt.Sprintf("OTM document has been generated with %d components, %d dataflows and %d trust zones",
+		len(document.Components), len(document.Dataflows), len(document.TrustZones)))
+	return document
+}
+
+// addTrustZones adds a trust zone for every trust boundary and returns the zone of each asset. A component of an
+// OTM document has a single parent, so each asset is put into its innermost zone and each zone is nested into the
+// smallest zone that contains all of its assets.
+func (oo *OTMOutput) addTrustZones(document *Document, model *common.ThreatModel, componentIDs map[string]string) map[string]string {
+	external := make(map[string]bool)
+	for _, asset := range model.Assets {
+		if asset.Type == common.AssetTypeExternalEntity {
+			external[asset.ID] = true
+		}
+	}
+
+	members := make([][]string, 0, len(model.Boundaries)) // assets of the model in each boundary
+	for _, boundary := range model.Boundaries {
+		contained := make([]string, 0, len(boundary.ContainedAssets))
+		for _, id := range boundary.ContainedAssets {
+			if _, ok := componentIDs[id]; ok && !slices.Contains(contained, id) {
+				contained = append(contained, id)
+			}
+		}
+		members = append(members, contained)
+	}
+	parents := common.BoundaryParents(members)
+
+	zoneIDs := make([]string, len(model.Boundaries))
+	for i, boundary := range model.Boundaries {
+		zoneIDs[i] = common.GetOr(boundary.Extra, "OTMID", boundary.ID)
+	}
+	for i, boundary := range model.Boundaries {
+		rating := defaultTrustRating
+		if len(members[i]) > 0 && !slices.ContainsFunc(members[i], func(id string) bool { return !external[id] }) {
+			rating = externalTrustRating
+		}
+		zone := TrustZone{
+			ID:          zoneIDs[i],
+			Name:        boundary.DisplayName,
+			Type:        common.GetOr(boundary.Extra, "OTMType", ""),
+			Description: common.GetOr(boundary.Extra, "initial-description", ""),
+			Risk:        TrustZoneRisk{TrustRating: common.GetOr(boundary.Extra, "OTMTrustRating", rating)},
+		}
+		if parents[i] >= 0 {
+			zone.Parent = &Parent{TrustZone: zoneIDs[parents[i]]}
+		}
+		document.TrustZones = append(document.TrustZones, zone)
+	}
+
+	assetZones := make(map[string]string)
+	for _, asset := range model.Assets {
+		innermost, others := common.InnermostBoundary(members, parents, asset.ID)
+		if innermost < 0 {
+			continue
+		}
+		assetZones[asset.ID] = zoneIDs[innermost]
+		if len(others) > 0 {
+			dropped := make([]string, 0, len(others))
+			for _, i := range others {
+				dropped = append(dropped, model.Boundaries[i].DisplayName)
+			}
+			oo.logger.Warn("Asset is in overlapping trust boundaries, but OTM allows only one", "asset", asset.DisplayName, "boundary", model.Boundaries[innermost].DisplayName, "dropped", dropped)
+			oo.cl.AddEntry(fmt.Sprintf("Asset '%s' has been put into trust zone '%s' only, it is also in '%s'", asset.DisplayName, model.Boundaries[innermost].DisplayName, strings.Join(dropped, "', '")))
+		}
+	}
+	return assetZones
+}
+
+// defaultZone returns the ID of the zone of the components without a trust boundary. The zone is added on first use.
+func (oo *OTMOutput) defaultZone(document *Document) string {
+	for _, zone := range document.TrustZones {
+		if zone.ID == defaultZoneID {
+			return zone.ID
+		}
+	}
+	document.TrustZones = append(document.TrustZones, TrustZone{
+		ID:          defaultZoneID,
+		Name:        "Other assets",
+		Description: "Assets without a trust boundary",
+		Risk:        TrustZoneRisk{TrustRating: defaultTrustRating},
+	})
+	return defaultZoneID
+}
+
+// addThreats adds the threats to the threats of the document, and their mitigations to the mitigations,
+// and returns the references of an element to them
+func (oo *OTMOutput) addThreats(document *Document, threats []common.Threat) []ThreatInstance {
+	instances := make([]ThreatInstance, 0, len(threats))
+	for _, threat := range threats {
+		id := threat.ID
+		if id == "" {
+			id = threat.InternalID
+		}
+		if !slices.ContainsFunc(document.Threats, func(t Threat) bool { return t.ID == id }) {
+			rating := severityRating(threat.Severity)
+			categories := []string{}
+			if category, ok := strideCategories[threat.Type]; ok {
+				categories = append(categories, category)
+			}
+			document.Threats = append(document.Threats, Threat{
+				ID:          id,
+				Name:        threat.Title,
+				Description: threat.Description,
+				Categories:  categories,
+				Risk:        ThreatRisk{Likelihood: rating, Impact: rating},
+			})
+		}
+
+		instance := ThreatInstance{Threat: id, State: threatState(threat.Status)}
+		if threat.Mitigation != "" {
+			mitigationID := id + "-mitigation"
+			if !slices.ContainsFunc(document.Mitigations, func(m Mitigation) bool { return m.ID == mitigationID }) {
+				document.Mitigations = append(document.Mitigations, Mitigation{
+					ID:            mitigationID,
+					Name:          fmt.Sprintf("Mitigation of %s", threat.Title),
+					Description:   threat.Mitigation,
+					RiskReduction: 50,
+				})
+			}
+			state := "required"
+			if threat.Status == common.Mitigated {
+				state = "implemented"
+			}
+			instance.Mitigations = []MitigationInstance{{Mitigation: mitigationID, State: state}}
+		}
+		instances = append(instances, instance)
+	}
+	return instances
+}
+
+// componentTypes are the component types that are written for the asset types
+var componentTypes = map[common.AssetType]string{
+	common.AssetTypeApplication:    "application",
+	common.AssetTypeDatabase:       "database",
+	common.AssetTypeWebserver:      "webserver",
+	common.AssetTypeInfrastructure: "infrastructure",
+	common.AssetTypeExternalEntity: "external-entity",
+}
+
+func componentType(assetType common.AssetType) string {
+	if t, ok := componentTypes[assetType]; ok {
+		return t
+	}
+	return "unknown"
+}
+
+// strideCategories are the threat categories that are written for the STRIDE threat types
+var strideCategories = map[common.ThreatType]string{
+	common.Spoofing:              "Spoofing",
+	common.Tampering:             "Tampering",
+	common.Repudiation:           "Repudiation",
+	common.InformationDisclosure: "Information Disclosure",
+	common.DenialOfService:       "Denial of Service",
+	common.ElevationOfPrivilege:  "Elevation of Privilege",
+}
+
+// severityRating maps the severity of a threat to the likelihood and impact of OTM, from 0 to 100
+func severityRating(severity string) float64 {
+	switch strings.ToLower(severity) {
+	case "low":
+		return 25
+	case "high":
+		return 75
+	case "critical":
+		return 100
+	}
+	return 50
+}
+
+// threatState maps the status of a threat to the state of its threat instance
+func threatState(status common.Status) string {
+	switch status {
+	case common.Mitigated:
+		return "mitigated"
+	case common.NotApplicable:
+		return "not-applicable"
+	}
+	return "open"
+}
+
+// slug turns a name into an ID of lower case letters, digits and dashes
+func slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
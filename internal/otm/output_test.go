@@ -0,0 +1,209 @@
+package otm
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+	"gopkg.in/yaml.v3"
+)
+
+// recordingChangelog keeps all entries for assertions
+type recordingChangelog struct {
+	entries *[]string
+}
+
+func (rc recordingChangelog) AddEntry(entry string) {
+	*rc.entries = append(*rc.entries, entry)
+}
+
+func newTestOutput(path string) (*OTMOutput, *[]string) {
+	entries := []string{}
+	return NewOTMOutput(path, recordingChangelog{entries: &entries}, slog.Default()), &entries
+}
+
+// composeModel is a docker compose project with a web server on the frontend network, an api on both networks
+// and a database on the backend network, reachable from the internet
+func composeModel() *common.ThreatModel {
+	user := common.Asset{ID: "user", DisplayName: "Internet / External User", Type: common.AssetTypeExternalEntity, Source: common.DataSourceDockerCompose}
+	web := common.Asset{ID: "web", DisplayName: "web", Type: common.AssetTypeWebserver, Source: common.DataSourceDockerCompose}
+	api := common.Asset{ID: "api", DisplayName: "api", Type: common.AssetTypeApplication, Source: common.DataSourceDockerCompose,
+		Threats: []common.Threat{
+			{ID: "t1", Title: "Spoofed API client", Type: common.Spoofing, Severity: "High", Status: common.Mitigated, Mitigation: "Use mTLS"},
+		}}
+	db := common.Asset{ID: "db", DisplayName: "db", Type: common.AssetTypeDatabase, Source: common.DataSourceDockerCompose, StoresCredentials: true}
+	worker := common.Asset{ID: "worker", DisplayName: "worker", Type: common.AssetTypeApplication, Source: common.DataSourceDockerCompose}
+	return &common.ThreatModel{
+		Assets: []common.Asset{user, web, api, db, worker},
+		DataFlows: []common.DataFlow{
+			{ID: "1", Name: "public", Source: "Internet / External User", Target: "web", Protocol: "https", Encrypted: true, PublicNetwork: true},
+			{ID: "2", Name: "rest", Source: "web", Target: "api", Protocol: "http"},
+			{ID: "3", Name: "sql", Source: "api", Target: "db", Protocol: "postgresql", Bidirectional: true,
+				Threats: []common.Threat{{ID: "t2", Title: "Leaked queries", Type: common.InformationDisclosure, Severity: "Low"}}},
+			{ID: "4", Name: "missing", Source: "api", Target: "queue", Protocol: "amqp"},
+		},
+		Boundaries: []common.TrustBoundary{
+			{ID: "internet", DisplayName: "Internet", ContainedAssets: []string{"user"}, Source: common.DataSourceDockerCompose},
+			{ID: "default", DisplayName: "default", ContainedAssets: []string{"web", "api", "db"}, Source: common.DataSourceDockerCompose,
+				Extra: map[string]any{"initial-description": "General trust boundary for docker compose file 'docker-compose.yml'"}},
+			{ID: "frontend", DisplayName: "frontend", ContainedAssets: []string{"web", "api"}, Source: common.DataSourceDockerCompose},
+			{ID: "backend", DisplayName: "backend", ContainedAssets: []string{"api", "db"}, Source: common.DataSourceDockerCompose},
+		},
+	}
+}
+
+func readJSON(t *testing.T, path string) Document {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var document Document
+	require.NoError(t, json.Unmarshal(content, &document))
+	return document
+}
+
+func findComponent(t *testing.T, document Document, name string) Component {
+	t.Helper()
+	for _, component := range document.Components {
+		if component.Name == name {
+			return component
+		}
+	}
+	require.Failf(t, "component not found", name)
+	return Component{}
+}
+
+func findZone(t *testing.T, document Document, id string) TrustZone {
+	t.Helper()
+	for _, zone := range document.TrustZones {
+		if zone.ID == id {
+			return zone
+		}
+	}
+	require.Failf(t, "trust zone not found", id)
+	return TrustZone{}
+}
+
+func TestGenerate(t *testing.T) {
+	const path = "testdata/testoutput_otm.json"
+	defer os.Remove(path)
+
+	output, entries := newTestOutput(path)
+	output.Title = "Compose Shop"
+	require.NoError(t, output.Generate(composeModel()))
+	document := readJSON(t, path)
+
+	assert.Equal(t, "0.2.0", document.OTMVersion)
+	assert.Equal(t, Project{Name: "Compose Shop", ID: "compose-shop", Description: "this model is auto generated by threatcat"}, document.Project)
+	assert.Len(t, document.Components, 5)
+	// the missing target drops its dataflow
+	assert.Len(t, document.Dataflows, 3)
+	assert.Contains(t, *entries, "Dataflow 'missing' could not be added, because 'api' or 'queue' was not found")
+
+	user := findComponent(t, document, "Internet / External User")
+	assert.Equal(t, "user", user.ID)
+	assert.Equal(t, "external-entity", user.Type)
+	assert.Equal(t, Parent{TrustZone: "internet"}, user.Parent)
+
+	db := findComponent(t, document, "db")
+	assert.Equal(t, "database", db.Type)
+	assert.Equal(t, Parent{TrustZone: "backend"}, db.Parent)
+	assert.Equal(t, map[string]any{"source": "Docker Compose", "storesCredentials": true}, db.Attributes)
+
+	// components without a trust boundary are put into a zone of their own
+	assert.Equal(t, Parent{TrustZone: defaultZoneID}, findComponent(t, document, "worker").Parent)
+	assert.Equal(t, float64(defaultTrustRating), findZone(t, document, defaultZoneID).Risk.TrustRating)
+
+	assert.Equal(t, Dataflow{
+		ID: "3", Name: "sql", Bidirectional: true, Source: "api", Destination: "db",
+		Threats:    []ThreatInstance{{Threat: "t2", State: "open"}},
+		Attributes: map[string]any{"protocol": "postgresql", "encrypted": false, "publicNetwork": false},
+	}, document.Dataflows[2])
+}
+
+func TestGenerate_TrustZones(t *testing.T) {
+	const path = "testdata/testoutput_otm_zones.json"
+	defer os.Remove(path)
+
+	output, entries := newTestOutput(path)
+	require.NoError(t, output.Generate(composeModel()))
+	document := readJSON(t, path)
+
+	internet := findZone(t, document, "internet")
+	assert.Equal(t, float64(externalTrustRating), internet.Risk.TrustRating)
+	assert.Nil(t, internet.Parent)
+
+	assert.Equal(t, "General trust boundary for docker compose file 'docker-compose.yml'", findZone(t, document, "default").Description)
+	assert.Equal(t, &Parent{TrustZone: "default"}, findZone(t, document, "frontend").Parent)
+	assert.Equal(t, &Parent{TrustZone: "default"}, findZone(t, document, "backend").Parent)
+
+	// the api is in both networks, but a component has only one parent
+	assert.Equal(t, Parent{TrustZone: "backend"}, findComponent(t, document, "api").Parent)
+	assert.Contains(t, *entries, "Asset 'api' has been put into trust zone 'backend' only, it is also in 'frontend'")
+}
+
+func TestGenerate_Threats(t *testing.T) {
+	const path = "testdata/testoutput_otm_threats.json"
+	defer os.Remove(path)
+
+	output, _ := newTestOutput(path)
+	require.NoError(t, output.Generate(composeModel()))
+	document := readJSON(t, path)
+
+	assert.Equal(t, []Threat{
+		{ID: "t1", Name: "Spoofed API client", Categories: []string{"Spoofing"}, Risk: ThreatRisk{Likelihood: 75, Impact: 75}},
+		{ID: "t2", Name: "Leaked queries", Categories: []string{"Information Disclosure"}, Risk: ThreatRisk{Likelihood: 25, Impact: 25}},
+	}, document.Threats)
+	assert.Equal(t, []Mitigation{
+		{ID: "t1-mitigation", Name: "Mitigation of Spoofed API client", Description: "Use mTLS", RiskReduction: 50},
+	}, document.Mitigations)
+	assert.Equal(t, []ThreatInstance{
+		{Threat: "t1", State: "mitigated", Mitigations: []MitigationInstance{{Mitigation: "t1-mitigation", State: "implemented"}}},
+	}, findComponent(t, document, "api").Threats)
+}
+
+func TestGenerate_YAML(t *testing.T) {
+	const path = "testdata/testoutput_otm.yaml"
+	defer os.Remove(path)
+
+	output, _ := newTestOutput(path)
+	require.NoError(t, output.Generate(composeModel()))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var document Document
+	require.NoError(t, yaml.Unmarshal(content, &document))
+	assert.Equal(t, "0.2.0", document.OTMVersion)
+	assert.Len(t, document.Components, 5)
+}
+
+// TestGenerate_RoundTrip tests that a document read from OTM is written with the same IDs, types, zones and threats
+func TestGenerate_RoundTrip(t *testing.T) {
+	const path = "testdata/testoutput_otm_roundtrip.json"
+	defer os.Remove(path)
+
+	output, _ := newTestOutput(path)
+	require.NoError(t, output.Generate(analyzeTestModel(t)))
+	document := readJSON(t, path)
+
+	shop := findComponent(t, document, "Shop Backend")
+	assert.Equal(t, "shop", shop.ID)
+	assert.Equal(t, "web-service", shop.Type)
+	assert.Equal(t, Parent{TrustZone: "private"}, shop.Parent)
+	assert.Equal(t, Parent{TrustZone: "private"}, findComponent(t, document, "Order Worker").Parent)
+
+	private := findZone(t, document, "private")
+	assert.Equal(t, &Parent{TrustZone: "cloud"}, private.Parent)
+	assert.Equal(t, float64(80), private.Risk.TrustRating)
+	assert.Equal(t, "aws-vpc", findZone(t, document, "cloud").Type)
+
+	require.Len(t, shop.Threats, 1)
+	assert.Equal(t, "mitigated", shop.Threats[0].State)
+	assert.Len(t, document.Threats, 2)
+	assert.Len(t, document.Mitigations, 1)
+	assert.Equal(t, "order-queries", document.Dataflows[1].ID)
+	assert.Equal(t, "jdbc", document.Dataflows[1].Attributes["protocol"])
+}
@@ -0,0 +1,50 @@
+package otm
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OTMParser parses Open Threat Model files in JSON or YAML
+type OTMParser struct {
+	filePath string
+	logger   *slog.Logger
+}
+
+// NewOTMParser creates a new instance of OTMParser
+func NewOTMParser(filePath string, logger *slog.Logger) *OTMParser {
+	return &OTMParser{
+		filePath: filePath,
+		logger:   logger.With("package", "otm", "component", "OTMParser"),
+	}
+}
+
+// Parse reads the OTM document. Files starting with a brace are read as JSON, all others as YAML.
+func (p *OTMParser) Parse() (*Document, error) {
+	p.logger.Debug("Parsing OTM file", "filePath", p.filePath)
+	content, err := os.ReadFile(p.filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OTM file: %w", err)
+	}
+
+	var document Document
+	if strings.HasPrefix(strings.TrimSpace(string(content)), "{") {
+		err = json.Unmarshal(content, &document)
+	} else {
+		err = yaml.Unmarshal(content, &document)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OTM file: %w", err)
+	}
+	if document.OTMVersion == "" {
+		return nil, fmt.Errorf("file %s is not an OTM document: otmVersion is missing", p.filePath)
+	}
+
+	p.logger.Debug("Parsed OTM file", "version", document.OTMVersion, "componentCount", len(document.Components))
+	return &document, nil
+}
@@ -0,0 +1,44 @@
+package otm
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	document, err := NewOTMParser("testdata/otm.json", slog.Default()).Parse()
+	require.NoError(t, err)
+
+	assert.Equal(t, "0.2.0", document.OTMVersion)
+	assert.Equal(t, "shop", document.Project.ID)
+	assert.Len(t, document.Components, 4)
+	assert.Equal(t, &Parent{TrustZone: "cloud"}, document.TrustZones[2].Parent)
+	assert.Equal(t, Parent{Component: "shop"}, document.Components[2].Parent)
+	assert.Equal(t, "https", document.Dataflows[0].Attributes["protocol"])
+	assert.Equal(t, ThreatRisk{Likelihood: 30, Impact: 90}, document.Threats[1].Risk)
+}
+
+func TestParse_YAML(t *testing.T) {
+	path := t.TempDir() + "/otm.yaml"
+	content := "otmVersion: 0.2.0\nproject:\n  name: Shop\n  id: shop\ncomponents:\n  - id: web\n    name: Web\n    parent:\n      trustZone: dmz\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	document, err := NewOTMParser(path, slog.Default()).Parse()
+	require.NoError(t, err)
+	require.Len(t, document.Components, 1)
+	assert.Equal(t, "dmz", document.Components[0].Parent.TrustZone)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := NewOTMParser("testdata/missing.json", slog.Default()).Parse()
+	assert.Error(t, err)
+
+	path := t.TempDir() + "/compose.yaml"
+	require.NoError(t, os.WriteFile(path, []byte("services:\n  web:\n    image: nginx\n"), 0644))
+	_, err = NewOTMParser(path, slog.Default()).Parse()
+	assert.ErrorContains(t, err, "not an OTM document")
+}
@@ -0,0 +1,178 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/threatcat-dev/threatcat/internal/common"
+	"github.com/threatcat-dev/threatcat/internal/markdown"
+	"github.com/threatcat-dev/threatcat/internal/otm"
+	"github.com/threatcat-dev/threatcat/internal/sarif"
+	"github.com/threatcat-dev/threatcat/internal/threagile"
+	"github.com/threatcat-dev/threatcat/internal/threatdragon"
+)
+
+// names of the formats of the default registry
+const (
+	FormatThreatDragon = "threatdragon"
+	FormatThreagile    = "threagile"
+	FormatOTM          = "otm"
+	FormatMarkdown     = "markdown"
+	FormatSarif        = "sarif"
+)
+
+// Writer writes a threat model to a file in one output format.
+// Writers must not modify the model, as all writers of a run receive the same merged model.
+type Writer interface {
+	Generate(model *common.ThreatModel) error
+}
+
+// Changelog collects the changes that the writers make
+type Changelog interface {
+	AddEntry(string)
+}
+
+// Options are the settings of a run that are passed to every writer factory. Each writer uses the settings of its format only.
+type Options struct {
+	Changelog       Changelog
+	Logger          *slog.Logger
+	DiagramStrategy threatdragon.DiagramStrategy
+	Layout          threatdragon.LayoutStrategy
+}
+
+// Factory creates a writer for an output path
+type Factory func(path string, opts Options) Writer
+
+// Target is an output file and the format it is written in
+type Target struct {
+	Format string
+	Path   string
+}
+
+// Registry maps the names and aliases of output formats to the factories of their writers
+type Registry struct {
+	factories map[string]Factory
+	aliases   map[string]string // alias -> format name
+	formats   []string          // format names in the order of registration
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: make(map[string]Factory),
+		aliases:   make(map[string]string),
+		formats:   []string{},
+	}
+}
+
+// DefaultRegistry returns a registry with all output formats of Threatcat
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.Register(FormatThreatDragon, func(path string, opts Options) Writer {
+		output := threatdragon.NewThreatdragonOutput(path, opts.Changelog, opts.Logger)
+		output.DiagramStrategy = opts.DiagramStrategy
+		output.Layout = opts.Layout
+		return output
+	}, "td")
+	r.Register(FormatThreagile, func(path string, opts Options) Writer {
+		return threagile.NewThreagileOutput(path, opts.Changelog, opts.Logger)
+	})
+	r.Register(FormatOTM, func(path string, opts Options) Writer {
+		return otm.NewOTMOutput(path, opts.Changelog, opts.Logger)
+	})
+	r.Register(FormatMarkdown, func(path string, opts Options) Writer {
+		return markdown.NewMarkdownOutput(path, opts.Changelog, opts.Logger)
+	}, "md")
+	r.Register(FormatSarif, func(path string, opts Options) Writer {
+		return sarif.NewSarifOutput(path, opts.Changelog, opts.Logger)
+	})
+	return r
+}
+
+// Register adds an output format. A format registered again replaces the former factory.
+func (r *Registry) Register(name string, factory Factory, aliases ...string) {
+	if _, ok := r.factories[name]; !ok {
+		r.formats = append(r.formats, name)
+	}
+	r.factories[name] = factory
+	for _, alias := range aliases {
+		r.aliases[alias] = name
+	}
+}
+
+// Formats returns the names of the registered formats
+func (r *Registry) Formats() []string {
+	return slices.Clone(r.formats)
+}
+
+// Lookup resolves a format name or alias to the name of the format
+func (r *Registry) Lookup(format string) (string, bool) {
+	format = strings.ToLower(format)
+	if name, ok := r.aliases[format]; ok {
+		return name, true
+	}
+	_, ok := r.factories[format]
+	return format, ok
+}
+
+// ParseTarget reads an output argument of the form format:path, e.g. md:report.md.
+// Arguments without a format prefix are written in the default format. Single letter prefixes are
+// Windows drive letters and belong to the path.
+func (r *Registry) ParseTarget(value, defaultFormat string) (Target, error) {
+	format, path := defaultFormat, value
+	if prefix, rest, ok := strings.Cut(value, ":"); ok && len(prefix) > 1 && !strings.ContainsAny(prefix, `/\.`) {
+		format, path = prefix, rest
+	}
+	name, ok := r.Lookup(format)
+	if !ok {
+		return Target{}, fmt.Errorf("unknown output format '%s', expected one of %s", format, strings.Join(r.formats, ", "))
+	}
+	if path == "" {
+		return Target{}, fmt.Errorf("output file path for format '%s' must be provided", name)
+	}
+	return Target{Format: name, Path: path}, nil
+}
+
+// ParseTargets reads all output arguments. A file may be written only once per run.
+func (r *Registry) ParseTargets(values []string, defaultFormat string) ([]Target, error) {
+	targets := make([]Target, 0, len(values))
+	for _, value := range values {
+		target, err := r.ParseTarget(value, defaultFormat)
+		if err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(targets, func(t Target) bool { return filepath.Clean(t.Path) == filepath.Clean(target.Path) }) {
+			return nil, fmt.Errorf("output file %s is given more than once", target.Path)
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// NewWriter creates the writer of a target
+func (r *Registry) NewWriter(target Target, opts Options) (Writer, error) {
+	name, ok := r.Lookup(target.Format)
+	if !ok {
+		return nil, fmt.Errorf("unknown output format '%s'", target.Format)
+	}
+	return r.factories[name](target.Path, opts), nil
+}
+
+// Generate writes the model to all targets. A failing writer does not stop the others, their errors are joined.
+func (r *Registry) Generate(targets []Target, model *common.ThreatModel, opts Options) error {
+	var errs []error
+	for _, target := range targets {
+		opts.Logger.Info("Generating output", "format", target.Format, "filepath", target.Path)
+		writer, err := r.NewWriter(target, opts)
+		if err == nil {
+			err = writer.Generate(model)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not generate %s output %s: %w", target.Format, target.Path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
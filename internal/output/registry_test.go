@@ -0,0 +1,165 @@
+package output
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// recordingChangelog keeps all entries for assertions
+type recordingChangelog struct {
+	entries *[]string
+}
+
+func (rc recordingChangelog) AddEntry(entry string) {
+	*rc.entries = append(*rc.entries, entry)
+}
+
+// recordingWriter remembers the models it has been given
+type recordingWriter struct {
+	path   string
+	models *[]*common.ThreatModel
+	err    error
+}
+
+func (rw recordingWriter) Generate(model *common.ThreatModel) error {
+	*rw.models = append(*rw.models, model)
+	return rw.err
+}
+
+func TestParseTarget(t *testing.T) {
+	r := DefaultRegistry()
+	tests := []struct {
+		value    string
+		expected Target
+	}{
+		{"out.json", Target{FormatThreatDragon, "out.json"}},
+		{"td:model.json", Target{FormatThreatDragon, "model.json"}},
+		{"md:report.md", Target{FormatMarkdown, "report.md"}},
+		{"markdown:docs/report.md", Target{FormatMarkdown, "docs/report.md"}},
+		{"SARIF:threats.sarif", Target{FormatSarif, "threats.sarif"}},
+		{"otm:model.otm.yaml", Target{FormatOTM, "model.otm.yaml"}},
+		{`C:\models\out.json`, Target{FormatThreatDragon, `C:\models\out.json`}},
+		{"./odd:name.json", Target{FormatThreatDragon, "./odd:name.json"}},
+	}
+	for _, tt := range tests {
+		target, err := r.ParseTarget(tt.value, FormatThreatDragon)
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.expected, target, tt.value)
+	}
+
+	target, err := r.ParseTarget("threagile.yaml", "threagile")
+	require.NoError(t, err)
+	assert.Equal(t, Target{FormatThreagile, "threagile.yaml"}, target)
+}
+
+func TestParseTarget_Invalid(t *testing.T) {
+	r := DefaultRegistry()
+
+	_, err := r.ParseTarget("xml:out.xml", FormatThreatDragon)
+	assert.EqualError(t, err, "unknown output format 'xml', expected one of threatdragon, threagile, otm, markdown, sarif")
+	_, err = r.ParseTarget("out.json", "xml")
+	assert.Error(t, err)
+	_, err = r.ParseTarget("md:", FormatThreatDragon)
+	assert.EqualError(t, err, "output file path for format 'markdown' must be provided")
+}
+
+func TestParseTargets(t *testing.T) {
+	r := DefaultRegistry()
+
+	targets, err := r.ParseTargets([]string{"td:model.json", "md:report.md", "sarif:threats.sarif"}, FormatThreatDragon)
+	require.NoError(t, err)
+	assert.Equal(t, []Target{{FormatThreatDragon, "model.json"}, {FormatMarkdown, "report.md"}, {FormatSarif, "threats.sarif"}}, targets)
+
+	_, err = r.ParseTargets([]string{"out.json", "otm:./out.json"}, FormatThreatDragon)
+	assert.EqualError(t, err, "output file ./out.json is given more than once")
+}
+
+func TestRegister(t *testing.T) {
+	r := NewRegistry()
+	models := []*common.ThreatModel{}
+	paths := []string{}
+	factory := func(path string, opts Options) Writer {
+		paths = append(paths, path)
+		return recordingWriter{path: path, models: &models}
+	}
+	r.Register("first", factory, "1")
+	r.Register("second", factory)
+	r.Register("first", factory)
+	assert.Equal(t, []string{"first", "second"}, r.Formats())
+
+	name, ok := r.Lookup("1")
+	assert.True(t, ok)
+	assert.Equal(t, "first", name)
+	_, ok = r.Lookup("third")
+	assert.False(t, ok)
+
+	writer, err := r.NewWriter(Target{Format: "1", Path: "a"}, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "a", writer.(recordingWriter).path)
+	_, err = r.NewWriter(Target{Format: "third", Path: "b"}, Options{})
+	assert.Error(t, err)
+}
+
+func TestGenerate(t *testing.T) {
+	r := NewRegistry()
+	models := []*common.ThreatModel{}
+	r.Register("ok", func(path string, opts Options) Writer {
+		return recordingWriter{path: path, models: &models}
+	})
+	r.Register("failing", func(path string, opts Options) Writer {
+		return recordingWriter{path: path, models: &models, err: errors.New("disk full")}
+	})
+
+	model := common.EmptyThreatModel()
+	targets := []Target{{"failing", "a"}, {"ok", "b"}, {"ok", "c"}}
+	err := r.Generate(targets, &model, Options{Logger: slog.Default()})
+
+	// every writer runs and receives the same model
+	assert.EqualError(t, err, "could not generate failing output a: disk full")
+	require.Len(t, models, 3)
+	for _, m := range models {
+		assert.Same(t, &model, m)
+	}
+}
+
+func TestDefaultRegistry_Generate(t *testing.T) {
+	dir := t.TempDir()
+	r := DefaultRegistry()
+	values := []string{
+		filepath.Join(dir, "model.json"),
+		"threagile:" + filepath.Join(dir, "threagile.yaml"),
+		"otm:" + filepath.Join(dir, "model.otm.json"),
+		"md:" + filepath.Join(dir, "report.md"),
+		"sarif:" + filepath.Join(dir, "threats.sarif"),
+	}
+	targets, err := r.ParseTargets(values, FormatThreatDragon)
+	require.NoError(t, err)
+
+	model := common.ThreatModel{
+		Assets: []common.Asset{
+			{ID: "web", DisplayName: "web", Type: common.AssetTypeWebserver, Source: common.DataSourceDockerCompose,
+				Threats: []common.Threat{{ID: "t1", Title: "Unpinned image", Type: common.Tampering, Severity: "Low", MapIndex: -1}}},
+			{ID: "db", DisplayName: "db", Type: common.AssetTypeDatabase, Source: common.DataSourceDockerCompose},
+		},
+		DataFlows: []common.DataFlow{{ID: "1", Name: "sql", Source: "web", Target: "db", Protocol: "postgresql"}},
+		Extra:     map[string]any{},
+	}
+	entries := []string{}
+	require.NoError(t, r.Generate(targets, &model, Options{Changelog: recordingChangelog{entries: &entries}, Logger: slog.Default()}))
+
+	for _, target := range targets {
+		info, err := os.Stat(target.Path)
+		require.NoError(t, err, target.Format)
+		assert.NotZero(t, info.Size(), target.Format)
+	}
+	// all writers share the changelog
+	assert.Contains(t, entries, "Markdown report has been generated with 1 threats")
+	assert.Contains(t, entries, "SARIF log has been generated with 1 results for 1 rules")
+}
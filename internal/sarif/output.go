@@ -0,0 +1,214 @@
+package sarif
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+)
+
+// fingerprintKey is the key of the partial fingerprint that identifies a threat across runs
+const fingerprintKey = "threatcatThreat/v1"
+
+// SarifOutput writes the threats of a threat model as the results of a SARIF log, e.g. for code scanning dashboards
+type SarifOutput struct {
+	OutputPath string
+	cl         changelog
+	logger     *slog.Logger
+}
+
+type changelog interface {
+	AddEntry(string)
+}
+
+func NewSarifOutput(outputPath string, cl changelog, logger *slog.Logger) *SarifOutput {
+	return &SarifOutput{
+		OutputPath: outputPath,
+		cl:         cl,
+		logger:     logger.With("package", "sarif", "component", "SarifOutput"),
+	}
+}
+
+// Generate writes the SARIF log. Existing files are overwritten.
+func (so *SarifOutput) Generate(model *common.ThreatModel) error {
+	so.logger.Debug("Generating SARIF log")
+
+	log := so.buildLog(model)
+	content, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal SARIF log: %w", err)
+	}
+	err = os.MkdirAll(filepath.Dir(so.OutputPath), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(so.OutputPath, content, 0644)
+	if err != nil {
+		return err
+	}
+
+	so.cl.AddEntry(fmt.Sprintf("SARIF log has been generated with %d results for %d rules", len(log.Runs[0].Results), len(log.Runs[0].Tool.Driver.Rules)))
+	so.logger.Debug("SARIF log has been written to file", "filePath", so.OutputPath)
+	return nil
+}
+
+// buildLog creates a result for each threat of the assets and dataflows. Threats with the same title share a rule.
+func (so *SarifOutput) buildLog(model *common.ThreatModel) *Log {
+	run := Run{
+		Tool: Tool{Driver: ToolComponent{
+			Name:           "Threatcat",
+			InformationURI: "https://github.com/threatcat-dev/threatcat",
+			Rules:          []ReportingDescriptor{},
+		}},
+		Results: []Result{},
+	}
+	ruleIndex := make(map[string]int) // rule ID -> index of the rule
+
+	add := func(threat common.Threat, kind, element string, extra map[string]any) {
+		id := ruleID(threat.Title)
+		index, ok := ruleIndex[id]
+		if !ok {
+			index = len(run.Tool.Driver.Rules)
+			ruleIndex[id] = index
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule(id, threat))
+		}
+		run.Results = append(run.Results, threatResult(threat, index, id, kind, element, common.GetOr(extra, "InputFile", "")))
+	}
+	for _, asset := range model.Assets {
+		for _, threat := range asset.Threats {
+			add(threat, "asset", asset.DisplayName, asset.Extra)
+		}
+	}
+	for _, dataflow := range model.DataFlows {
+		for _, threat := range dataflow.Threats {
+			add(threat, "dataflow", dataflow.Name, dataflow.Extra)
+		}
+	}
+
+	return &Log{Schema: sarifSchema, Version: sarifVersion, Runs: []Run{run}}
+}
+
+// rule describes the kind of a threat by its title, description and mitigation
+func rule(id string, threat common.Threat) ReportingDescriptor {
+	descriptor := ReportingDescriptor{
+		ID:                   id,
+		Name:                 threat.Title,
+		ShortDescription:     &Message{Text: threat.Title},
+		DefaultConfiguration: &Configuration{Level: level(threat.Severity)},
+		Properties: map[string]any{
+			"tags":              tags(threat),
+			"security-severity": securitySeverity(threat.Severity),
+		},
+	}
+	if threat.Description != "" {
+		descriptor.FullDescription = &Message{Text: threat.Description}
+	}
+	if threat.Mitigation != "" {
+		descriptor.Help = &Message{Text: threat.Mitigation}
+	}
+	return descriptor
+}
+
+// threatResult reports a threat of an element. Mitigated and not applicable threats are suppressed.
+func threatResult(threat common.Threat, index int, id, kind, element, inputFile string) Result {
+	text := fmt.Sprintf("%s at %s '%s'", threat.Title, kind, element)
+	if threat.Description != "" {
+		text += ": " + threat.Description
+	}
+	location := Location{LogicalLocations: []LogicalLocation{{Name: element, FullyQualifiedName: kind + "/" + element, Kind: "resource"}}}
+	if inputFile != "" {
+		location.PhysicalLocation = &PhysicalLocation{ArtifactLocation: ArtifactLocation{URI: filepath.ToSlash(inputFile)}}
+	}
+	fingerprint := threat.ID
+	if fingerprint == "" {
+		fingerprint = threat.InternalID
+	}
+
+	result := Result{
+		RuleID:    id,
+		RuleIndex: index,
+		Level:     level(threat.Severity),
+		Message:   Message{Text: text},
+		Locations: []Location{location},
+		Properties: map[string]any{
+			"severity": threat.Severity,
+			"status":   common.StatusString(threat.Status),
+		},
+	}
+	if fingerprint != "" {
+		result.PartialFingerprints = map[string]string{fingerprintKey: fingerprint}
+	}
+	switch threat.Status {
+	case common.Mitigated:
+		justification := "Mitigated"
+		if threat.Mitigation != "" {
+			justification += ": " + threat.Mitigation
+		}
+		result.Suppressions = []Suppression{{Kind: "external", Status: "accepted", Justification: justification}}
+	case common.NotApplicable:
+		result.Suppressions = []Suppression{{Kind: "external", Status: "accepted", Justification: "Not applicable"}}
+	}
+	return result
+}
+
+// ruleID turns a threat title into a rule ID of lower case letters, digits and dashes
+func ruleID(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	if b.Len() == 0 {
+		return "threat"
+	}
+	return b.String()
+}
+
+func tags(threat common.Threat) []string {
+	result := []string{"security", "threat-model"}
+	if threat.Type != common.ThreatTypeUnknown {
+		result = append(result, common.TypeString(threat.Type))
+	}
+	return result
+}
+
+// level maps the severities of Threat Dragon to the levels of SARIF
+func level(severity string) string {
+	switch strings.ToLower(severity) {
+	case "high", "critical":
+		return "error"
+	case "low":
+		return "note"
+	}
+	return "warning"
+}
+
+// securitySeverity maps the severities of Threat Dragon to the scores that code scanning uses to rank results
+func securitySeverity(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return "9.0"
+	case "high":
+		return "7.0"
+	case "low":
+		return "2.0"
+	}
+	return "5.0"
+}
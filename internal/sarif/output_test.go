@@ -0,0 +1,111 @@
+package sarif
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// recordingChangelog keeps all entries for assertions
+type recordingChangelog struct {
+	entries *[]string
+}
+
+func (rc recordingChangelog) AddEntry(entry string) {
+	*rc.entries = append(*rc.entries, entry)
+}
+
+func testModel() *common.ThreatModel {
+	privileged := func(id string, status common.Status) common.Threat {
+		return common.Threat{ID: id, Title: "Privileged Container", Type: common.ElevationOfPrivilege, Severity: "High", Status: status,
+			Description: "The container runs privileged.", Mitigation: "Remove privileged: true"}
+	}
+	return &common.ThreatModel{
+		Assets: []common.Asset{
+			{ID: "web", DisplayName: "web", Threats: []common.Threat{privileged("t1", common.Open)},
+				Extra: map[string]any{"InputFile": "deploy/docker-compose.yml"}},
+			{ID: "worker", DisplayName: "worker", Threats: []common.Threat{privileged("t2", common.Mitigated)}},
+		},
+		DataFlows: []common.DataFlow{
+			{ID: "1", Name: "sql", Source: "web", Target: "db",
+				Threats: []common.Threat{{InternalID: "i3", Title: "Unencrypted Queries", Severity: "Low", Status: common.NotApplicable}}},
+		},
+	}
+}
+
+func generate(t *testing.T, path string) (Log, []string) {
+	t.Helper()
+	entries := []string{}
+	output := NewSarifOutput(path, recordingChangelog{entries: &entries}, slog.Default())
+	require.NoError(t, output.Generate(testModel()))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var log Log
+	require.NoError(t, json.Unmarshal(content, &log))
+	return log, entries
+}
+
+func TestGenerate(t *testing.T) {
+	const path = "testdata/testoutput_threats.sarif"
+	defer os.Remove(path)
+
+	log, entries := generate(t, path)
+	assert.Equal(t, "2.1.0", log.Version)
+	assert.Equal(t, sarifSchema, log.Schema)
+	require.Len(t, log.Runs, 1)
+	assert.Contains(t, entries, "SARIF log has been generated with 3 results for 2 rules")
+
+	// threats with the same title share a rule
+	rules := log.Runs[0].Tool.Driver.Rules
+	require.Len(t, rules, 2)
+	assert.Equal(t, "privileged-container", rules[0].ID)
+	assert.Equal(t, &Message{Text: "Remove privileged: true"}, rules[0].Help)
+	assert.Equal(t, &Configuration{Level: "error"}, rules[0].DefaultConfiguration)
+	assert.Equal(t, map[string]any{"tags": []any{"security", "threat-model", "Elevation of privilege"}, "security-severity": "7.0"}, rules[0].Properties)
+	assert.Equal(t, "unencrypted-queries", rules[1].ID)
+	assert.Nil(t, rules[1].Help)
+
+	results := log.Runs[0].Results
+	require.Len(t, results, 3)
+	assert.Equal(t, Result{
+		RuleID:    "privileged-container",
+		RuleIndex: 0,
+		Level:     "error",
+		Message:   Message{Text: "Privileged Container at asset 'web': The container runs privileged."},
+		Locations: []Location{{
+			PhysicalLocation: &PhysicalLocation{ArtifactLocation: ArtifactLocation{URI: "deploy/docker-compose.yml"}},
+			LogicalLocations: []LogicalLocation{{Name: "web", FullyQualifiedName: "asset/web", Kind: "resource"}},
+		}},
+		PartialFingerprints: map[string]string{fingerprintKey: "t1"},
+		Properties:          map[string]any{"severity": "High", "status": "Open"},
+	}, results[0])
+}
+
+func TestGenerate_Suppressions(t *testing.T) {
+	const path = "testdata/testoutput_suppressions.sarif"
+	defer os.Remove(path)
+
+	log, _ := generate(t, path)
+	results := log.Runs[0].Results
+
+	assert.Empty(t, results[0].Suppressions)
+	assert.Equal(t, []Suppression{{Kind: "external", Status: "accepted", Justification: "Mitigated: Remove privileged: true"}}, results[1].Suppressions)
+	assert.Nil(t, results[1].Locations[0].PhysicalLocation)
+
+	queries := results[2]
+	assert.Equal(t, []Suppression{{Kind: "external", Status: "accepted", Justification: "Not applicable"}}, queries.Suppressions)
+	assert.Equal(t, 1, queries.RuleIndex)
+	assert.Equal(t, "note", queries.Level)
+	assert.Equal(t, "dataflow/sql", queries.Locations[0].LogicalLocations[0].FullyQualifiedName)
+	assert.Equal(t, map[string]string{fingerprintKey: "i3"}, queries.PartialFingerprints)
+}
+
+func TestRuleID(t *testing.T) {
+	assert.Equal(t, "missing-order-audit-at-shop-backend", ruleID("Missing Order Audit at <Shop Backend>"))
+	assert.Equal(t, "threat", ruleID("---"))
+}
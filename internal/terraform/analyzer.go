@@ -0,0 +1,192 @@
+package terraform
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// maxGlueDepth limits how many resources that are neither assets nor boundaries are followed
+// when resolving the boundaries of a resource (e.g. instance -> network interface -> subnet)
+const maxGlueDepth = 2
+
+// TerraformAnalyzer analyzes the resources of a terraform module, plan or state.
+// The generated IDs depend on the resource addresses and the optional workspace name, but not on the input path,
+// so that the same resource gets the same ID from a module, a plan and a state.
+type TerraformAnalyzer struct {
+	Workspace string
+	logger    *slog.Logger
+}
+
+// NewTerraformAnalyzer creates a new instance of TerraformAnalyzer.
+// The workspace tells apart resources with the same address in different workspaces and can be empty.
+func NewTerraformAnalyzer(workspace string, logger *slog.Logger) *TerraformAnalyzer {
+	return &TerraformAnalyzer{
+		Workspace: workspace,
+		logger:    logger.With("package", "terraform", "component", "TerraformAnalyzer"),
+	}
+}
+
+// Analyze analyzes the given resources and returns a threat model.
+// Compute, database, load balancer and storage resources become assets, networks, subnets and
+// security groups become trust boundaries. Security group rules between groups become dataflows.
+func (a *TerraformAnalyzer) Analyze(resources []Resource) (*common.ThreatModel, error) {
+	if resources == nil {
+		return nil, fmt.Errorf("no resources to analyze were given")
+	}
+
+	model := common.EmptyThreatModel()
+	a.logger.Debug("Beginning terraform analysis", "resourceCount", len(resources))
+
+	byAddress := make(map[string]Resource, len(resources))
+	for _, resource := range resources {
+		byAddress[resource.Address] = resource
+	}
+
+	assetNames := uniqueDisplayNames(resources, func(r Resource) bool {
+		_, ok := assetResourceTypes[r.Type]
+		return ok
+	})
+	boundaryNames := uniqueDisplayNames(resources, func(r Resource) bool {
+		_, ok := boundaryResourceTypes[r.Type]
+		return ok
+	})
+
+	boundaryIndex := make(map[string]int)
+	for _, resource := range resources {
+		kind, ok := boundaryResourceTypes[resource.Type]
+		if !ok {
+			continue
+		}
+		boundaryIndex[resource.Address] = len(model.Boundaries)
+		model.Boundaries = append(model.Boundaries, common.TrustBoundary{
+			ID:              a.resourceID(resource.Address),
+			DisplayName:     boundaryNames[resource.Address],
+			ContainedAssets: []string{},
+			Source:          common.DataSourceTerraform,
+			Extra: map[string]any{
+				"initial-description": fmt.Sprintf("Terraform %s", kind),
+				"TerraformAddress":    resource.Address,
+				"TerraformType":       resource.Type,
+			},
+		})
+	}
+
+	members := make(map[string][]string) // boundary address -> asset addresses
+	for _, resource := range resources {
+		assetType, ok := assetResourceTypes[resource.Type]
+		if !ok {
+			a.logger.Debug("Resource type is not relevant for the analysis. Skipping.", "address", resource.Address)
+			continue
+		}
+
+		asset := common.Asset{
+			ID:          a.resourceID(resource.Address),
+			DisplayName: assetNames[resource.Address],
+			Type:        assetType,
+			Source:      common.DataSourceTerraform,
+			Extra: map[string]any{
+				"TerraformAddress": resource.Address,
+				"TerraformType":    resource.Type,
+			},
+		}
+		a.logger.Debug("Created a new instance of Asset for terraform resource", "address", resource.Address, "asset", asset)
+		model.Assets = append(model.Assets, asset)
+
+		for _, boundary := range a.boundariesOf(resource, byAddress) {
+			members[boundary] = append(members[boundary], resource.Address)
+			index := boundaryIndex[boundary]
+			model.Boundaries[index].ContainedAssets = append(model.Boundaries[index].ContainedAssets, asset.ID)
+		}
+	}
+
+	model.DataFlows = a.securityGroupDataFlows(resources, byAddress, members, assetNames)
+
+	a.logger.Debug("Terraform analysis finished", "assetCount", len(model.Assets), "boundaryCount", len(model.Boundaries), "dataflowCount", len(model.DataFlows))
+	return &model, nil
+}
+
+// resourceID generates a unique ID for a resource by hashing the workspace and the resource address
+func (a *TerraformAnalyzer) resourceID(address string) string {
+	return common.GenerateIDHash(a.idNamespace(), address)
+}
+
+// idNamespace returns the prefix of all generated IDs
+func (a *TerraformAnalyzer) idNamespace() string {
+	return "terraform:" + a.Workspace
+}
+
+// boundariesOf returns the addresses of all boundaries the resource is part of.
+// Boundaries are found through the references of the resource, also following references of
+// resources that are neither assets nor boundaries. The boundaries containing the found
+// boundaries (e.g. the VPC of a subnet) are included as well.
+func (a *TerraformAnalyzer) boundariesOf(resource Resource, byAddress map[string]Resource) []string {
+	_, isBoundary := boundaryResourceTypes[resource.Type]
+	kind := boundaryResourceTypes[resource.Type]
+
+	found := make([]string, 0)
+	visited := map[string]bool{resource.Address: true}
+	var follow func(r Resource, depth int)
+	follow = func(r Resource, depth int) {
+		for _, address := range references(r.Attributes) {
+			if visited[address] {
+				continue
+			}
+			visited[address] = true
+
+			referenced, ok := byAddress[address]
+			if !ok {
+				a.logger.Debug("Referenced resource is not part of the module", "address", r.Address, "reference", address)
+				continue
+			}
+			if refKind, ok := boundaryResourceTypes[referenced.Type]; ok {
+				// a boundary can only be part of a boundary of a wider kind
+				if !isBoundary || refKind < kind {
+					found = append(found, address)
+				}
+				continue
+			}
+			if _, ok := assetResourceTypes[referenced.Type]; !ok && depth < maxGlueDepth {
+				follow(referenced, depth+1)
+			}
+		}
+	}
+	follow(resource, 0)
+
+	// add the parents of the found boundaries
+	result := slices.Clone(found)
+	for _, address := range found {
+		for _, parent := range a.boundariesOf(byAddress[address], byAddress) {
+			if !slices.Contains(result, parent) {
+				result = append(result, parent)
+			}
+		}
+	}
+	return result
+}
+
+// uniqueDisplayNames returns the display names of the selected resources by address.
+// Resources whose display name is not unique are named by their address instead.
+func uniqueDisplayNames(resources []Resource, selected func(Resource) bool) map[string]string {
+	count := make(map[string]int)
+	for _, resource := range resources {
+		if selected(resource) {
+			count[resource.displayName()]++
+		}
+	}
+
+	names := make(map[string]string)
+	for _, resource := range resources {
+		if !selected(resource) {
+			continue
+		}
+		name := resource.displayName()
+		if count[name] > 1 {
+			name = resource.Address
+		}
+		names[resource.Address] = name
+	}
+	return names
+}
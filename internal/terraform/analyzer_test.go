@@ -0,0 +1,130 @@
+package terraform
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// TestAnalyzer tests the Analyze method of TerraformAnalyzer with the test module
+func TestAnalyzer(t *testing.T) {
+	const modulePath = "testdata/module"
+
+	resources, err := NewTerraformParser(modulePath, slog.Default()).Parse()
+	require.NoError(t, err)
+
+	model, err := NewTerraformAnalyzer("", slog.Default()).Analyze(resources)
+	require.NoError(t, err)
+
+	expectedAssets := []struct {
+		displayName string
+		assetType   common.AssetType
+	}{
+		{"frontend", common.AssetTypeWebserver},
+		{"app-server", common.AssetTypeApplication},
+		{"orders", common.AssetTypeDatabase},
+		{"cache", common.AssetTypeInfrastructure},
+		{"assets", common.AssetTypeDatabase},
+	}
+	require.Len(t, model.Assets, len(expectedAssets))
+	assetIDs := make(map[string]string)
+	for i, expected := range expectedAssets {
+		asset := model.Assets[i]
+		assert.Equal(t, expected.displayName, asset.DisplayName)
+		assert.Equal(t, expected.assetType, asset.Type)
+		assert.Equal(t, common.DataSourceTerraform, asset.Source)
+		assert.Equal(t, common.GenerateIDHash("terraform:", asset.Extra["TerraformAddress"].(string)), asset.ID)
+		assetIDs[asset.DisplayName] = asset.ID
+	}
+
+	expectedBoundaries := map[string][]string{
+		"main-vpc": {"frontend", "app-server", "orders", "cache"},
+		"public":   {"frontend"},
+		"private":  {"app-server", "orders"},
+		"lb":       {"frontend"},
+		"app":      {"app-server"},
+		"db":       {"orders", "cache"},
+	}
+	require.Len(t, model.Boundaries, len(expectedBoundaries))
+	for _, boundary := range model.Boundaries {
+		expected, ok := expectedBoundaries[boundary.DisplayName]
+		require.True(t, ok, "unexpected boundary %s", boundary.DisplayName)
+		expectedIDs := make([]string, 0, len(expected))
+		for _, name := range expected {
+			expectedIDs = append(expectedIDs, assetIDs[name])
+		}
+		assert.ElementsMatch(t, expectedIDs, boundary.ContainedAssets, "boundary %s", boundary.DisplayName)
+		assert.Equal(t, common.DataSourceTerraform, boundary.Source)
+	}
+
+	expectedFlows := []struct {
+		name, protocol, source, target string
+		encrypted                      bool
+	}{
+		{"http (8080)", "http", "frontend", "app-server", false},
+		{"postgresql (5432)", "postgresql", "app-server", "orders", false},
+		{"postgresql (5432)", "postgresql", "app-server", "cache", false},
+		{"redis (6379)", "redis", "app-server", "orders", false},
+		{"redis (6379)", "redis", "app-server", "cache", false},
+	}
+	require.Len(t, model.DataFlows, len(expectedFlows))
+	for i, expected := range expectedFlows {
+		flow := model.DataFlows[i]
+		assert.Equal(t, expected.name, flow.Name)
+		assert.Equal(t, expected.protocol, flow.Protocol)
+		assert.Equal(t, expected.source, flow.Source)
+		assert.Equal(t, expected.target, flow.Target)
+		assert.Equal(t, expected.encrypted, flow.Encrypted)
+		assert.Len(t, flow.ID, common.MaxIDHashLength)
+	}
+}
+
+// TestAnalyzerDuplicateNames ensures that resources with the same display name are named by their address
+func TestAnalyzerDuplicateNames(t *testing.T) {
+	resources := []Resource{
+		{Address: "aws_instance.web", Type: "aws_instance", Name: "web"},
+		{Address: "aws_lambda_function.web", Type: "aws_lambda_function", Name: "web"},
+		{Address: "aws_db_instance.db", Type: "aws_db_instance", Name: "db"},
+	}
+
+	model, err := NewTerraformAnalyzer("", slog.Default()).Analyze(resources)
+	require.NoError(t, err)
+	require.Len(t, model.Assets, 3)
+	assert.Equal(t, "aws_instance.web", model.Assets[0].DisplayName)
+	assert.Equal(t, "aws_lambda_function.web", model.Assets[1].DisplayName)
+	assert.Equal(t, "db", model.Assets[2].DisplayName)
+	assert.NotEqual(t, model.Assets[0].ID, model.Assets[1].ID)
+}
+
+// TestAnalyzerAllTraffic ensures that rules allowing all protocols are named accordingly
+func TestAnalyzerAllTraffic(t *testing.T) {
+	resources := []Resource{
+		{Address: "aws_security_group.a", Type: "aws_security_group", Name: "a", Attributes: map[string]any{
+			"egress": []any{map[string]any{"from_port": 0.0, "to_port": 0.0, "protocol": "-1", "self": true}},
+		}},
+		{Address: "aws_instance.one", Type: "aws_instance", Name: "one", Attributes: map[string]any{
+			"vpc_security_group_ids": []any{Reference{Address: "aws_security_group.a"}},
+		}},
+		{Address: "aws_instance.two", Type: "aws_instance", Name: "two", Attributes: map[string]any{
+			"vpc_security_group_ids": []any{Reference{Address: "aws_security_group.a"}},
+		}},
+	}
+
+	model, err := NewTerraformAnalyzer("", slog.Default()).Analyze(resources)
+	require.NoError(t, err)
+	require.Len(t, model.DataFlows, 2)
+	assert.Equal(t, "all traffic", model.DataFlows[0].Name)
+	assert.Equal(t, "one", model.DataFlows[0].Source)
+	assert.Equal(t, "two", model.DataFlows[0].Target)
+	assert.Equal(t, "two", model.DataFlows[1].Source)
+	assert.Equal(t, "one", model.DataFlows[1].Target)
+}
+
+// TestAnalyzerNoResources ensures that missing input is reported
+func TestAnalyzerNoResources(t *testing.T) {
+	_, err := NewTerraformAnalyzer("", slog.Default()).Analyze(nil)
+	assert.Error(t, err)
+}
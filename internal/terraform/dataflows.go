@@ -0,0 +1,180 @@
+package terraform
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// securityGroupRule is a single rule of a security group, independent of how it was declared
+type securityGroupRule struct {
+	owner    string // address of the security group the rule belongs to
+	peers    []string
+	ingress  bool
+	fromPort int
+	toPort   int
+	protocol string
+}
+
+// wellKnownPorts maps ports to the application protocol that is usually spoken on them
+var wellKnownPorts = map[int]string{
+	22:    "ssh",
+	80:    "http",
+	443:   "https",
+	1433:  "mssql",
+	2049:  "nfs",
+	3306:  "mysql",
+	5432:  "postgresql",
+	5672:  "amqp",
+	6379:  "redis",
+	8080:  "http",
+	8443:  "https",
+	9092:  "kafka",
+	27017: "mongodb",
+}
+
+// encryptedProtocols lists the protocols of wellKnownPorts that are encrypted
+var encryptedProtocols = []string{"https", "ssh"}
+
+// securityGroupDataFlows creates dataflows between the assets of security groups that are connected by rules.
+// An ingress rule creates flows from the assets of the peer group to the assets of the owning group,
+// an egress rule creates flows in the other direction. Rules that only allow CIDR ranges are skipped.
+func (a *TerraformAnalyzer) securityGroupDataFlows(resources []Resource, byAddress map[string]Resource, members map[string][]string, assetNames map[string]string) []common.DataFlow {
+	flows := make([]common.DataFlow, 0)
+	for _, rule := range a.securityGroupRules(resources) {
+		if len(rule.peers) == 0 {
+			a.logger.Debug("Security group rule does not reference other security groups. Skipping.", "securityGroup", rule.owner)
+			continue
+		}
+
+		for _, peer := range rule.peers {
+			if _, ok := byAddress[peer]; !ok {
+				a.logger.Debug("Security group rule references a security group that is not part of the module", "securityGroup", rule.owner, "peer", peer)
+				continue
+			}
+
+			sources, targets := members[peer], members[rule.owner]
+			if !rule.ingress {
+				sources, targets = targets, sources
+			}
+			for _, source := range sources {
+				for _, target := range targets {
+					if source == target {
+						continue
+					}
+					flow := a.ruleDataFlow(rule, assetNames[source], assetNames[target], source, target)
+					if !slices.ContainsFunc(flows, func(f common.DataFlow) bool { return f.ID == flow.ID }) {
+						flows = append(flows, flow)
+					}
+				}
+			}
+		}
+	}
+	return flows
+}
+
+// ruleDataFlow creates the dataflow between two assets that is allowed by a security group rule
+func (a *TerraformAnalyzer) ruleDataFlow(rule securityGroupRule, sourceName, targetName, sourceAddress, targetAddress string) common.DataFlow {
+	ports := fmt.Sprintf("%d", rule.fromPort)
+	if rule.toPort != rule.fromPort {
+		ports = fmt.Sprintf("%d-%d", rule.fromPort, rule.toPort)
+	}
+
+	protocol := rule.protocol
+	if known, ok := wellKnownPorts[rule.fromPort]; ok && rule.fromPort == rule.toPort {
+		protocol = known
+	}
+	name := fmt.Sprintf("%s (%s)", protocol, ports)
+	if rule.protocol == "all" {
+		name = "all traffic"
+	}
+
+	return common.DataFlow{
+		ID:            common.GenerateIDHash(a.idNamespace(), fmt.Sprintf("flow:%s->%s:%s", sourceAddress, targetAddress, ports)),
+		Name:          name,
+		Protocol:      protocol,
+		Encrypted:     slices.Contains(encryptedProtocols, protocol),
+		PublicNetwork: false,
+		Source:        sourceName,
+		Target:        targetName,
+		DataSource:    common.DataSourceTerraform,
+	}
+}
+
+// securityGroupRules collects the rules of all security groups. Rules can be declared as inline
+// ingress and egress blocks, as aws_security_group_rule or as aws_vpc_security_group_*_rule.
+func (a *TerraformAnalyzer) securityGroupRules(resources []Resource) []securityGroupRule {
+	rules := make([]securityGroupRule, 0)
+	for _, resource := range resources {
+		switch resource.Type {
+		case "aws_security_group":
+			for _, direction := range []string{"ingress", "egress"} {
+				blocks, _ := resource.Attributes[direction].([]any)
+				for _, block := range blocks {
+					attributes, ok := block.(map[string]any)
+					if !ok {
+						continue
+					}
+					rule := newSecurityGroupRule(resource.Address, direction == "ingress", attributes, "protocol")
+					rule.peers = references(attributes["security_groups"])
+					if self, _ := attributes["self"].(bool); self {
+						rule.peers = append(rule.peers, resource.Address)
+					}
+					rules = append(rules, rule)
+				}
+			}
+		case "aws_security_group_rule":
+			owner, ok := ruleOwner(resource)
+			if !ok {
+				a.logger.Debug("Security group rule does not reference its security group. Skipping.", "address", resource.Address)
+				continue
+			}
+			typ, _ := resource.Attributes["type"].(string)
+			rule := newSecurityGroupRule(owner, typ == "ingress", resource.Attributes, "protocol")
+			rule.peers = references(resource.Attributes["source_security_group_id"])
+			if self, _ := resource.Attributes["self"].(bool); self {
+				rule.peers = append(rule.peers, owner)
+			}
+			rules = append(rules, rule)
+		case "aws_vpc_security_group_ingress_rule", "aws_vpc_security_group_egress_rule":
+			owner, ok := ruleOwner(resource)
+			if !ok {
+				a.logger.Debug("Security group rule does not reference its security group. Skipping.", "address", resource.Address)
+				continue
+			}
+			rule := newSecurityGroupRule(owner, resource.Type == "aws_vpc_security_group_ingress_rule", resource.Attributes, "ip_protocol")
+			rule.peers = references(resource.Attributes["referenced_security_group_id"])
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// ruleOwner returns the address of the security group a standalone rule belongs to
+func ruleOwner(resource Resource) (string, bool) {
+	owners := references(resource.Attributes["security_group_id"])
+	if len(owners) != 1 {
+		return "", false
+	}
+	return owners[0], true
+}
+
+// newSecurityGroupRule reads the ports and protocol of a rule. The protocol "-1" allows all traffic.
+func newSecurityGroupRule(owner string, ingress bool, attributes map[string]any, protocolKey string) securityGroupRule {
+	rule := securityGroupRule{owner: owner, ingress: ingress, protocol: "tcp"}
+	if from, ok := attributes["from_port"].(float64); ok {
+		rule.fromPort = int(from)
+	}
+	rule.toPort = rule.fromPort
+	if to, ok := attributes["to_port"].(float64); ok {
+		rule.toPort = int(to)
+	}
+	if protocol, ok := attributes[protocolKey].(string); ok && protocol != "" {
+		rule.protocol = protocol
+	}
+	if rule.protocol == "-1" || rule.protocol == "all" {
+		rule.protocol = "all"
+	}
+	return rule
+}
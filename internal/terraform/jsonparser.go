@@ -0,0 +1,337 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// TerraformJSONParser parses the JSON output of 'terraform show -json' for plans and state files
+type TerraformJSONParser struct {
+	filePath string
+	logger   *slog.Logger
+}
+
+// NewTerraformJSONParser creates a new instance of TerraformJSONParser
+func NewTerraformJSONParser(filePath string, logger *slog.Logger) *TerraformJSONParser {
+	return &TerraformJSONParser{
+		filePath: filePath,
+		logger:   logger.With("package", "terraform", "component", "TerraformJSONParser"),
+	}
+}
+
+// jsonOutput holds the parts of the 'terraform show -json' output that are relevant for the analysis.
+// Plans contain resource_changes and configuration, state files contain values.
+type jsonOutput struct {
+	FormatVersion   string            `json:"format_version"`
+	Values          *jsonValues       `json:"values"`
+	ResourceChanges []jsonChange      `json:"resource_changes"`
+	Configuration   jsonConfiguration `json:"configuration"`
+}
+
+type jsonValues struct {
+	RootModule jsonModule `json:"root_module"`
+}
+
+type jsonModule struct {
+	Resources    []jsonResource `json:"resources"`
+	ChildModules []jsonModule   `json:"child_modules"`
+}
+
+type jsonResource struct {
+	Address string         `json:"address"`
+	Mode    string         `json:"mode"`
+	Type    string         `json:"type"`
+	Name    string         `json:"name"`
+	Values  map[string]any `json:"values"`
+}
+
+type jsonChange struct {
+	Address       string `json:"address"`
+	ModuleAddress string `json:"module_address"`
+	Mode          string `json:"mode"`
+	Type          string `json:"type"`
+	Name          string `json:"name"`
+	Change        struct {
+		Actions []string       `json:"actions"`
+		Before  map[string]any `json:"before"`
+		After   map[string]any `json:"after"`
+	} `json:"change"`
+}
+
+type jsonConfiguration struct {
+	RootModule jsonConfigModule `json:"root_module"`
+}
+
+type jsonConfigModule struct {
+	Resources   []jsonConfigResource      `json:"resources"`
+	ModuleCalls map[string]jsonModuleCall `json:"module_calls"`
+}
+
+type jsonModuleCall struct {
+	Module jsonConfigModule `json:"module"`
+}
+
+type jsonConfigResource struct {
+	Address     string         `json:"address"`
+	Mode        string         `json:"mode"`
+	Expressions map[string]any `json:"expressions"`
+}
+
+// moduleIndexPattern matches the instance keys of module addresses, e.g. '["a"]' in 'module.net["a"]'
+var moduleIndexPattern = regexp.MustCompile(`\[[^\]]*\]`)
+
+// Parse reads the JSON file and returns the managed resources with resolved module and instance addresses.
+// Values that hold the id or arn of another resource are turned into a Reference. For plans, values
+// that are only known after apply are resolved through the references of the configuration.
+func (jp *TerraformJSONParser) Parse() ([]Resource, error) {
+	jp.logger.Debug("Opening terraform JSON file for parsing", "filePath", jp.filePath)
+	content, err := os.ReadFile(jp.filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var output jsonOutput
+	if err := json.Unmarshal(content, &output); err != nil {
+		return nil, fmt.Errorf("failed to decode terraform JSON file: %w", err)
+	}
+	if output.FormatVersion == "" {
+		return nil, fmt.Errorf("file is not the JSON output of 'terraform show -json'")
+	}
+
+	var resources []Resource
+	if output.ResourceChanges != nil {
+		jp.logger.Debug("Reading resource changes of terraform plan", "changeCount", len(output.ResourceChanges))
+		resources = jp.planResources(output)
+	} else {
+		jp.logger.Debug("Reading values of terraform state")
+		resources = jp.stateResources(output)
+	}
+
+	linkResourceIDs(resources)
+	jp.logger.Info("Successfully parsed terraform JSON file", "resources", len(resources))
+	return resources, nil
+}
+
+// stateResources collects the managed resources of all modules of a state file
+func (jp *TerraformJSONParser) stateResources(output jsonOutput) []Resource {
+	resources := make([]Resource, 0)
+	if output.Values == nil {
+		return resources
+	}
+
+	var collect func(module jsonModule)
+	collect = func(module jsonModule) {
+		for _, resource := range module.Resources {
+			if resource.Mode != "managed" {
+				continue
+			}
+			resources = append(resources, Resource{
+				Address:    resource.Address,
+				Type:       resource.Type,
+				Name:       resource.Name,
+				Attributes: nonNilAttributes(resource.Values),
+			})
+		}
+		for _, child := range module.ChildModules {
+			collect(child)
+		}
+	}
+	collect(output.Values.RootModule)
+	return resources
+}
+
+// planResources collects the managed resources of a plan that still exist after apply.
+// References of the configuration are added for values that are unknown until apply.
+func (jp *TerraformJSONParser) planResources(output jsonOutput) []Resource {
+	addresses := make([]string, 0, len(output.ResourceChanges))
+	for _, change := range output.ResourceChanges {
+		addresses = append(addresses, change.Address)
+	}
+
+	resources := make([]Resource, 0, len(output.ResourceChanges))
+	for _, change := range output.ResourceChanges {
+		if change.Mode != "managed" {
+			continue
+		}
+		if slices.Equal(change.Change.Actions, []string{"delete"}) {
+			jp.logger.Debug("Resource is deleted by the plan. Skipping.", "address", change.Address)
+			continue
+		}
+
+		attributes := nonNilAttributes(change.Change.After)
+		if config, ok := configResource(output.Configuration.RootModule, change.ModuleAddress, change.Type+"."+change.Name); ok {
+			resolve := func(reference string) []string {
+				return resolveReference(reference, change.ModuleAddress, addresses)
+			}
+			addConfigReferences(attributes, config.Expressions, resolve)
+		} else {
+			jp.logger.Debug("Resource is not part of the configuration", "address", change.Address)
+		}
+
+		resources = append(resources, Resource{
+			Address:    change.Address,
+			Type:       change.Type,
+			Name:       change.Name,
+			Attributes: attributes,
+		})
+	}
+	return resources
+}
+
+// configResource finds the configuration of a resource in the module with the given address
+func configResource(root jsonConfigModule, moduleAddress, resourceAddress string) (jsonConfigResource, bool) {
+	module := root
+	if moduleAddress != "" {
+		parts := strings.Split(moduleIndexPattern.ReplaceAllString(moduleAddress, ""), ".")
+		for i := 1; i < len(parts); i += 2 {
+			call, ok := module.ModuleCalls[parts[i]]
+			if !ok {
+				return jsonConfigResource{}, false
+			}
+			module = call.Module
+		}
+	}
+
+	index := slices.IndexFunc(module.Resources, func(r jsonConfigResource) bool {
+		return r.Address == resourceAddress && r.Mode == "managed"
+	})
+	if index < 0 {
+		return jsonConfigResource{}, false
+	}
+	return module.Resources[index], true
+}
+
+// addConfigReferences adds the references (or constant values) of the configuration expressions to
+// all attributes whose value is unknown. Nested blocks are matched by their position.
+func addConfigReferences(attributes map[string]any, expressions map[string]any, resolve func(string) []string) {
+	for key, expression := range expressions {
+		switch expr := expression.(type) {
+		case map[string]any:
+			if _, known := attributes[key]; known {
+				continue
+			}
+			if constant, ok := expr["constant_value"]; ok && constant != nil {
+				attributes[key] = constant
+				continue
+			}
+			references, _ := expr["references"].([]any)
+			refs := make([]any, 0)
+			for _, reference := range references {
+				name, _ := reference.(string)
+				for _, address := range resolve(name) {
+					if !slices.Contains(refs, any(Reference{Address: address})) {
+						refs = append(refs, Reference{Address: address})
+					}
+				}
+			}
+			if len(refs) > 0 {
+				attributes[key] = refs
+			}
+		case []any:
+			blocks, known := attributes[key].([]any)
+			if !known {
+				// the whole list of blocks is unknown, it is rebuilt from the configuration
+				blocks = make([]any, 0, len(expr))
+				for range expr {
+					blocks = append(blocks, map[string]any{})
+				}
+				attributes[key] = blocks
+			}
+			for i, blockExpr := range expr {
+				blockExpressions, ok := blockExpr.(map[string]any)
+				if !ok || i >= len(blocks) {
+					continue
+				}
+				if block, ok := blocks[i].(map[string]any); ok {
+					addConfigReferences(block, blockExpressions, resolve)
+				}
+			}
+		}
+	}
+}
+
+// resolveReference returns the addresses of the resource instances a configuration reference
+// (e.g. 'aws_vpc.main.id') refers to. Only resources of the same module can be resolved.
+func resolveReference(reference, moduleAddress string, addresses []string) []string {
+	parts := strings.Split(reference, ".")
+	if len(parts) < 2 || !isResourceAddress(parts[0]) {
+		return nil
+	}
+
+	address := parts[0] + "." + parts[1]
+	if moduleAddress != "" {
+		address = moduleAddress + "." + address
+	}
+	if slices.Contains(addresses, address) {
+		return []string{address}
+	}
+
+	// references without index refer to all instances created by count or for_each
+	instances := make([]string, 0)
+	for _, candidate := range addresses {
+		if strings.HasPrefix(candidate, address+"[") {
+			instances = append(instances, candidate)
+		}
+	}
+	return instances
+}
+
+// unlinkedAttributes are never turned into a Reference. Besides the own identifiers of a resource,
+// these are descriptive values that may accidentally equal the id of another resource (e.g. a name).
+var unlinkedAttributes = []string{"id", "arn", "name", "description", "tags", "tags_all"}
+
+// linkResourceIDs replaces string values that hold the id or arn of another resource by a Reference
+func linkResourceIDs(resources []Resource) {
+	byID := make(map[string]string)
+	for _, resource := range resources {
+		for _, key := range []string{"id", "arn"} {
+			if id, ok := resource.Attributes[key].(string); ok && id != "" {
+				byID[id] = resource.Address
+			}
+		}
+	}
+
+	var link func(value any, self string) any
+	link = func(value any, self string) any {
+		switch v := value.(type) {
+		case string:
+			if address, ok := byID[v]; ok && address != self {
+				return Reference{Address: address}
+			}
+		case []any:
+			for i, item := range v {
+				v[i] = link(item, self)
+			}
+		case map[string]any:
+			for key, item := range v {
+				v[key] = link(item, self)
+			}
+		}
+		return value
+	}
+
+	for _, resource := range resources {
+		for key, value := range resource.Attributes {
+			if slices.Contains(unlinkedAttributes, key) {
+				continue
+			}
+			resource.Attributes[key] = link(value, resource.Address)
+		}
+	}
+}
+
+// nonNilAttributes returns the attributes without null values, as null is the same as a missing attribute
+func nonNilAttributes(values map[string]any) map[string]any {
+	attributes := make(map[string]any, len(values))
+	for key, value := range values {
+		if value != nil {
+			attributes[key] = value
+		}
+	}
+	return attributes
+}
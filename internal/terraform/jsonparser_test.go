@@ -0,0 +1,171 @@
+package terraform
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+func resourceByAddress(t *testing.T, resources []Resource, address string) Resource {
+	t.Helper()
+	for _, resource := range resources {
+		if resource.Address == address {
+			return resource
+		}
+	}
+	require.Failf(t, "resource not found", "address %s", address)
+	return Resource{}
+}
+
+// Confirm that the resource changes of a plan are read and unknown values are resolved through the configuration
+func TestJSONParser_ParsePlan(t *testing.T) {
+	resources, err := NewTerraformJSONParser("testdata/json/plan.json", slog.Default()).Parse()
+	require.NoError(t, err)
+
+	// deleted resources and data sources are skipped
+	require.Len(t, resources, 7)
+
+	subnet := resourceByAddress(t, resources, "module.net.aws_subnet.private")
+	assert.Equal(t, "aws_subnet", subnet.Type)
+	assert.Equal(t, "private", subnet.Name)
+	assert.Equal(t, []any{Reference{Address: "module.net.aws_vpc.main"}}, subnet.Attributes["vpc_id"])
+
+	// references to module outputs and data sources can not be resolved
+	instance := resourceByAddress(t, resources, "aws_instance.app[1]")
+	assert.NotContains(t, instance.Attributes, "subnet_id")
+	assert.Equal(t, "ami-123456", instance.Attributes["ami"])
+	assert.Equal(t, []any{Reference{Address: "aws_security_group.app"}}, instance.Attributes["vpc_security_group_ids"])
+
+	// unknown nested blocks are rebuilt from the configuration
+	db := resourceByAddress(t, resources, "aws_security_group.db")
+	assert.Equal(t, []any{map[string]any{
+		"from_port":       5432.0,
+		"to_port":         5432.0,
+		"protocol":        "tcp",
+		"security_groups": []any{Reference{Address: "aws_security_group.app"}},
+	}}, db.Attributes["ingress"])
+}
+
+// Confirm that the resources of all modules of a state file are read and ids are linked
+func TestJSONParser_ParseState(t *testing.T) {
+	resources, err := NewTerraformJSONParser("testdata/json/state.json", slog.Default()).Parse()
+	require.NoError(t, err)
+	require.Len(t, resources, 6)
+
+	instance := resourceByAddress(t, resources, "module.compute.aws_instance.web")
+	assert.Equal(t, Reference{Address: "module.compute.aws_subnet.apps"}, instance.Attributes["subnet_id"])
+	assert.Equal(t, []any{Reference{Address: "aws_security_group.web"}}, instance.Attributes["vpc_security_group_ids"])
+	assert.Equal(t, "i-0d1", instance.Attributes["id"])
+
+	// names are never linked, even if they equal the id of another resource
+	cache := resourceByAddress(t, resources, "aws_security_group.cache")
+	assert.Equal(t, "sg-0b1", cache.Attributes["name"])
+}
+
+// Confirm that files that are not the output of 'terraform show -json' are rejected
+func TestJSONParser_InvalidFileWillFail(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "other.json")
+	require.NoError(t, os.WriteFile(fpath, []byte(`{"resources": []}`), 0600))
+
+	_, err := NewTerraformJSONParser(fpath, slog.Default()).Parse()
+	assert.Error(t, err)
+
+	_, err = NewTerraformJSONParser("testdata/json/does-not-exist.json", slog.Default()).Parse()
+	assert.Error(t, err)
+}
+
+// TestAnalyzerPlan tests the analysis of a plan with modules and count
+func TestAnalyzerPlan(t *testing.T) {
+	const planPath = "testdata/json/plan.json"
+
+	resources, err := NewTerraformJSONParser(planPath, slog.Default()).Parse()
+	require.NoError(t, err)
+	model, err := NewTerraformAnalyzer("", slog.Default()).Analyze(resources)
+	require.NoError(t, err)
+
+	require.Len(t, model.Assets, 3)
+	assert.Equal(t, "aws_instance.app[0]", model.Assets[0].DisplayName)
+	assert.Equal(t, "aws_instance.app[1]", model.Assets[1].DisplayName)
+	assert.Equal(t, "orders", model.Assets[2].DisplayName)
+	assert.Equal(t, common.GenerateIDHash("terraform:", "aws_instance.app[0]"), model.Assets[0].ID)
+
+	require.Len(t, model.Boundaries, 4)
+	assert.Equal(t, "main-vpc", model.Boundaries[0].DisplayName)
+	assert.Equal(t, "private", model.Boundaries[1].DisplayName)
+	assert.Equal(t, []string{model.Assets[0].ID, model.Assets[1].ID}, model.Boundaries[2].ContainedAssets)
+	assert.Equal(t, []string{model.Assets[2].ID}, model.Boundaries[3].ContainedAssets)
+
+	require.Len(t, model.DataFlows, 2)
+	for i, flow := range model.DataFlows {
+		assert.Equal(t, "postgresql (5432)", flow.Name)
+		assert.Equal(t, model.Assets[i].DisplayName, flow.Source)
+		assert.Equal(t, "orders", flow.Target)
+	}
+}
+
+// TestAnalyzerState tests the analysis of a state file with linked ids
+func TestAnalyzerState(t *testing.T) {
+	const statePath = "testdata/json/state.json"
+
+	resources, err := NewTerraformJSONParser(statePath, slog.Default()).Parse()
+	require.NoError(t, err)
+	model, err := NewTerraformAnalyzer("", slog.Default()).Analyze(resources)
+	require.NoError(t, err)
+
+	require.Len(t, model.Assets, 2)
+	web, sessions := model.Assets[0], model.Assets[1]
+	assert.Equal(t, "web", web.DisplayName)
+	assert.Equal(t, "sessions", sessions.DisplayName)
+
+	expectedBoundaries := map[string][]string{
+		"main-vpc": {web.ID, sessions.ID},
+		"web":      {web.ID},
+		"cache":    {sessions.ID},
+		"apps":     {web.ID},
+	}
+	require.Len(t, model.Boundaries, len(expectedBoundaries))
+	for _, boundary := range model.Boundaries {
+		assert.ElementsMatch(t, expectedBoundaries[boundary.DisplayName], boundary.ContainedAssets, "boundary %s", boundary.DisplayName)
+	}
+
+	require.Len(t, model.DataFlows, 1)
+	assert.Equal(t, "redis (6379)", model.DataFlows[0].Name)
+	assert.Equal(t, "web", model.DataFlows[0].Source)
+	assert.Equal(t, "sessions", model.DataFlows[0].Target)
+}
+
+// TestAnalyzerIDsDoNotDependOnTheInputPath tests that a resource gets the same ID from every input it is read from
+func TestAnalyzerIDsDoNotDependOnTheInputPath(t *testing.T) {
+	data, err := os.ReadFile("testdata/json/plan.json")
+	require.NoError(t, err)
+	copyPath := filepath.Join(t.TempDir(), "terraform.tfstate.json")
+	require.NoError(t, os.WriteFile(copyPath, data, 0600))
+
+	ids := func(path, workspace string) []string {
+		resources, err := NewTerraformJSONParser(path, slog.Default()).Parse()
+		require.NoError(t, err)
+		model, err := NewTerraformAnalyzer(workspace, slog.Default()).Analyze(resources)
+		require.NoError(t, err)
+
+		ids := make([]string, 0, len(model.Assets)+len(model.Boundaries)+len(model.DataFlows))
+		for _, asset := range model.Assets {
+			ids = append(ids, asset.ID)
+		}
+		for _, boundary := range model.Boundaries {
+			ids = append(ids, boundary.ID)
+		}
+		for _, flow := range model.DataFlows {
+			ids = append(ids, flow.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, ids("testdata/json/plan.json", ""), ids(copyPath, ""))
+	assert.Equal(t, ids("testdata/json/plan.json", "prod"), ids(copyPath, "prod"))
+	assert.NotEqual(t, ids("testdata/json/plan.json", "prod"), ids("testdata/json/plan.json", "staging"))
+}
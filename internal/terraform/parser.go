@@ -0,0 +1,212 @@
+package terraform
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// TerraformParser parses the terraform files (*.tf) of a module directory
+type TerraformParser struct {
+	dirPath string
+	logger  *slog.Logger
+}
+
+// NewTerraformParser creates a new instance of TerraformParser
+func NewTerraformParser(dirPath string, logger *slog.Logger) *TerraformParser {
+	return &TerraformParser{
+		dirPath: dirPath,
+		logger:  logger.With("package", "terraform", "component", "TerraformParser"),
+	}
+}
+
+// Parse reads all terraform files of the module directory and returns the declared resources.
+// Sub directories are not parsed, as they contain separate modules.
+// Expressions are evaluated statically: literals are kept, references to other resources become
+// a Reference and everything that depends on variables, locals or functions is dropped.
+func (tp *TerraformParser) Parse() ([]Resource, error) {
+	tp.logger.Debug("Reading terraform module directory", "dirPath", tp.dirPath)
+	entries, err := os.ReadDir(tp.dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := hclparse.NewParser()
+	resources := make([]Resource, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".tf" {
+			continue
+		}
+
+		path := filepath.Join(tp.dirPath, entry.Name())
+		file, diags := parser.ParseHCLFile(path)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to parse terraform file %s: %w", path, diags)
+		}
+
+		fileResources, err := tp.fileResources(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read terraform file %s: %w", path, err)
+		}
+		tp.logger.Debug("Parsed terraform file", "filePath", path, "resourceCount", len(fileResources))
+		resources = append(resources, fileResources...)
+	}
+
+	tp.logger.Info("Successfully parsed terraform module", "resources", len(resources))
+	return resources, nil
+}
+
+// fileResources collects all resource blocks of a parsed file
+func (tp *TerraformParser) fileResources(file *hcl.File) ([]Resource, error) {
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("unexpected body type %T", file.Body)
+	}
+
+	resources := make([]Resource, 0)
+	for _, block := range body.Blocks {
+		if block.Type != "resource" {
+			continue
+		}
+		if len(block.Labels) != 2 {
+			return nil, fmt.Errorf("resource block at %s needs a type and a name label", block.DefRange())
+		}
+
+		resources = append(resources, Resource{
+			Address:    block.Labels[0] + "." + block.Labels[1],
+			Type:       block.Labels[0],
+			Name:       block.Labels[1],
+			Attributes: bodyAttributes(block.Body),
+		})
+	}
+	return resources, nil
+}
+
+// bodyAttributes converts the attributes and nested blocks of a body into plain go values.
+// Nested blocks of the same type are collected in a list, as the plan JSON representation does.
+func bodyAttributes(body *hclsyntax.Body) map[string]any {
+	attributes := make(map[string]any)
+	for name, attribute := range body.Attributes {
+		if value := evalExpr(attribute.Expr); value != nil {
+			attributes[name] = value
+		}
+	}
+	for _, block := range body.Blocks {
+		blocks, _ := attributes[block.Type].([]any)
+		attributes[block.Type] = append(blocks, bodyAttributes(block.Body))
+	}
+	return attributes
+}
+
+// evalExpr evaluates an expression without an evaluation context.
+// It returns nil if the value can not be determined statically and does not contain references.
+func evalExpr(expr hclsyntax.Expression) any {
+	switch e := expr.(type) {
+	case *hclsyntax.TupleConsExpr:
+		items := make([]any, 0, len(e.Exprs))
+		for _, itemExpr := range e.Exprs {
+			if item := evalExpr(itemExpr); item != nil {
+				items = append(items, item)
+			}
+		}
+		return items
+	case *hclsyntax.ObjectConsExpr:
+		object := make(map[string]any)
+		for _, item := range e.Items {
+			key, diags := item.KeyExpr.Value(nil)
+			if diags.HasErrors() || !key.IsKnown() || key.IsNull() || key.Type() != cty.String {
+				continue
+			}
+			if value := evalExpr(item.ValueExpr); value != nil {
+				object[key.AsString()] = value
+			}
+		}
+		return object
+	case *hclsyntax.TemplateWrapExpr:
+		return evalExpr(e.Wrapped)
+	case *hclsyntax.ScopeTraversalExpr:
+		if address, ok := resourceAddress(e.Traversal); ok {
+			return Reference{Address: address}
+		}
+		return nil
+	}
+
+	value, diags := expr.Value(nil)
+	if !diags.HasErrors() {
+		return ctyToGo(value)
+	}
+
+	// the value depends on variables or functions, only the referenced resources are kept
+	addresses := make([]string, 0)
+	for _, traversal := range expr.Variables() {
+		if address, ok := resourceAddress(traversal); ok {
+			addresses = append(addresses, address)
+		}
+	}
+	slices.Sort(addresses)
+	addresses = slices.Compact(addresses)
+
+	switch len(addresses) {
+	case 0:
+		return nil
+	case 1:
+		return Reference{Address: addresses[0]}
+	}
+	refs := make([]any, 0, len(addresses))
+	for _, address := range addresses {
+		refs = append(refs, Reference{Address: address})
+	}
+	return refs
+}
+
+// resourceAddress returns the address (type.name) of the resource a traversal refers to
+func resourceAddress(traversal hcl.Traversal) (string, bool) {
+	if len(traversal) < 2 || !isResourceAddress(traversal.RootName()) {
+		return "", false
+	}
+	attr, ok := traversal[1].(hcl.TraverseAttr)
+	if !ok {
+		return "", false
+	}
+	return traversal.RootName() + "." + attr.Name, true
+}
+
+// ctyToGo converts a known cty value into plain go values. Unknown, null and capsule values become nil.
+func ctyToGo(value cty.Value) any {
+	if value.IsNull() || !value.IsWhollyKnown() {
+		return nil
+	}
+
+	valueType := value.Type()
+	switch {
+	case valueType == cty.String:
+		return value.AsString()
+	case valueType == cty.Number:
+		number, _ := value.AsBigFloat().Float64()
+		return number
+	case valueType == cty.Bool:
+		return value.True()
+	case valueType.IsListType() || valueType.IsTupleType() || valueType.IsSetType():
+		items := make([]any, 0, value.LengthInt())
+		for it := value.ElementIterator(); it.Next(); {
+			_, item := it.Element()
+			items = append(items, ctyToGo(item))
+		}
+		return items
+	case valueType.IsMapType() || valueType.IsObjectType():
+		object := make(map[string]any)
+		for it := value.ElementIterator(); it.Next(); {
+			key, item := it.Element()
+			object[key.AsString()] = ctyToGo(item)
+		}
+		return object
+	}
+	return nil
+}
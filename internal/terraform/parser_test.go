@@ -0,0 +1,89 @@
+package terraform
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Confirm that all resources of the module directory are parsed and sub directories are ignored
+func TestParser_ParseModule(t *testing.T) {
+	resources, err := NewTerraformParser("testdata/module", slog.Default()).Parse()
+	require.NoError(t, err)
+
+	addresses := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		addresses = append(addresses, resource.Address)
+	}
+	assert.Len(t, resources, 15)
+	assert.Contains(t, addresses, "aws_instance.app")
+	assert.Contains(t, addresses, "aws_vpc.main")
+	assert.NotContains(t, addresses, "aws_instance.ignored")
+}
+
+// Confirm that literals, references and nested blocks are converted into plain go values
+func TestParser_Attributes(t *testing.T) {
+	content := `
+resource "aws_instance" "app" {
+  ami                    = "ami-123456"
+  instance_type          = var.instance_type
+  subnet_id              = aws_subnet.private.id
+  vpc_security_group_ids = [aws_security_group.app.id, data.aws_security_group.shared.id]
+  monitoring             = true
+  user_data              = "${aws_db_instance.db.address}:${local.port}"
+
+  tags = {
+    Name = "app-server"
+  }
+
+  ebs_block_device {
+    volume_size = 20
+  }
+
+  ebs_block_device {
+    volume_size = 40
+  }
+}
+`
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(content), 0600))
+
+	resources, err := NewTerraformParser(dir, slog.Default()).Parse()
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+
+	resource := resources[0]
+	assert.Equal(t, "aws_instance", resource.Type)
+	assert.Equal(t, "app", resource.Name)
+	assert.Equal(t, "ami-123456", resource.Attributes["ami"])
+	assert.NotContains(t, resource.Attributes, "instance_type")
+	assert.Equal(t, Reference{Address: "aws_subnet.private"}, resource.Attributes["subnet_id"])
+	assert.Equal(t, []any{Reference{Address: "aws_security_group.app"}}, resource.Attributes["vpc_security_group_ids"])
+	assert.Equal(t, true, resource.Attributes["monitoring"])
+	assert.Equal(t, Reference{Address: "aws_db_instance.db"}, resource.Attributes["user_data"])
+	assert.Equal(t, map[string]any{"Name": "app-server"}, resource.Attributes["tags"])
+	assert.Equal(t, []any{
+		map[string]any{"volume_size": 20.0},
+		map[string]any{"volume_size": 40.0},
+	}, resource.Attributes["ebs_block_device"])
+	assert.Equal(t, "app-server", resource.displayName())
+}
+
+// Confirm parser reports syntax errors
+func TestParser_InvalidSyntaxWillFail(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`resource "aws_instance" {`), 0600))
+
+	_, err := NewTerraformParser(dir, slog.Default()).Parse()
+	assert.Error(t, err)
+}
+
+// Confirm parser rejects non existing directories
+func TestParser_PathToNonExistingDirectoryWillFail(t *testing.T) {
+	_, err := NewTerraformParser("testdata/does-not-exist", slog.Default()).Parse()
+	assert.Error(t, err)
+}
@@ -0,0 +1,163 @@
+package terraform
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// Resource is the input independent representation of a terraform resource.
+// Attribute values are plain go values (string, float64, bool, []any, map[string]any).
+// Nested blocks are stored as []any of map[string]any under the block type.
+// Values that refer to other resources are represented by a Reference.
+type Resource struct {
+	Address    string
+	Type       string
+	Name       string
+	Attributes map[string]any
+}
+
+// Reference marks an attribute value that refers to another resource
+type Reference struct {
+	Address string
+}
+
+type boundaryKind int
+
+// The order of the boundary kinds defines the nesting: a boundary can only be part of a boundary with a smaller kind.
+const (
+	boundaryKindNetwork boundaryKind = iota
+	boundaryKindSubnet
+	boundaryKindSecurityGroup
+)
+
+func (kind boundaryKind) String() string {
+	switch kind {
+	case boundaryKindNetwork:
+		return "VPC"
+	case boundaryKindSubnet:
+		return "subnet"
+	case boundaryKindSecurityGroup:
+		return "security group"
+	}
+	return "unknown"
+}
+
+// assetResourceTypes maps resource types of the common cloud providers to asset types.
+// Compute resources are represented as applications, storage resources as databases.
+var assetResourceTypes = map[string]common.AssetType{
+	// compute
+	"aws_instance":                      common.AssetTypeApplication,
+	"aws_ecs_service":                   common.AssetTypeApplication,
+	"aws_lambda_function":               common.AssetTypeApplication,
+	"aws_autoscaling_group":             common.AssetTypeApplication,
+	"aws_eks_node_group":                common.AssetTypeApplication,
+	"google_compute_instance":           common.AssetTypeApplication,
+	"google_cloud_run_service":          common.AssetTypeApplication,
+	"google_cloudfunctions_function":    common.AssetTypeApplication,
+	"azurerm_linux_virtual_machine":     common.AssetTypeApplication,
+	"azurerm_windows_virtual_machine":   common.AssetTypeApplication,
+	"azurerm_virtual_machine":           common.AssetTypeApplication,
+	"azurerm_linux_web_app":             common.AssetTypeApplication,
+	"azurerm_function_app":              common.AssetTypeApplication,
+	"azurerm_container_group":           common.AssetTypeApplication,
+	"azurerm_kubernetes_cluster":        common.AssetTypeInfrastructure,
+	"aws_eks_cluster":                   common.AssetTypeInfrastructure,
+	"google_container_cluster":          common.AssetTypeInfrastructure,
+	"aws_sqs_queue":                     common.AssetTypeInfrastructure,
+	"aws_sns_topic":                     common.AssetTypeInfrastructure,
+	"aws_mq_broker":                     common.AssetTypeInfrastructure,
+	"aws_elasticache_cluster":           common.AssetTypeInfrastructure,
+	"aws_elasticache_replication_group": common.AssetTypeInfrastructure,
+	// databases
+	"aws_db_instance":                    common.AssetTypeDatabase,
+	"aws_rds_cluster":                    common.AssetTypeDatabase,
+	"aws_dynamodb_table":                 common.AssetTypeDatabase,
+	"aws_docdb_cluster":                  common.AssetTypeDatabase,
+	"aws_redshift_cluster":               common.AssetTypeDatabase,
+	"google_sql_database_instance":       common.AssetTypeDatabase,
+	"google_bigquery_dataset":            common.AssetTypeDatabase,
+	"azurerm_mssql_server":               common.AssetTypeDatabase,
+	"azurerm_postgresql_flexible_server": common.AssetTypeDatabase,
+	"azurerm_mysql_flexible_server":      common.AssetTypeDatabase,
+	"azurerm_cosmosdb_account":           common.AssetTypeDatabase,
+	// storage
+	"aws_s3_bucket":           common.AssetTypeDatabase,
+	"aws_efs_file_system":     common.AssetTypeDatabase,
+	"aws_ebs_volume":          common.AssetTypeDatabase,
+	"google_storage_bucket":   common.AssetTypeDatabase,
+	"azurerm_storage_account": common.AssetTypeDatabase,
+	// load balancers
+	"aws_lb":                            common.AssetTypeWebserver,
+	"aws_alb":                           common.AssetTypeWebserver,
+	"aws_elb":                           common.AssetTypeWebserver,
+	"aws_api_gateway_rest_api":          common.AssetTypeWebserver,
+	"aws_apigatewayv2_api":              common.AssetTypeWebserver,
+	"aws_cloudfront_distribution":       common.AssetTypeWebserver,
+	"google_compute_forwarding_rule":    common.AssetTypeWebserver,
+	"google_compute_url_map":            common.AssetTypeWebserver,
+	"azurerm_lb":                        common.AssetTypeWebserver,
+	"azurerm_application_gateway":       common.AssetTypeWebserver,
+	"azurerm_frontdoor":                 common.AssetTypeWebserver,
+	"azurerm_cdn_frontdoor_profile":     common.AssetTypeWebserver,
+	"google_compute_target_https_proxy": common.AssetTypeWebserver,
+}
+
+// boundaryResourceTypes maps network resource types to the kind of trust boundary they represent
+var boundaryResourceTypes = map[string]boundaryKind{
+	"aws_vpc":                        boundaryKindNetwork,
+	"google_compute_network":         boundaryKindNetwork,
+	"azurerm_virtual_network":        boundaryKindNetwork,
+	"aws_subnet":                     boundaryKindSubnet,
+	"google_compute_subnetwork":      boundaryKindSubnet,
+	"azurerm_subnet":                 boundaryKindSubnet,
+	"aws_security_group":             boundaryKindSecurityGroup,
+	"azurerm_network_security_group": boundaryKindSecurityGroup,
+}
+
+// displayName returns the value of the 'Name' tag if it is known, otherwise the resource name
+func (r Resource) displayName() string {
+	tags, ok := r.Attributes["tags"].(map[string]any)
+	if ok {
+		if name, ok := tags["Name"].(string); ok && name != "" {
+			return name
+		}
+	}
+	return r.Name
+}
+
+// references returns the addresses of all resources referenced in the given value.
+// The result is sorted and does not contain duplicates.
+func references(value any) []string {
+	addresses := make([]string, 0)
+	var collect func(value any)
+	collect = func(value any) {
+		switch v := value.(type) {
+		case Reference:
+			addresses = append(addresses, v.Address)
+		case []any:
+			for _, item := range v {
+				collect(item)
+			}
+		case map[string]any:
+			for _, item := range v {
+				collect(item)
+			}
+		}
+	}
+	collect(value)
+
+	slices.Sort(addresses)
+	return slices.Compact(addresses)
+}
+
+// isResourceAddress checks if a reference root names a managed resource.
+// Variables, locals, data sources, modules and meta arguments are not resources.
+func isResourceAddress(rootName string) bool {
+	switch rootName {
+	case "var", "local", "module", "data", "path", "terraform", "count", "each", "self":
+		return false
+	}
+	return strings.Contains(rootName, "_")
+}
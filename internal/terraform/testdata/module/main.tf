@@ -0,0 +1,47 @@
+variable "instance_type" {
+  type    = string
+  default = "t3.micro"
+}
+
+locals {
+  environment = "prod"
+}
+
+resource "aws_lb" "frontend" {
+  name            = "frontend-${local.environment}"
+  subnets         = [aws_subnet.public.id]
+  security_groups = [aws_security_group.lb.id]
+}
+
+resource "aws_instance" "app" {
+  ami                    = "ami-123456"
+  instance_type          = var.instance_type
+  subnet_id              = aws_subnet.private.id
+  vpc_security_group_ids = [aws_security_group.app.id]
+
+  tags = {
+    Name        = "app-server"
+    Environment = local.environment
+  }
+}
+
+resource "aws_db_instance" "orders" {
+  engine                 = "postgres"
+  db_subnet_group_name   = aws_db_subnet_group.db.name
+  vpc_security_group_ids = [aws_security_group.db.id]
+}
+
+resource "aws_elasticache_cluster" "cache" {
+  cluster_id         = "cache"
+  engine             = "redis"
+  subnet_group_name  = "cache"
+  security_group_ids = [aws_security_group.db.id]
+}
+
+resource "aws_s3_bucket" "assets" {
+  bucket = "assets"
+}
+
+resource "aws_iam_role" "app" {
+  name = "app"
+}
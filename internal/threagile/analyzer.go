@@ -0,0 +1,319 @@
+package threagile
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// ThreagileAnalyzer analyzes Threagile models
+type ThreagileAnalyzer struct {
+	ModelFilePath string
+	logger        *slog.Logger
+}
+
+// NewThreagileAnalyzer creates a new instance of ThreagileAnalyzer
+func NewThreagileAnalyzer(modelFilePath string, logger *slog.Logger) *ThreagileAnalyzer {
+	return &ThreagileAnalyzer{
+		ModelFilePath: modelFilePath,
+		logger:        logger.With("package", "threagile", "component", "ThreagileAnalyzer"),
+	}
+}
+
+// Analyze analyzes the given Threagile model and returns a threat model.
+// Technical assets become assets, communication links become dataflows and trust boundaries become trust boundaries
+// that contain the assets of their nested boundaries as well. The risks of the individual risk categories become
+// threats of their most relevant technical asset or communication link.
+func (a *ThreagileAnalyzer) Analyze(model *Model) (*common.ThreatModel, error) {
+	if model == nil {
+		return nil, fmt.Errorf("no Threagile model to analyze was given")
+	}
+
+	result := common.EmptyThreatModel()
+	a.logger.Debug("Beginning threagile analysis", "technicalAssetCount", len(model.TechnicalAssets))
+
+	assetIndex := make(map[string]int) // technical asset ID -> index of the asset
+	for _, title := range slices.Sorted(maps.Keys(model.TechnicalAssets)) {
+		technical := model.TechnicalAssets[title]
+		asset := common.Asset{
+			ID:                a.id("technical-asset", technical.ID),
+			DisplayName:       title,
+			Type:              technicalAssetType(technical),
+			Source:            common.DataSourceThreagile,
+			StoresCredentials: storesCredentials(technical, model.DataAssets),
+			Extra: map[string]any{
+				"ThreagileID":         technical.ID,
+				"ThreagileTechnology": technical.Technology,
+			},
+		}
+		a.logger.Debug("Created a new instance of Asset for technical asset", "id", technical.ID, "asset", asset)
+		assetIndex[technical.ID] = len(result.Assets)
+		result.Assets = append(result.Assets, asset)
+	}
+
+	linkIndex := make(map[string]int) // communication link ID -> index of the dataflow
+	for _, title := range slices.Sorted(maps.Keys(model.TechnicalAssets)) {
+		source := model.TechnicalAssets[title]
+		for _, linkTitle := range slices.Sorted(maps.Keys(source.CommunicationLinks)) {
+			link := source.CommunicationLinks[linkTitle]
+			target, ok := assetIndex[link.Target]
+			if !ok {
+				a.logger.Warn("Communication link has an unknown target and is skipped", "asset", title, "link", linkTitle, "target", link.Target)
+				continue
+			}
+			linkID := fmt.Sprintf("%s>%s", source.ID, makeID(linkTitle))
+			targetTitle := result.Assets[target].DisplayName
+			linkIndex[linkID] = len(result.DataFlows)
+			result.DataFlows = append(result.DataFlows, common.DataFlow{
+				ID:            a.id("communication-link", linkID),
+				Name:          linkTitle,
+				Protocol:      strings.TrimSuffix(link.Protocol, "-encrypted"),
+				Encrypted:     encryptedProtocol(link.Protocol),
+				PublicNetwork: source.Internet || model.TechnicalAssets[targetTitle].Internet,
+				Source:        title,
+				Target:        targetTitle,
+				Bidirectional: len(link.DataAssetsReceived) > 0,
+				DataSource:    common.DataSourceThreagile,
+				Extra: map[string]any{
+					"ThreagileID": linkID,
+				},
+			})
+		}
+	}
+
+	for _, title := range slices.Sorted(maps.Keys(model.TrustBoundaries)) {
+		boundary := model.TrustBoundaries[title]
+		contained := make([]string, 0)
+		for _, id := range boundaryAssets(boundary.ID, model.TrustBoundaries, map[string]bool{}) {
+			index, ok := assetIndex[id]
+			if !ok {
+				a.logger.Warn("Trust boundary contains an unknown technical asset", "boundary", title, "asset", id)
+				continue
+			}
+			if !slices.Contains(contained, result.Assets[index].ID) {
+				contained = append(contained, result.Assets[index].ID)
+			}
+		}
+		result.Boundaries = append(result.Boundaries, common.TrustBoundary{
+			ID:              a.id("trust-boundary", boundary.ID),
+			DisplayName:     title,
+			ContainedAssets: contained,
+			Source:          common.DataSourceThreagile,
+			Extra: map[string]any{
+				"initial-description": boundary.Description,
+				"ThreagileID":         boundary.ID,
+				"ThreagileType":       boundary.Type,
+			},
+		})
+	}
+
+	for _, categoryTitle := range slices.Sorted(maps.Keys(model.IndividualRiskCategories)) {
+		category := model.IndividualRiskCategories[categoryTitle]
+		for _, riskTitle := range slices.Sorted(maps.Keys(category.RisksIdentified)) {
+			risk := category.RisksIdentified[riskTitle]
+			syntheticID := syntheticRiskID(category.ID, risk)
+			threat := a.threat(category, riskTitle, risk, model.RiskTracking[syntheticID], syntheticID)
+			if index, ok := assetIndex[risk.MostRelevantTechnicalAsset]; ok {
+				result.Assets[index].Threats = append(result.Assets[index].Threats, threat)
+			} else if index, ok := linkIndex[risk.MostRelevantCommunicationLink]; ok {
+				result.DataFlows[index].Threats = append(result.DataFlows[index].Threats, threat)
+			} else {
+				a.logger.Warn("Risk is neither related to a technical asset nor to a communication link and is skipped", "risk", syntheticID)
+			}
+		}
+	}
+
+	a.logger.Debug("Threagile analysis finished", "assetCount", len(result.Assets), "boundaryCount", len(result.Boundaries), "dataflowCount", len(result.DataFlows))
+	return &result, nil
+}
+
+// id generates a unique ID for an element of the model by hashing the file path, the kind and the Threagile ID
+func (a *ThreagileAnalyzer) id(kind, threagileID string) string {
+	return common.GenerateIDHash(a.ModelFilePath, fmt.Sprintf("%s:%s", kind, threagileID))
+}
+
+// threat creates the threat of an identified risk. Its status is taken from the risk tracking.
+func (a *ThreagileAnalyzer) threat(category RiskCategory, title string, risk Risk, tracking RiskTracking, syntheticID string) common.Threat {
+	id := a.id("threat", syntheticID)
+	description := category.Description
+	if category.Impact != "" {
+		description = strings.TrimSpace(description + "\n\n" + category.Impact)
+	}
+	return common.Threat{
+		InternalID:  id,
+		ID:          common.UUIDFormat(id),
+		Title:       htmlTags.ReplaceAllString(title, ""),
+		Status:      riskStatus(tracking.Status),
+		Severity:    riskSeverity(risk.Severity),
+		Type:        strideThreatType(category.Stride),
+		Description: description,
+		Mitigation:  category.Mitigation,
+		ModelType:   common.STRIDE,
+		Source:      common.DataSourceThreagile,
+		MapIndex:    -1,
+	}
+}
+
+// htmlTags matches the tags that Threagile uses to highlight parts of risk titles
+var htmlTags = regexp.MustCompile(`</?[a-zA-Z]+>`)
+
+// syntheticRiskID builds the ID under which Threagile tracks a risk: the category and the most relevant elements
+func syntheticRiskID(categoryID string, risk Risk) string {
+	parts := []string{categoryID}
+	for _, part := range []string{risk.MostRelevantDataAsset, risk.MostRelevantTechnicalAsset, risk.MostRelevantCommunicationLink,
+		risk.MostRelevantTrustBoundary, risk.MostRelevantSharedRuntime} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "@")
+}
+
+// boundaryAssets returns the IDs of the technical assets in the boundary and in the boundaries nested into it
+func boundaryAssets(id string, boundaries map[string]TrustBoundary, visited map[string]bool) []string {
+	if visited[id] {
+		return nil
+	}
+	visited[id] = true
+	for _, title := range slices.Sorted(maps.Keys(boundaries)) {
+		boundary := boundaries[title]
+		if boundary.ID != id {
+			continue
+		}
+		assets := slices.Clone(boundary.TechnicalAssetsInside)
+		for _, nested := range boundary.TrustBoundariesNested {
+			assets = append(assets, boundaryAssets(nested, boundaries, visited)...)
+		}
+		return assets
+	}
+	return nil
+}
+
+// technologyAssetTypes maps Threagile technologies to asset types. Technologies that are not listed are applications.
+var technologyAssetTypes = map[string]common.AssetType{
+	"web-server":              common.AssetTypeWebserver,
+	"reverse-proxy":           common.AssetTypeWebserver,
+	"load-balancer":           common.AssetTypeWebserver,
+	"waf":                     common.AssetTypeWebserver,
+	"gateway":                 common.AssetTypeWebserver,
+	"database":                common.AssetTypeDatabase,
+	"identity-store-database": common.AssetTypeDatabase,
+	"file-server":             common.AssetTypeDatabase,
+	"block-storage":           common.AssetTypeDatabase,
+	"data-lake":               common.AssetTypeDatabase,
+	"search-index":            common.AssetTypeDatabase,
+	"message-queue":           common.AssetTypeInfrastructure,
+	"stream-processing":       common.AssetTypeInfrastructure,
+	"service-mesh":            common.AssetTypeInfrastructure,
+	"service-registry":        common.AssetTypeInfrastructure,
+	"container-platform":      common.AssetTypeInfrastructure,
+	"build-pipeline":          common.AssetTypeInfrastructure,
+	"sourcecode-repository":   common.AssetTypeInfrastructure,
+	"artifact-registry":       common.AssetTypeInfrastructure,
+	"monitoring":              common.AssetTypeInfrastructure,
+	"vault":                   common.AssetTypeInfrastructure,
+	"hsm":                     common.AssetTypeInfrastructure,
+	"ids":                     common.AssetTypeInfrastructure,
+	"ips":                     common.AssetTypeInfrastructure,
+	"ldap-server":             common.AssetTypeInfrastructure,
+	"identity-store-ldap":     common.AssetTypeInfrastructure,
+	"mail-server":             common.AssetTypeInfrastructure,
+	"browser":                 common.AssetTypeExternalEntity,
+	"client-system":           common.AssetTypeExternalEntity,
+	"desktop":                 common.AssetTypeExternalEntity,
+	"mobile-app":              common.AssetTypeExternalEntity,
+	"devops-client":           common.AssetTypeExternalEntity,
+	"iot-device":              common.AssetTypeExternalEntity,
+}
+
+// technicalAssetType determines the asset type of a technical asset by its type and technology
+func technicalAssetType(technical TechnicalAsset) common.AssetType {
+	switch technical.Type {
+	case "external-entity":
+		return common.AssetTypeExternalEntity
+	case "datastore":
+		return common.AssetTypeDatabase
+	}
+	if assetType, ok := technologyAssetTypes[technical.Technology]; ok {
+		return assetType
+	}
+	return common.AssetTypeApplication
+}
+
+// credentialWords are parts of the IDs of data assets that hold credentials
+var credentialWords = []string{"credential", "secret", "password", "token", "key"}
+
+// storesCredentials checks if the technical asset stores a data asset that looks like credentials
+func storesCredentials(technical TechnicalAsset, dataAssets map[string]DataAsset) bool {
+	for title, dataAsset := range dataAssets {
+		if !slices.Contains(technical.DataAssetsStored, dataAsset.ID) {
+			continue
+		}
+		name := strings.ToLower(dataAsset.ID + " " + title)
+		if slices.ContainsFunc(credentialWords, func(word string) bool { return strings.Contains(name, word) }) {
+			return true
+		}
+	}
+	return false
+}
+
+// encryptedProtocol checks if a Threagile protocol is encrypted
+func encryptedProtocol(protocol string) bool {
+	switch protocol {
+	case "https", "wss", "ftps", "sftp", "scp", "ssh", "ssh-tunnel", "ldaps":
+		return true
+	}
+	return strings.HasSuffix(protocol, "-encrypted")
+}
+
+// riskStatus maps the status of a tracked risk to a threat status. Risks that are not tracked are open.
+func riskStatus(status string) common.Status {
+	switch status {
+	case "mitigated":
+		return common.Mitigated
+	case "false-positive":
+		return common.NotApplicable
+	}
+	return common.Open
+}
+
+// riskSeverity maps the Threagile severity to the severities of Threat Dragon
+func riskSeverity(severity string) string {
+	switch severity {
+	case "low":
+		return "Low"
+	case "high", "critical":
+		return "High"
+	}
+	return "Medium"
+}
+
+func strideThreatType(stride string) common.ThreatType {
+	switch stride {
+	case "spoofing":
+		return common.Spoofing
+	case "tampering":
+		return common.Tampering
+	case "repudiation":
+		return common.Repudiation
+	case "information-disclosure":
+		return common.InformationDisclosure
+	case "denial-of-service":
+		return common.DenialOfService
+	case "elevation-of-privilege":
+		return common.ElevationOfPrivilege
+	}
+	return common.ThreatTypeUnknown
+}
+
+// makeID normalizes a title like Threagile does for the IDs of communication links
+func makeID(title string) string {
+	return strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToLower(title), "-"), "- ")
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
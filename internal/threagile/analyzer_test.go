@@ -0,0 +1,170 @@
+package threagile
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+	"gopkg.in/yaml.v3"
+)
+
+const testModelPath = "testdata/threagile.yaml"
+
+func analyzeTestModel(t *testing.T) *common.ThreatModel {
+	t.Helper()
+	model, err := NewThreagileParser(testModelPath, slog.Default()).Parse()
+	require.NoError(t, err)
+	result, err := NewThreagileAnalyzer(testModelPath, slog.Default()).Analyze(model)
+	require.NoError(t, err)
+	return result
+}
+
+func findAsset(t *testing.T, model *common.ThreatModel, name string) common.Asset {
+	t.Helper()
+	for _, asset := range model.Assets {
+		if asset.DisplayName == name {
+			return asset
+		}
+	}
+	require.Failf(t, "asset not found", name)
+	return common.Asset{}
+}
+
+func findDataflow(t *testing.T, model *common.ThreatModel, name string) common.DataFlow {
+	t.Helper()
+	for _, dataflow := range model.DataFlows {
+		if dataflow.Name == name {
+			return dataflow
+		}
+	}
+	require.Failf(t, "dataflow not found", name)
+	return common.DataFlow{}
+}
+
+func TestAnalyze_Assets(t *testing.T) {
+	model := analyzeTestModel(t)
+	require.Len(t, model.Assets, 4)
+
+	tests := []struct {
+		name      string
+		assetType common.AssetType
+	}{
+		{"Customer Browser", common.AssetTypeExternalEntity},
+		{"Load Balancer", common.AssetTypeWebserver},
+		{"Shop Backend", common.AssetTypeApplication},
+		{"Orders DB", common.AssetTypeDatabase},
+	}
+	for _, tt := range tests {
+		asset := findAsset(t, model, tt.name)
+		assert.Equal(t, tt.assetType, asset.Type, tt.name)
+		assert.Equal(t, common.DataSourceThreagile, asset.Source, tt.name)
+	}
+
+	db := findAsset(t, model, "Orders DB")
+	assert.Equal(t, common.GenerateIDHash(testModelPath, "technical-asset:orders-db"), db.ID)
+	assert.Equal(t, "orders-db", db.Extra["ThreagileID"])
+	assert.True(t, db.StoresCredentials)
+	assert.False(t, findAsset(t, model, "Shop Backend").StoresCredentials)
+}
+
+func TestAnalyze_Dataflows(t *testing.T) {
+	model := analyzeTestModel(t)
+	// the audit log targets an asset that is not part of the model
+	require.Len(t, model.DataFlows, 3)
+
+	traffic := findDataflow(t, model, "Shop Traffic")
+	assert.Equal(t, "Customer Browser", traffic.Source)
+	assert.Equal(t, "Load Balancer", traffic.Target)
+	assert.Equal(t, "https", traffic.Protocol)
+	assert.True(t, traffic.Encrypted)
+	assert.True(t, traffic.PublicNetwork)
+	assert.True(t, traffic.Bidirectional)
+
+	requests := findDataflow(t, model, "Web Requests")
+	assert.Equal(t, "http", requests.Protocol)
+	assert.False(t, requests.Encrypted)
+	assert.False(t, requests.PublicNetwork)
+	assert.False(t, requests.Bidirectional)
+
+	queries := findDataflow(t, model, "Order Queries")
+	assert.Equal(t, "jdbc", queries.Protocol)
+	assert.True(t, queries.Encrypted)
+	assert.Equal(t, common.DataSourceThreagile, queries.DataSource)
+}
+
+func TestAnalyze_Boundaries(t *testing.T) {
+	model := analyzeTestModel(t)
+	require.Len(t, model.Boundaries, 2)
+
+	ids := func(names ...string) []string {
+		result := make([]string, 0, len(names))
+		for _, name := range names {
+			result = append(result, findAsset(t, model, name).ID)
+		}
+		return result
+	}
+	backend, cloud := model.Boundaries[0], model.Boundaries[1]
+	assert.Equal(t, "Backend Subnet", backend.DisplayName)
+	assert.Equal(t, ids("Shop Backend", "Orders DB"), backend.ContainedAssets)
+	assert.Equal(t, "Private subnet", backend.Extra["initial-description"])
+	// nested boundaries are part of the boundary around them
+	assert.Equal(t, "Cloud Network", cloud.DisplayName)
+	assert.Equal(t, ids("Load Balancer", "Shop Backend", "Orders DB"), cloud.ContainedAssets)
+	assert.Equal(t, "network-cloud-provider", cloud.Extra["ThreagileType"])
+}
+
+func TestAnalyze_Threats(t *testing.T) {
+	model := analyzeTestModel(t)
+
+	threats := findAsset(t, model, "Shop Backend").Threats
+	require.Len(t, threats, 1)
+	threat := threats[0]
+	assert.Equal(t, "Missing Order Audit at Shop Backend", threat.Title)
+	assert.Equal(t, common.Repudiation, threat.Type)
+	assert.Equal(t, "Medium", threat.Severity)
+	assert.Equal(t, common.Mitigated, threat.Status)
+	assert.Equal(t, "Order changes are not audited.\n\nFraudulent orders cannot be traced.", threat.Description)
+	assert.Equal(t, "Write an audit log entry for every order change.", threat.Mitigation)
+	assert.Equal(t, common.UUIDFormat(threat.InternalID), threat.ID)
+
+	threats = findDataflow(t, model, "Order Queries").Threats
+	require.Len(t, threats, 1)
+	assert.Equal(t, "High", threats[0].Severity)
+	assert.Equal(t, common.Open, threats[0].Status)
+
+	// the risk of the trust boundary has no place in the model
+	count := 0
+	for _, asset := range model.Assets {
+		count += len(asset.Threats)
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestAnalyze_NoModel(t *testing.T) {
+	_, err := NewThreagileAnalyzer(testModelPath, slog.Default()).Analyze(nil)
+	assert.Error(t, err)
+}
+
+// TestAnalyze_RoundTrip tests that a model read from Threagile is written with the same IDs, types and protocols
+func TestAnalyze_RoundTrip(t *testing.T) {
+	const path = "testdata/testoutput_threagile_roundtrip.yaml"
+	defer os.Remove(path)
+
+	output, _ := newTestOutput(path)
+	require.NoError(t, output.Generate(analyzeTestModel(t)))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var written Model
+	require.NoError(t, yaml.Unmarshal(content, &written))
+
+	backend := written.TechnicalAssets["Shop Backend"]
+	assert.Equal(t, "shop-backend", backend.ID)
+	assert.Equal(t, "web-service-rest", backend.Technology)
+	assert.Equal(t, "jdbc-encrypted", backend.CommunicationLinks["Order Queries"].Protocol)
+	assert.Equal(t, "network-cloud-security-group", written.TrustBoundaries["Backend Subnet"].Type)
+	assert.Equal(t, []string{"backend-subnet"}, written.TrustBoundaries["Cloud Network"].TrustBoundariesNested)
+	assert.Equal(t, []string{"load-balancer"}, written.TrustBoundaries["Cloud Network"].TechnicalAssetsInside)
+}
@@ -0,0 +1,113 @@
+package threagile
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// trustBoundaries maps the trust boundaries of the model. Threagile allows a technical asset in only one trust
+// boundary and a trust boundary in only one parent, so each asset is put into its innermost boundary and each boundary
+// is nested into the smallest boundary that contains all of its assets. Assets of overlapping boundaries, like a service
+// attached to two networks, are kept in one of them only.
+// Threagile models the internet as the space outside of all trust boundaries, so boundaries that only contain
+// external entities are left out.
+func (tgo *ThreagileOutput) trustBoundaries(model *common.ThreatModel, assetIDs map[string]string, ids ids) map[string]TrustBoundary {
+	external := make(map[string]bool)
+	for _, asset := range model.Assets {
+		if asset.Type == common.AssetTypeExternalEntity {
+			external[asset.ID] = true
+		}
+	}
+
+	boundaries := make([]common.TrustBoundary, 0, len(model.Boundaries))
+	members := make([][]string, 0, len(model.Boundaries)) // assets of the model in each boundary
+	for _, boundary := range model.Boundaries {
+		contained := make([]string, 0, len(boundary.ContainedAssets))
+		for _, id := range boundary.ContainedAssets {
+			if _, ok := assetIDs[id]; ok && !slices.Contains(contained, id) {
+				contained = append(contained, id)
+			}
+		}
+		if len(contained) > 0 && !slices.ContainsFunc(contained, func(id string) bool { return !external[id] }) {
+			tgo.logger.Debug("Trust boundary only contains external entities and is left out", "boundary", boundary.DisplayName)
+			continue
+		}
+		boundaries = append(boundaries, boundary)
+		members = append(members, contained)
+	}
+
+	parents := common.BoundaryParents(members)
+	titles := newIDs()
+	boundaryIDs := make([]string, len(boundaries))
+	boundaryTitles := make([]string, len(boundaries))
+	for i, boundary := range boundaries {
+		boundaryIDs[i] = ids.unique(common.GetOr(boundary.Extra, "ThreagileID", slug(boundary.DisplayName)))
+		boundaryTitles[i] = titles.unique(boundary.DisplayName)
+	}
+
+	result := make(map[string]TrustBoundary, len(boundaries))
+	for i, boundary := range boundaries {
+		result[boundaryTitles[i]] = TrustBoundary{
+			ID:                    boundaryIDs[i],
+			Description:           common.GetOr(boundary.Extra, "initial-description", boundary.DisplayName),
+			Type:                  boundaryType(boundary),
+			Tags:                  []string{},
+			TechnicalAssetsInside: []string{},
+			TrustBoundariesNested: []string{},
+		}
+	}
+	for i, parent := range parents {
+		if parent >= 0 {
+			nested := result[boundaryTitles[parent]]
+			nested.TrustBoundariesNested = append(nested.TrustBoundariesNested, boundaryIDs[i])
+			result[boundaryTitles[parent]] = nested
+		}
+	}
+
+	for _, asset := range model.Assets {
+		innermost, others := common.InnermostBoundary(members, parents, asset.ID)
+		if innermost < 0 {
+			continue
+		}
+		boundary := result[boundaryTitles[innermost]]
+		boundary.TechnicalAssetsInside = append(boundary.TechnicalAssetsInside, assetIDs[asset.ID])
+		result[boundaryTitles[innermost]] = boundary
+
+		dropped := make([]string, 0, len(others))
+		for _, i := range others {
+			dropped = append(dropped, boundaries[i].DisplayName)
+		}
+		if len(dropped) > 0 {
+			tgo.logger.Warn("Asset is in overlapping trust boundaries, but Threagile allows only one", "asset", asset.DisplayName, "boundary", boundaries[innermost].DisplayName, "dropped", dropped)
+			tgo.cl.AddEntry(fmt.Sprintf("Asset '%s' has been put into trust boundary '%s' only, it is also in '%s'", asset.DisplayName, boundaries[innermost].DisplayName, strings.Join(dropped, "', '")))
+		}
+	}
+	return result
+}
+
+// boundaryType maps a trust boundary to its Threagile type by the source it was found in.
+// Boundaries read from a Threagile model keep their type.
+func boundaryType(boundary common.TrustBoundary) string {
+	if threagileType := common.GetOr(boundary.Extra, "ThreagileType", ""); threagileType != "" {
+		return threagileType
+	}
+	switch boundary.Source {
+	case common.DataSourceDockerCompose:
+		return "network-virtual-lan"
+	case common.DataSourceKubernetes:
+		return "network-policy-namespace-isolation"
+	case common.DataSourceTerraform:
+		terraformType := common.GetOr(boundary.Extra, "TerraformType", "")
+		switch {
+		case strings.HasSuffix(terraformType, "security_group"):
+			return "network-cloud-security-group"
+		case strings.HasSuffix(terraformType, "subnet") || strings.HasSuffix(terraformType, "subnetwork"):
+			return "network-virtual-lan"
+		}
+		return "network-cloud-provider"
+	}
+	return "network-on-prem"
+}
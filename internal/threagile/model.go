@@ -0,0 +1,125 @@
+package threagile
+
+// Model is the root of a threagile.yaml file.
+// Only the parts of the Threagile schema that Threatcat fills are declared.
+type Model struct {
+	ThreagileVersion         string                    `yaml:"threagile_version"`
+	Title                    string                    `yaml:"title"`
+	Author                   Author                    `yaml:"author"`
+	Date                     string                    `yaml:"date"`
+	BusinessOverview         Overview                  `yaml:"business_overview"`
+	TechnicalOverview        Overview                  `yaml:"technical_overview"`
+	BusinessCriticality      string                    `yaml:"business_criticality"`
+	ManagementSummaryComment string                    `yaml:"management_summary_comment"`
+	Questions                map[string]string         `yaml:"questions"`
+	AbuseCases               map[string]string         `yaml:"abuse_cases"`
+	SecurityRequirements     map[string]string         `yaml:"security_requirements"`
+	TagsAvailable            []string                  `yaml:"tags_available"`
+	DataAssets               map[string]DataAsset      `yaml:"data_assets"`
+	TechnicalAssets          map[string]TechnicalAsset `yaml:"technical_assets"`
+	TrustBoundaries          map[string]TrustBoundary  `yaml:"trust_boundaries"`
+	SharedRuntimes           map[string]any            `yaml:"shared_runtimes"`
+	IndividualRiskCategories map[string]RiskCategory   `yaml:"individual_risk_categories"`
+	RiskTracking             map[string]RiskTracking   `yaml:"risk_tracking"`
+}
+
+type Author struct {
+	Name     string `yaml:"name"`
+	Homepage string `yaml:"homepage"`
+}
+
+type Overview struct {
+	Description string   `yaml:"description"`
+	Images      []string `yaml:"images"`
+}
+
+type DataAsset struct {
+	ID                     string   `yaml:"id"`
+	Description            string   `yaml:"description"`
+	Usage                  string   `yaml:"usage"`
+	Tags                   []string `yaml:"tags"`
+	Origin                 string   `yaml:"origin"`
+	Owner                  string   `yaml:"owner"`
+	Quantity               string   `yaml:"quantity"`
+	Confidentiality        string   `yaml:"confidentiality"`
+	Integrity              string   `yaml:"integrity"`
+	Availability           string   `yaml:"availability"`
+	JustificationCiaRating string   `yaml:"justification_cia_rating"`
+}
+
+type TechnicalAsset struct {
+	ID                      string                       `yaml:"id"`
+	Description             string                       `yaml:"description"`
+	Type                    string                       `yaml:"type"`
+	Usage                   string                       `yaml:"usage"`
+	UsedAsClientByHuman     bool                         `yaml:"used_as_client_by_human"`
+	OutOfScope              bool                         `yaml:"out_of_scope"`
+	JustificationOutOfScope string                       `yaml:"justification_out_of_scope"`
+	Size                    string                       `yaml:"size"`
+	Technology              string                       `yaml:"technology"`
+	Tags                    []string                     `yaml:"tags"`
+	Internet                bool                         `yaml:"internet"`
+	Machine                 string                       `yaml:"machine"`
+	Encryption              string                       `yaml:"encryption"`
+	Owner                   string                       `yaml:"owner"`
+	Confidentiality         string                       `yaml:"confidentiality"`
+	Integrity               string                       `yaml:"integrity"`
+	Availability            string                       `yaml:"availability"`
+	JustificationCiaRating  string                       `yaml:"justification_cia_rating"`
+	MultiTenant             bool                         `yaml:"multi_tenant"`
+	Redundant               bool                         `yaml:"redundant"`
+	CustomDevelopedParts    bool                         `yaml:"custom_developed_parts"`
+	DataAssetsProcessed     []string                     `yaml:"data_assets_processed"`
+	DataAssetsStored        []string                     `yaml:"data_assets_stored"`
+	DataFormatsAccepted     []string                     `yaml:"data_formats_accepted"`
+	CommunicationLinks      map[string]CommunicationLink `yaml:"communication_links"`
+}
+
+type CommunicationLink struct {
+	Target             string   `yaml:"target"`
+	Description        string   `yaml:"description"`
+	Protocol           string   `yaml:"protocol"`
+	Authentication     string   `yaml:"authentication"`
+	Authorization      string   `yaml:"authorization"`
+	Tags               []string `yaml:"tags"`
+	VPN                bool     `yaml:"vpn"`
+	IPFiltered         bool     `yaml:"ip_filtered"`
+	Readonly           bool     `yaml:"readonly"`
+	Usage              string   `yaml:"usage"`
+	DataAssetsSent     []string `yaml:"data_assets_sent"`
+	DataAssetsReceived []string `yaml:"data_assets_received"`
+}
+
+type TrustBoundary struct {
+	ID                    string   `yaml:"id"`
+	Description           string   `yaml:"description"`
+	Type                  string   `yaml:"type"`
+	Tags                  []string `yaml:"tags"`
+	TechnicalAssetsInside []string `yaml:"technical_assets_inside"`
+	TrustBoundariesNested []string `yaml:"trust_boundaries_nested"`
+}
+
+// RiskCategory is a risk category that is defined in the model itself, together with the risks it identified
+type RiskCategory struct {
+	ID              string          `yaml:"id"`
+	Description     string          `yaml:"description"`
+	Impact          string          `yaml:"impact"`
+	Mitigation      string          `yaml:"mitigation"`
+	Stride          string          `yaml:"stride"`
+	RisksIdentified map[string]Risk `yaml:"risks_identified"`
+}
+
+type Risk struct {
+	Severity                      string `yaml:"severity"`
+	MostRelevantDataAsset         string `yaml:"most_relevant_data_asset"`
+	MostRelevantTechnicalAsset    string `yaml:"most_relevant_technical_asset"`
+	MostRelevantCommunicationLink string `yaml:"most_relevant_communication_link"`
+	MostRelevantTrustBoundary     string `yaml:"most_relevant_trust_boundary"`
+	MostRelevantSharedRuntime     string `yaml:"most_relevant_shared_runtime"`
+}
+
+// RiskTracking is the state of a risk, keyed by the synthetic ID of the risk
+type RiskTracking struct {
+	Status        string `yaml:"status"`
+	Justification string `yaml:"justification"`
+}
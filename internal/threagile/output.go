@@ -0,0 +1,362 @@
+package threagile
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/threatcat-dev/threatcat/internal/common"
+	"gopkg.in/yaml.v3"
+)
+
+// threagileVersion is the version of the Threagile model format that is written
+const threagileVersion = "1.0.0"
+
+// IDs of the data assets that all technical assets process
+const (
+	applicationDataID = "application-data"
+	credentialsID     = "credentials"
+)
+
+type ThreagileOutput struct {
+	OutputPath string
+	// Title is the title of the generated model
+	Title  string
+	cl     changelog
+	logger *slog.Logger
+}
+
+type changelog interface {
+	AddEntry(string)
+}
+
+func NewThreagileOutput(outputPath string, cl changelog, logger *slog.Logger) *ThreagileOutput {
+	return &ThreagileOutput{
+		OutputPath: outputPath,
+		Title:      "new Threagile Output",
+		cl:         cl,
+		logger:     logger.With("package", "threagile", "component", "ThreagileOutput"),
+	}
+}
+
+// Generate writes the model as a threagile.yaml file. Existing files are overwritten.
+func (tgo *ThreagileOutput) Generate(model *common.ThreatModel) error {
+	tgo.logger.Debug("Generating threagile model")
+	tgo.cl.AddEntry("Starting the Threagile model generation from scratch.")
+
+	content, err := marshal(tgo.buildModel(model))
+	if err != nil {
+		return fmt.Errorf("failed to marshal Threagile model: %w", err)
+	}
+	err = os.MkdirAll(filepath.Dir(tgo.OutputPath), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(tgo.OutputPath, content, 0644)
+	if err != nil {
+		return err
+	}
+
+	tgo.logger.Debug("Threagile model has been written to file", "filePath", tgo.OutputPath)
+	return nil
+}
+
+func marshal(model *Model) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(model); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// buildModel maps the assets to technical assets, the dataflows to communication links of their source assets
+// and the trust boundaries to trust boundaries
+func (tgo *ThreagileOutput) buildModel(model *common.ThreatModel) *Model {
+	out := &Model{
+		ThreagileVersion: threagileVersion,
+		Title:            tgo.Title,
+		Author:           Author{Name: "Threatcat", Homepage: "https://github.com/threatcat-dev/threatcat"},
+		Date:             time.Now().Format(time.DateOnly),
+		BusinessOverview: Overview{
+			Description: "this model is auto generated by threatcat",
+			Images:      []string{},
+		},
+		TechnicalOverview: Overview{
+			Description: "this model is auto generated by threatcat",
+			Images:      []string{},
+		},
+		BusinessCriticality:      "important",
+		Questions:                map[string]string{},
+		AbuseCases:               map[string]string{},
+		SecurityRequirements:     map[string]string{},
+		TagsAvailable:            []string{},
+		DataAssets:               map[string]DataAsset{},
+		TechnicalAssets:          map[string]TechnicalAsset{},
+		TrustBoundaries:          map[string]TrustBoundary{},
+		SharedRuntimes:           map[string]any{},
+		IndividualRiskCategories: map[string]RiskCategory{},
+		RiskTracking:             map[string]RiskTracking{},
+	}
+
+	out.DataAssets["Application Data"] = dataAsset(applicationDataID, "Data processed by the applications", "internal")
+	if slices.ContainsFunc(model.Assets, func(asset common.Asset) bool { return asset.StoresCredentials }) {
+		out.DataAssets["Credentials"] = dataAsset(credentialsID, "Passwords, keys and tokens", "confidential")
+	}
+
+	ids := newIDs()
+	titles := newIDs()
+	assetIDs := make(map[string]string, len(model.Assets)) // asset ID -> technical asset ID
+	assetTitles := make(map[string]string, len(model.Assets))
+	for _, asset := range model.Assets {
+		assetIDs[asset.ID] = ids.unique(common.GetOr(asset.Extra, "ThreagileID", slug(asset.DisplayName)))
+		assetTitles[asset.ID] = titles.unique(asset.DisplayName)
+		out.TechnicalAssets[assetTitles[asset.ID]] = technicalAsset(asset, assetIDs[asset.ID])
+	}
+
+	links := 0
+	for _, dataflow := range model.DataFlows {
+		source := common.EndpointAsset(model.Assets, dataflow, dataflow.Source)
+		target := common.EndpointAsset(model.Assets, dataflow, dataflow.Target)
+		if source.ID == "" || target.ID == "" {
+			tgo.logger.Warn("Dataflow has no technical asset at one of its ends and is skipped", "dataflow", dataflow.Name)
+			tgo.cl.AddEntry(fmt.Sprintf("Dataflow '%s' could not be added, because '%s' or '%s' was not found", dataflow.Name, dataflow.Source, dataflow.Target))
+			continue
+		}
+		technical := out.TechnicalAssets[assetTitles[source.ID]]
+		title := dataflow.Name
+		if title == "" {
+			title = fmt.Sprintf("%s to %s", dataflow.Source, dataflow.Target)
+		}
+		base := title
+		for n := 2; ; n++ {
+			if _, ok := technical.CommunicationLinks[title]; !ok {
+				break
+			}
+			title = fmt.Sprintf("%s (%d)", base, n)
+		}
+		technical.CommunicationLinks[title] = communicationLink(dataflow, assetIDs[target.ID])
+		links++
+	}
+
+	for title, boundary := range tgo.trustBoundaries(model, assetIDs, ids) {
+		out.TrustBoundaries[title] = boundary
+	}
+
+	tgo.cl.AddEntry(fmt.Sprintf("Threagile model has been generated with %d technical assets, %d communication links and %d trust boundaries",
+		len(out.TechnicalAssets), links, len(out.TrustBoundaries)))
+	return out
+}
+
+func dataAsset(id, description, confidentiality string) DataAsset {
+	return DataAsset{
+		ID:              id,
+		Description:     description,
+		Usage:           "business",
+		Tags:            []string{},
+		Quantity:        "many",
+		Confidentiality: confidentiality,
+		Integrity:       "operational",
+		Availability:    "operational",
+	}
+}
+
+func technicalAsset(asset common.Asset, id string) TechnicalAsset {
+	description := fmt.Sprintf("%s, generated by Threatcat from %s", asset.DisplayName, asset.Source.ShortString())
+	if address := common.GetOr(asset.Extra, "TerraformAddress", ""); address != "" {
+		description += fmt.Sprintf(" (%s)", address)
+	}
+	technical := TechnicalAsset{
+		ID:                  id,
+		Description:         description,
+		Type:                "process",
+		Usage:               "business",
+		Size:                "service",
+		Technology:          common.GetOr(asset.Extra, "ThreagileTechnology", assetTechnology(asset.Type)),
+		Tags:                []string{},
+		Machine:             assetMachine(asset),
+		Encryption:          "none",
+		Confidentiality:     "internal",
+		Integrity:           "operational",
+		Availability:        "operational",
+		DataAssetsProcessed: []string{applicationDataID},
+		DataAssetsStored:    []string{},
+		DataFormatsAccepted: []string{},
+		CommunicationLinks:  map[string]CommunicationLink{},
+	}
+	switch asset.Type {
+	case common.AssetTypeDatabase:
+		technical.Type = "datastore"
+		technical.DataAssetsStored = []string{applicationDataID}
+	case common.AssetTypeExternalEntity:
+		technical.Type = "external-entity"
+		technical.Internet = true
+		technical.OutOfScope = true
+		technical.JustificationOutOfScope = "External entity outside of the analyzed infrastructure"
+	}
+	if asset.StoresCredentials {
+		technical.Confidentiality = "confidential"
+		technical.DataAssetsProcessed = append(technical.DataAssetsProcessed, credentialsID)
+		technical.DataAssetsStored = append(technical.DataAssetsStored, credentialsID)
+	}
+	return technical
+}
+
+// assetTechnology maps the asset type to a Threagile technology
+func assetTechnology(assetType common.AssetType) string {
+	switch assetType {
+	case common.AssetTypeApplication:
+		return "application-server"
+	case common.AssetTypeDatabase:
+		return "database"
+	case common.AssetTypeWebserver:
+		return "web-server"
+	case common.AssetTypeExternalEntity:
+		return "client-system"
+	}
+	return "unknown-technology"
+}
+
+// assetMachine maps the source of the asset to the Threagile machine it runs on
+func assetMachine(asset common.Asset) string {
+	switch asset.Source {
+	case common.DataSourceDockerCompose, common.DataSourceKubernetes:
+		return "container"
+	}
+	if asset.Type == common.AssetTypeExternalEntity {
+		return "physical"
+	}
+	return "virtual"
+}
+
+func communicationLink(dataflow common.DataFlow, target string) CommunicationLink {
+	description := dataflow.Name
+	if dataflow.Inferred {
+		description += " (inferred)"
+	}
+	link := CommunicationLink{
+		Target:             target,
+		Description:        description,
+		Protocol:           linkProtocol(dataflow.Protocol, dataflow.Encrypted),
+		Authentication:     "none",
+		Authorization:      "none",
+		Tags:               []string{},
+		Usage:              "business",
+		DataAssetsSent:     []string{applicationDataID},
+		DataAssetsReceived: []string{},
+	}
+	if dataflow.Bidirectional {
+		link.DataAssetsReceived = []string{applicationDataID}
+	}
+	return link
+}
+
+// linkProtocols maps the protocols of dataflows to the unencrypted and the encrypted Threagile protocol
+var linkProtocols = map[string][2]string{
+	"http":       {"http", "https"},
+	"https":      {"http", "https"},
+	"ws":         {"ws", "wss"},
+	"wss":        {"ws", "wss"},
+	"grpc":       {"binary", "binary-encrypted"},
+	"postgresql": {"sql-access-protocol", "sql-access-protocol-encrypted"},
+	"mysql":      {"sql-access-protocol", "sql-access-protocol-encrypted"},
+	"mssql":      {"sql-access-protocol", "sql-access-protocol-encrypted"},
+	"mongodb":    {"nosql-access-protocol", "nosql-access-protocol-encrypted"},
+	"redis":      {"nosql-access-protocol", "nosql-access-protocol-encrypted"},
+	"memcached":  {"nosql-access-protocol", "nosql-access-protocol-encrypted"},
+	"amqp":       {"binary", "binary-encrypted"},
+	"kafka":      {"binary", "binary-encrypted"},
+	"nats":       {"binary", "binary-encrypted"},
+	"mqtt":       {"mqtt", "mqtt"},
+	"ldap":       {"ldap", "ldaps"},
+	"ldaps":      {"ldap", "ldaps"},
+	"ftp":        {"ftp", "ftps"},
+	"ftps":       {"ftp", "ftps"},
+	"sftp":       {"sftp", "sftp"},
+	"smtp":       {"smtp", "smtp-encrypted"},
+	"ssh":        {"ssh", "ssh"},
+	"tcp":        {"binary", "binary-encrypted"},
+	"udp":        {"binary", "binary-encrypted"},
+	// protocols of Threagile models, without the "-encrypted" suffix
+	"sql-access-protocol":        {"sql-access-protocol", "sql-access-protocol-encrypted"},
+	"nosql-access-protocol":      {"nosql-access-protocol", "nosql-access-protocol-encrypted"},
+	"reverse-proxy-web-protocol": {"reverse-proxy-web-protocol", "reverse-proxy-web-protocol-encrypted"},
+	"jdbc":                       {"jdbc", "jdbc-encrypted"},
+	"odbc":                       {"odbc", "odbc-encrypted"},
+	"binary":                     {"binary", "binary-encrypted"},
+	"text":                       {"text", "text-encrypted"},
+	"pop3":                       {"pop3", "pop3-encrypted"},
+	"imap":                       {"imap", "imap-encrypted"},
+	"smb":                        {"smb", "smb-encrypted"},
+	"iiop":                       {"iiop", "iiop-encrypted"},
+	"jrmp":                       {"jrmp", "jrmp-encrypted"},
+	"ssh-tunnel":                 {"ssh-tunnel", "ssh-tunnel"},
+	"scp":                        {"scp", "scp"},
+	"jms":                        {"jms", "jms"},
+	"nfs":                        {"nfs", "nfs"},
+	"nrpe":                       {"nrpe", "nrpe"},
+	"xmpp":                       {"xmpp", "xmpp"},
+	"local-file-access":          {"local-file-access", "local-file-access"},
+	"in-process-library-call":    {"in-process-library-call", "in-process-library-call"},
+	"container-spawning":         {"container-spawning", "container-spawning"},
+}
+
+// linkProtocol maps the protocol of a dataflow to a Threagile protocol. Suffixes like in "postgresql+tls" are ignored.
+func linkProtocol(protocol string, encrypted bool) string {
+	name, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(protocol)), "+")
+	protocols, ok := linkProtocols[name]
+	if !ok {
+		return "unknown-protocol"
+	}
+	if encrypted {
+		return protocols[1]
+	}
+	return protocols[0]
+}
+
+// ids hands out unique IDs and titles
+type ids map[string]bool
+
+func newIDs() ids {
+	return ids{}
+}
+
+// unique returns the name, or the name with a number if it has already been handed out
+func (i ids) unique(name string) string {
+	result := name
+	for n := 2; i[result]; n++ {
+		result = fmt.Sprintf("%s-%d", name, n)
+	}
+	i[result] = true
+	return result
+}
+
+// slug turns a display name into a Threagile ID, which may only contain letters, digits and dashes
+func slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	if b.Len() == 0 {
+		return "unnamed"
+	}
+	return b.String()
+}
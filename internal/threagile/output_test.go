@@ -0,0 +1,181 @@
+package threagile
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+	"gopkg.in/yaml.v3"
+)
+
+// recordingChangelog keeps all entries for assertions
+type recordingChangelog struct {
+	entries *[]string
+}
+
+func (rc recordingChangelog) AddEntry(entry string) {
+	*rc.entries = append(*rc.entries, entry)
+}
+
+func newTestOutput(path string) (*ThreagileOutput, *[]string) {
+	entries := []string{}
+	return NewThreagileOutput(path, recordingChangelog{entries: &entries}, slog.Default()), &entries
+}
+
+// composeModel is a docker compose project with a web server on the frontend network, an api on both networks
+// and a database on the backend network, reachable from the internet
+func composeModel() *common.ThreatModel {
+	user := common.Asset{ID: "user", DisplayName: "Internet / External User", Type: common.AssetTypeExternalEntity, Source: common.DataSourceDockerCompose}
+	web := common.Asset{ID: "web", DisplayName: "web", Type: common.AssetTypeWebserver, Source: common.DataSourceDockerCompose}
+	api := common.Asset{ID: "api", DisplayName: "api", Type: common.AssetTypeApplication, Source: common.DataSourceDockerCompose}
+	db := common.Asset{ID: "db", DisplayName: "db", Type: common.AssetTypeDatabase, Source: common.DataSourceDockerCompose, StoresCredentials: true}
+	return &common.ThreatModel{
+		Assets: []common.Asset{user, web, api, db},
+		DataFlows: []common.DataFlow{
+			{ID: "1", Name: "public", Source: "Internet / External User", Target: "web", Protocol: "https", Encrypted: true, PublicNetwork: true},
+			{ID: "2", Name: "rest", Source: "web", Target: "api", Protocol: "http"},
+			{ID: "3", Name: "sql", Source: "api", Target: "db", Protocol: "postgresql", Bidirectional: true},
+			{ID: "4", Name: "missing", Source: "api", Target: "queue", Protocol: "amqp"},
+		},
+		Boundaries: []common.TrustBoundary{
+			{ID: "internet", DisplayName: "Internet", ContainedAssets: []string{"user"}, Source: common.DataSourceDockerCompose},
+			{ID: "default", DisplayName: "default", ContainedAssets: []string{"web", "api", "db"}, Source: common.DataSourceDockerCompose,
+				Extra: map[string]any{"initial-description": "General trust boundary for docker compose file 'docker-compose.yml'"}},
+			{ID: "frontend", DisplayName: "frontend", ContainedAssets: []string{"web", "api"}, Source: common.DataSourceDockerCompose},
+			{ID: "backend", DisplayName: "backend", ContainedAssets: []string{"api", "db"}, Source: common.DataSourceDockerCompose},
+		},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	const path = "testdata/testoutput_threagile.yaml"
+	defer os.Remove(path)
+
+	output, entries := newTestOutput(path)
+	require.NoError(t, output.Generate(composeModel()))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var model Model
+	require.NoError(t, yaml.Unmarshal(content, &model))
+
+	assert.Equal(t, "1.0.0", model.ThreagileVersion)
+	assert.Len(t, model.TechnicalAssets, 4)
+	assert.Contains(t, model.DataAssets, "Credentials")
+
+	user := model.TechnicalAssets["Internet / External User"]
+	assert.Equal(t, "internet-external-user", user.ID)
+	assert.Equal(t, "external-entity", user.Type)
+	assert.True(t, user.Internet)
+	assert.Equal(t, CommunicationLink{
+		Target: "web", Description: "public", Protocol: "https", Authentication: "none", Authorization: "none",
+		Tags: []string{}, Usage: "business", DataAssetsSent: []string{"application-data"}, DataAssetsReceived: []string{},
+	}, user.CommunicationLinks["public"])
+
+	db := model.TechnicalAssets["db"]
+	assert.Equal(t, "datastore", db.Type)
+	assert.Equal(t, "database", db.Technology)
+	assert.Equal(t, "container", db.Machine)
+	assert.Equal(t, "confidential", db.Confidentiality)
+	assert.Equal(t, []string{"application-data", "credentials"}, db.DataAssetsStored)
+
+	api := model.TechnicalAssets["api"]
+	require.Len(t, api.CommunicationLinks, 1)
+	assert.Equal(t, "db", api.CommunicationLinks["sql"].Target)
+	assert.Equal(t, "sql-access-protocol", api.CommunicationLinks["sql"].Protocol)
+	assert.Equal(t, []string{"application-data"}, api.CommunicationLinks["sql"].DataAssetsReceived)
+	assert.Equal(t, "http", model.TechnicalAssets["web"].CommunicationLinks["rest"].Protocol)
+
+	// the internet is outside of all trust boundaries
+	assert.NotContains(t, model.TrustBoundaries, "Internet")
+	require.Len(t, model.TrustBoundaries, 3)
+	assert.Equal(t, "network-virtual-lan", model.TrustBoundaries["default"].Type)
+	assert.Equal(t, "General trust boundary for docker compose file 'docker-compose.yml'", model.TrustBoundaries["default"].Description)
+	assert.ElementsMatch(t, []string{"frontend", "backend"}, model.TrustBoundaries["default"].TrustBoundariesNested)
+	assert.Equal(t, []string{"web"}, model.TrustBoundaries["frontend"].TechnicalAssetsInside)
+	assert.Equal(t, []string{"api", "db"}, model.TrustBoundaries["backend"].TechnicalAssetsInside)
+	assert.Empty(t, model.TrustBoundaries["default"].TechnicalAssetsInside)
+
+	assert.Contains(t, *entries, "Dataflow 'missing' could not be added, because 'api' or 'queue' was not found")
+	assert.Contains(t, *entries, "Asset 'api' has been put into trust boundary 'backend' only, it is also in 'frontend'")
+}
+
+func TestGenerate_UniqueIDs(t *testing.T) {
+	output, _ := newTestOutput("")
+	model := output.buildModel(&common.ThreatModel{
+		Assets: []common.Asset{
+			{ID: "a", DisplayName: "web"},
+			{ID: "b", DisplayName: "web", Extra: map[string]any{"InputFile": "other.yml"}},
+			{ID: "c", DisplayName: "Web!"},
+		},
+		DataFlows: []common.DataFlow{
+			{ID: "1", Name: "call", Source: "web", Target: "Web!", Extra: map[string]any{"InputFile": "other.yml"}},
+			{ID: "2", Name: "call", Source: "web", Target: "Web!", Extra: map[string]any{"InputFile": "other.yml"}},
+		},
+		Boundaries: []common.TrustBoundary{{ID: "net", DisplayName: "web", ContainedAssets: []string{"a"}}},
+	})
+
+	ids := []string{}
+	for _, asset := range model.TechnicalAssets {
+		ids = append(ids, asset.ID)
+	}
+	assert.ElementsMatch(t, []string{"web", "web-2", "web-3"}, ids)
+	assert.Equal(t, "web-4", model.TrustBoundaries["web"].ID)
+	// the flows start at the asset of their input file
+	assert.Len(t, model.TechnicalAssets["web-2"].CommunicationLinks, 2)
+	assert.Contains(t, model.TechnicalAssets["web-2"].CommunicationLinks, "call (2)")
+}
+
+func TestLinkProtocol(t *testing.T) {
+	tests := []struct {
+		protocol  string
+		encrypted bool
+		expected  string
+	}{
+		{"http", false, "http"},
+		{"http", true, "https"},
+		{"HTTPS", true, "https"},
+		{"postgresql+tls", true, "sql-access-protocol-encrypted"},
+		{"redis", false, "nosql-access-protocol"},
+		{"grpc", true, "binary-encrypted"},
+		{"tcp", false, "binary"},
+		{"ldap", true, "ldaps"},
+		{"", false, "unknown-protocol"},
+		{"carrier-pigeon", true, "unknown-protocol"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, linkProtocol(tt.protocol, tt.encrypted), tt.protocol)
+	}
+}
+
+func TestBoundaryType(t *testing.T) {
+	terraform := func(terraformType string) common.TrustBoundary {
+		return common.TrustBoundary{Source: common.DataSourceTerraform, Extra: map[string]any{"TerraformType": terraformType}}
+	}
+	tests := []struct {
+		boundary common.TrustBoundary
+		expected string
+	}{
+		{common.TrustBoundary{Source: common.DataSourceDockerCompose}, "network-virtual-lan"},
+		{common.TrustBoundary{Source: common.DataSourceKubernetes}, "network-policy-namespace-isolation"},
+		{terraform("aws_vpc"), "network-cloud-provider"},
+		{terraform("azurerm_virtual_network"), "network-cloud-provider"},
+		{terraform("aws_subnet"), "network-virtual-lan"},
+		{terraform("google_compute_subnetwork"), "network-virtual-lan"},
+		{terraform("aws_security_group"), "network-cloud-security-group"},
+		{terraform("azurerm_network_security_group"), "network-cloud-security-group"},
+		{common.TrustBoundary{Source: common.DataSourceThreatDragon}, "network-on-prem"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, boundaryType(tt.boundary), common.GetOr(tt.boundary.Extra, "TerraformType", tt.boundary.Source.ShortString()))
+	}
+}
+
+func TestSlug(t *testing.T) {
+	assert.Equal(t, "internet-external-user", slug("Internet / External User"))
+	assert.Equal(t, "my-app-v2", slug("  my_app  V2 "))
+	assert.Equal(t, "unnamed", slug("?!"))
+}
@@ -0,0 +1,43 @@
+package threagile
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ThreagileParser parses threagile.yaml files
+type ThreagileParser struct {
+	filePath string
+	logger   *slog.Logger
+}
+
+// NewThreagileParser creates a new instance of ThreagileParser
+func NewThreagileParser(filePath string, logger *slog.Logger) *ThreagileParser {
+	return &ThreagileParser{
+		filePath: filePath,
+		logger:   logger.With("package", "threagile", "component", "ThreagileParser"),
+	}
+}
+
+// Parse reads the Threagile model. Included files are not resolved.
+func (p *ThreagileParser) Parse() (*Model, error) {
+	p.logger.Debug("Parsing threagile file", "filePath", p.filePath)
+	content, err := os.ReadFile(p.filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Threagile file: %w", err)
+	}
+
+	var model Model
+	if err := yaml.Unmarshal(content, &model); err != nil {
+		return nil, fmt.Errorf("failed to parse Threagile file: %w", err)
+	}
+	if model.ThreagileVersion == "" {
+		return nil, fmt.Errorf("file %s is not a Threagile model: threagile_version is missing", p.filePath)
+	}
+
+	p.logger.Debug("Parsed threagile file", "version", model.ThreagileVersion, "technicalAssetCount", len(model.TechnicalAssets))
+	return &model, nil
+}
@@ -0,0 +1,32 @@
+package threagile
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	model, err := NewThreagileParser("testdata/threagile.yaml", slog.Default()).Parse()
+	require.NoError(t, err)
+
+	assert.Equal(t, "1.0.0", model.ThreagileVersion)
+	assert.Len(t, model.TechnicalAssets, 4)
+	assert.Equal(t, "orders-db", model.TechnicalAssets["Shop Backend"].CommunicationLinks["Order Queries"].Target)
+	assert.Equal(t, []string{"backend-subnet"}, model.TrustBoundaries["Cloud Network"].TrustBoundariesNested)
+	assert.Len(t, model.IndividualRiskCategories["Missing Order Audit"].RisksIdentified, 3)
+	assert.Equal(t, "mitigated", model.RiskTracking["missing-order-audit@shop-backend"].Status)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := NewThreagileParser("testdata/missing.yaml", slog.Default()).Parse()
+	assert.Error(t, err)
+
+	path := t.TempDir() + "/compose.yaml"
+	require.NoError(t, os.WriteFile(path, []byte("services:\n  web:\n    image: nginx\n"), 0644))
+	_, err = NewThreagileParser(path, slog.Default()).Parse()
+	assert.ErrorContains(t, err, "not a Threagile model")
+}
@@ -0,0 +1,88 @@
+package threatdragon
+
+// point is a point of a curve in the diagram
+type point struct {
+	x, y float64
+}
+
+// isBoundaryCurve checks if the cell is a trust boundary drawn as a line instead of a box
+func isBoundaryCurve(cell Cell) bool {
+	return cell.Data.Type == "tm.Boundary"
+}
+
+// curvePoints returns the polyline of a flow or boundary curve from its source over its vertices to its target.
+// Ends that are connected to a cell start at the center of that cell.
+// It returns false if an end has neither a connected cell nor coordinates.
+func curvePoints(cell Cell, cells []Cell) ([]point, bool) {
+	source, ok := endPoint(cell.Source, cells)
+	if !ok {
+		return nil, false
+	}
+	target, ok := endPoint(cell.Target, cells)
+	if !ok {
+		return nil, false
+	}
+
+	points := []point{source}
+	if cell.Vertices != nil {
+		for _, vertex := range *cell.Vertices {
+			points = append(points, point{vertex.X, vertex.Y})
+		}
+	}
+	return append(points, target), true
+}
+
+// endPoint returns the point of a curve end
+func endPoint(end *Source, cells []Cell) (point, bool) {
+	if end == nil {
+		return point{}, false
+	}
+	if end.Cell != nil {
+		for _, cell := range cells {
+			if cell.ID == *end.Cell && cell.Position != nil && cell.Size != nil {
+				return point{cell.Position.X + cell.Size.Width/2, cell.Position.Y + cell.Size.Height/2}, true
+			}
+		}
+		return point{}, false
+	}
+	if end.X == nil || end.Y == nil {
+		return point{}, false
+	}
+	return point{float64(*end.X), float64(*end.Y)}, true
+}
+
+// polylinesCross checks if any segment of the first polyline crosses any segment of the second one.
+// Curves are smoothed in Threat Dragon, so the straight segments between the vertices are an approximation.
+func polylinesCross(a, b []point) bool {
+	for i := 1; i < len(a); i++ {
+		for j := 1; j < len(b); j++ {
+			if segmentsCross(a[i-1], a[i], b[j-1], b[j]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// segmentsCross checks if the segments p1-p2 and q1-q2 intersect, including touching ends
+func segmentsCross(p1, p2, q1, q2 point) bool {
+	d1 := orientation(q1, q2, p1)
+	d2 := orientation(q1, q2, p2)
+	d3 := orientation(p1, p2, q1)
+	d4 := orientation(p1, p2, q2)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	return (d1 == 0 && onSegment(q1, q2, p1)) || (d2 == 0 && onSegment(q1, q2, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, q1)) || (d4 == 0 && onSegment(p1, p2, q2))
+}
+
+// orientation returns the cross product of a-b and a-c, which is positive if c is left of the line from a to b
+func orientation(a, b, c point) float64 {
+	return (b.x-a.x)*(c.y-a.y) - (b.y-a.y)*(c.x-a.x)
+}
+
+// onSegment checks if c, which is on the line through a and b, lies between a and b
+func onSegment(a, b, c point) bool {
+	return min(a.x, b.x) <= c.x && c.x <= max(a.x, b.x) && min(a.y, b.y) <= c.y && c.y <= max(a.y, b.y)
+}
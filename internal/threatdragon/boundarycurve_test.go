@@ -0,0 +1,54 @@
+package threatdragon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentsCross(t *testing.T) {
+	tests := []struct {
+		name           string
+		p1, p2, q1, q2 point
+		expected       bool
+	}{
+		{"crossing", point{0, 0}, point{10, 10}, point{0, 10}, point{10, 0}, true},
+		{"parallel", point{0, 0}, point{10, 0}, point{0, 5}, point{10, 5}, false},
+		{"apart", point{0, 0}, point{1, 1}, point{5, 0}, point{5, 10}, false},
+		{"touching end", point{0, 0}, point{5, 5}, point{5, 0}, point{5, 10}, true},
+		{"collinear overlapping", point{0, 0}, point{10, 0}, point{5, 0}, point{15, 0}, true},
+		{"collinear apart", point{0, 0}, point{4, 0}, point{5, 0}, point{15, 0}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, segmentsCross(tt.p1, tt.p2, tt.q1, tt.q2))
+		})
+	}
+}
+
+func TestPolylinesCross(t *testing.T) {
+	boundary := []point{{50, 0}, {60, 50}, {50, 100}}
+	assert.True(t, polylinesCross([]point{{0, 20}, {100, 20}}, boundary))
+	assert.True(t, polylinesCross([]point{{0, 90}, {30, 90}, {100, 90}}, boundary))
+	assert.False(t, polylinesCross([]point{{0, 20}, {40, 80}}, boundary))
+	assert.False(t, polylinesCross([]point{{0, 20}}, boundary))
+}
+
+func TestCurvePoints(t *testing.T) {
+	cells := []Cell{{ID: "asset", Position: &VertexClass{X: 100, Y: 200}, Size: &Size{Width: 40, Height: 60}}}
+	x, y := int64(10), int64(20)
+	curve := Cell{
+		Source:   &Source{X: &x, Y: &y},
+		Target:   &Source{Cell: stringPtr("asset")},
+		Vertices: &[]VertexClass{{X: 50, Y: 60}},
+	}
+
+	points, ok := curvePoints(curve, cells)
+	require.True(t, ok)
+	assert.Equal(t, []point{{10, 20}, {50, 60}, {120, 230}}, points)
+
+	curve.Target = &Source{Cell: stringPtr("missing")}
+	_, ok = curvePoints(curve, cells)
+	assert.False(t, ok)
+}
@@ -0,0 +1,299 @@
+package threatdragon
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// DiagramStrategy decides how the assets of a new model are split into diagrams
+type DiagramStrategy int
+
+const (
+	// DiagramSingle draws all assets into one diagram
+	DiagramSingle DiagramStrategy = iota
+	// DiagramPerInputFile draws one diagram per input file, e.g. per docker compose project
+	DiagramPerInputFile
+	// DiagramPerTrustBoundary draws one diagram per trust boundary. Assets of nested boundaries go to the innermost one.
+	DiagramPerTrustBoundary
+)
+
+// ParseDiagramStrategy converts the name of a strategy as given on the command line
+func ParseDiagramStrategy(name string) (DiagramStrategy, error) {
+	switch name {
+	case "", "single":
+		return DiagramSingle, nil
+	case "per-file":
+		return DiagramPerInputFile, nil
+	case "per-boundary":
+		return DiagramPerTrustBoundary, nil
+	}
+	return DiagramSingle, fmt.Errorf("unknown diagram strategy '%s', expected single, per-file or per-boundary", name)
+}
+
+// singleDiagramTitle is the title of the diagram of a model that is not split
+const singleDiagramTitle = "new diagram 0"
+
+// unassignedDiagramTitle is the title of the diagram of the assets without an input file or trust boundary
+const unassignedDiagramTitle = "Other assets"
+
+// diagramGroup holds the assets that are drawn in one diagram
+type diagramGroup struct {
+	title  string
+	assets []common.Asset
+}
+
+// diagramReference is an asset of another diagram that a flow of the diagram is connected to
+type diagramReference struct {
+	asset   common.Asset
+	diagram string
+}
+
+// splitDiagrams splits the assets of a new model into diagrams. There is always at least one diagram.
+func splitDiagrams(model *common.ThreatModel, strategy DiagramStrategy) []diagramGroup {
+	var group func(asset common.Asset) (key, title string)
+	switch strategy {
+	case DiagramPerInputFile:
+		group = func(asset common.Asset) (string, string) {
+			file := common.GetOr(asset.Extra, "InputFile", "")
+			return file, file
+		}
+	case DiagramPerTrustBoundary:
+		group = func(asset common.Asset) (string, string) {
+			boundary, ok := innermostBoundary(asset.ID, model.Boundaries)
+			if !ok {
+				return "", ""
+			}
+			return boundary.ID, boundary.DisplayName
+		}
+	default:
+		return []diagramGroup{{title: singleDiagramTitle, assets: model.Assets}}
+	}
+
+	groups := make([]diagramGroup, 0)
+	index := make(map[string]int)
+	unassigned := make([]common.Asset, 0)
+	for _, asset := range model.Assets {
+		key, title := group(asset)
+		if key == "" {
+			unassigned = append(unassigned, asset)
+			continue
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, diagramGroup{title: title})
+		}
+		groups[i].assets = append(groups[i].assets, asset)
+	}
+	if len(unassigned) > 0 || len(groups) == 0 {
+		groups = append(groups, diagramGroup{title: unassignedDiagramTitle, assets: unassigned})
+	}
+	return groups
+}
+
+// innermostBoundary returns the boundary with the fewest assets among the boundaries that contain the asset
+func innermostBoundary(assetID string, boundaries []common.TrustBoundary) (common.TrustBoundary, bool) {
+	var innermost common.TrustBoundary
+	found := false
+	for _, boundary := range boundaries {
+		if !slices.Contains(boundary.ContainedAssets, assetID) {
+			continue
+		}
+		if !found || len(boundary.ContainedAssets) < len(innermost.ContainedAssets) {
+			innermost, found = boundary, true
+		}
+	}
+	return innermost, found
+}
+
+// diagramBoundaries returns the boundaries drawn in the diagram with the given index, reduced to the assets of that diagram.
+// Boundaries without any asset in the model are drawn in the first diagram.
+func diagramBoundaries(boundaries []common.TrustBoundary, assetDiagrams map[string]int, i int) []common.TrustBoundary {
+	result := make([]common.TrustBoundary, 0, len(boundaries))
+	for _, boundary := range boundaries {
+		contained := make([]string, 0, len(boundary.ContainedAssets))
+		placed := false
+		for _, id := range boundary.ContainedAssets {
+			d, ok := assetDiagrams[id]
+			placed = placed || ok
+			if ok && d == i {
+				contained = append(contained, id)
+			}
+		}
+		switch {
+		case len(contained) > 0:
+			boundary.ContainedAssets = contained
+			result = append(result, boundary)
+		case !placed && i == 0:
+			result = append(result, boundary)
+		}
+	}
+	return result
+}
+
+// diagramDataflows returns the dataflows that are drawn in the diagram with the given index and the references to
+// the assets of other diagrams that they are connected to. Flows are drawn in the diagram of their source.
+// Flows with an unknown source belong to the first diagram. Assets that are already referenced in the diagram are skipped.
+func diagramDataflows(model *common.ThreatModel, assetDiagrams map[string]int, titles []string, i int, referenced []string) ([]common.DataFlow, []diagramReference) {
+	dataflows := make([]common.DataFlow, 0)
+	references := make([]diagramReference, 0)
+	for _, dataflow := range model.DataFlows {
+		source, ok := assetDiagrams[common.EndpointAsset(model.Assets, dataflow, dataflow.Source).ID]
+		if !ok {
+			source = 0
+		}
+		if source != i {
+			continue
+		}
+		dataflows = append(dataflows, dataflow)
+
+		target := common.EndpointAsset(model.Assets, dataflow, dataflow.Target)
+		if d, ok := assetDiagrams[target.ID]; ok && d != i && !slices.Contains(referenced, target.ID) {
+			references = append(references, diagramReference{asset: target, diagram: titles[d]})
+			referenced = append(referenced, target.ID)
+		}
+	}
+	return dataflows, references
+}
+
+// generateReferenceCells generates a cell for every referenced asset. It looks like the asset, is out of scope
+// and names the diagram the asset is drawn in. References are tagged with the ID of their asset and are not read as assets.
+func (tdo *ThreatdragonOutput) generateReferenceCells(references []diagramReference, placementLogic cellPlacer) ([]Cell, error) {
+	cells := make([]Cell, 0, len(references))
+	for _, reference := range references {
+		cell, err := tdo.generatePlacedCell(common.Asset{
+			ID:          referenceKey(reference.asset.ID),
+			DisplayName: reference.asset.DisplayName,
+			Type:        reference.asset.Type,
+		}, placementLogic)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate reference cell: %w", err)
+		}
+
+		reason := fmt.Sprintf("Defined in diagram '%s'", reference.diagram)
+		description := referenceTag(reference.asset.ID) + " " + reason
+		cell.Data.Description = &description
+		cell.Data.OutOfScope = boolPtr(true)
+		cell.Data.ReasonOutOfScope = &reason
+		for _, body := range []*Body{cell.Attrs.Body, cell.Attrs.TopLine, cell.Attrs.BottomLine} {
+			if body != nil {
+				body.StrokeDasharray = Nullable[string]{Set: true, Present: true, Value: "4 3"}
+			}
+		}
+		tdo.logger.Debug("Generated reference to an asset of another diagram", "name", reference.asset.DisplayName, "diagram", reference.diagram)
+		cells = append(cells, *cell)
+	}
+	return cells, nil
+}
+
+// updateReferenceCell renames a reference cell after its asset
+func (tdo *ThreatdragonOutput) updateReferenceCell(cell Cell, asset common.Asset) Cell {
+	if valueOr(cell.Data.Name, "") == asset.DisplayName {
+		return cell
+	}
+	tdo.logger.Debug("Referenced asset has been renamed", "prevName", valueOr(cell.Data.Name, ""), "newName", asset.DisplayName)
+	cell.Data.Name = &asset.DisplayName
+	if cell.Attrs != nil && cell.Attrs.Text != nil {
+		cell.Attrs.Text.Text = asset.DisplayName
+	}
+	return cell
+}
+
+// newAssetDiagrams decides on which diagram of an existing model each new asset is drawn. An asset goes to the diagram
+// that already holds one of its trust boundaries, an asset it exchanges data with or an asset of the same input file.
+// Assets related to other new assets follow them. All other assets go to the first diagram.
+func newAssetDiagrams(model *common.ThreatModel, newAssets []common.Asset, assetDiagrams, boundaryDiagrams map[string]int) {
+	pending := slices.Clone(newAssets)
+	for len(pending) > 0 {
+		remaining := make([]common.Asset, 0, len(pending))
+		for _, asset := range pending {
+			if d, ok := relatedDiagram(model, asset, assetDiagrams, boundaryDiagrams); ok {
+				assetDiagrams[asset.ID] = d
+			} else {
+				remaining = append(remaining, asset)
+			}
+		}
+		if len(remaining) == len(pending) {
+			break
+		}
+		pending = remaining
+	}
+	for _, asset := range pending {
+		assetDiagrams[asset.ID] = 0
+	}
+}
+
+// relatedDiagram returns the diagram of the first trust boundary, dataflow partner or asset of the same input file
+// of the asset that is already placed
+func relatedDiagram(model *common.ThreatModel, asset common.Asset, assetDiagrams, boundaryDiagrams map[string]int) (int, bool) {
+	for _, boundary := range model.Boundaries {
+		if d, ok := boundaryDiagrams[boundary.ID]; ok && slices.Contains(boundary.ContainedAssets, asset.ID) {
+			return d, true
+		}
+	}
+
+	for _, dataflow := range model.DataFlows {
+		partner := dataflow.Target
+		if dataflow.Target == asset.DisplayName {
+			partner = dataflow.Source
+		} else if dataflow.Source != asset.DisplayName {
+			continue
+		}
+		if d, ok := assetDiagrams[common.EndpointAsset(model.Assets, dataflow, partner).ID]; ok {
+			return d, true
+		}
+	}
+
+	if file := common.GetOr(asset.Extra, "InputFile", ""); file != "" {
+		for _, other := range model.Assets {
+			if d, ok := assetDiagrams[other.ID]; ok && common.GetOr(other.Extra, "InputFile", "") == file {
+				return d, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// boundaryDiagram returns the diagram that holds most of the assets of a new trust boundary, or the first diagram
+func boundaryDiagram(boundary common.TrustBoundary, assetDiagrams map[string]int) int {
+	counts := make(map[int]int)
+	best := 0
+	for _, id := range boundary.ContainedAssets {
+		d, ok := assetDiagrams[id]
+		if !ok {
+			continue
+		}
+		counts[d]++
+		if counts[d] > counts[best] || (counts[d] == counts[best] && d < best) {
+			best = d
+		}
+	}
+	return best
+}
+
+// referenceKey is the key of a reference cell in the placement, which differs from the key of its asset
+func referenceKey(assetID string) string {
+	return "reference:" + assetID
+}
+
+// referenceTag marks a cell as a reference to the asset with the given ID
+func referenceTag(assetID string) string {
+	return fmt.Sprintf("#AnalyzerRef:%s#", assetID)
+}
+
+var referencePattern = regexp.MustCompile(fmt.Sprintf(`#AnalyzerRef:([0-9a-fA-F]{%d})#`, common.MaxIDHashLength))
+
+// extractReference extracts the ID of the referenced asset from the description of a reference cell
+func extractReference(description *string) string {
+	if description == nil {
+		return ""
+	}
+	if matches := referencePattern.FindStringSubmatch(*description); len(matches) > 1 {
+		return matches[1]
+	}
+	return ""
+}
@@ -0,0 +1,219 @@
+package threatdragon
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+func TestParseDiagramStrategy(t *testing.T) {
+	for name, expected := range map[string]DiagramStrategy{
+		"":             DiagramSingle,
+		"single":       DiagramSingle,
+		"per-file":     DiagramPerInputFile,
+		"per-boundary": DiagramPerTrustBoundary,
+	} {
+		strategy, err := ParseDiagramStrategy(name)
+		require.NoError(t, err)
+		assert.Equal(t, expected, strategy, name)
+	}
+
+	_, err := ParseDiagramStrategy("per-asset")
+	assert.Error(t, err)
+}
+
+func TestSplitDiagrams(t *testing.T) {
+	inFile := func(id, name, file string) common.Asset {
+		return common.Asset{ID: id, DisplayName: name, Extra: map[string]any{"InputFile": file}}
+	}
+	model := &common.ThreatModel{
+		Assets: []common.Asset{
+			inFile("1", "web", "a/docker-compose.yml"),
+			inFile("2", "db", "b/docker-compose.yml"),
+			inFile("3", "api", "a/docker-compose.yml"),
+			{ID: "4", DisplayName: "user"},
+		},
+		Boundaries: []common.TrustBoundary{
+			{ID: "outer", DisplayName: "outer", ContainedAssets: []string{"1", "2", "3"}},
+			{ID: "inner", DisplayName: "inner", ContainedAssets: []string{"2"}},
+		},
+	}
+	names := func(groups []diagramGroup) map[string][]string {
+		result := map[string][]string{}
+		for _, group := range groups {
+			result[group.title] = []string{}
+			for _, asset := range group.assets {
+				result[group.title] = append(result[group.title], asset.DisplayName)
+			}
+		}
+		return result
+	}
+
+	single := splitDiagrams(model, DiagramSingle)
+	require.Len(t, single, 1)
+	assert.Equal(t, singleDiagramTitle, single[0].title)
+	assert.Len(t, single[0].assets, 4)
+
+	assert.Equal(t, map[string][]string{
+		"a/docker-compose.yml": {"web", "api"},
+		"b/docker-compose.yml": {"db"},
+		unassignedDiagramTitle: {"user"},
+	}, names(splitDiagrams(model, DiagramPerInputFile)))
+
+	// assets of nested boundaries go to the innermost one
+	assert.Equal(t, map[string][]string{
+		"outer":                {"web", "api"},
+		"inner":                {"db"},
+		unassignedDiagramTitle: {"user"},
+	}, names(splitDiagrams(model, DiagramPerTrustBoundary)))
+
+	// an empty model still gets a diagram
+	assert.Len(t, splitDiagrams(&common.ThreatModel{}, DiagramPerTrustBoundary), 1)
+}
+
+func TestDiagramBoundaries(t *testing.T) {
+	assetDiagrams := map[string]int{"1": 0, "2": 1}
+	boundaries := []common.TrustBoundary{
+		{ID: "both", ContainedAssets: []string{"1", "2"}},
+		{ID: "second", ContainedAssets: []string{"2"}},
+		{ID: "empty", ContainedAssets: []string{}},
+	}
+
+	first := diagramBoundaries(boundaries, assetDiagrams, 0)
+	require.Len(t, first, 2)
+	assert.Equal(t, common.TrustBoundary{ID: "both", ContainedAssets: []string{"1"}}, first[0])
+	assert.Equal(t, "empty", first[1].ID)
+
+	second := diagramBoundaries(boundaries, assetDiagrams, 1)
+	require.Len(t, second, 2)
+	assert.Equal(t, []string{"2"}, second[0].ContainedAssets)
+	assert.Equal(t, "second", second[1].ID)
+
+	// the boundaries of the model are not changed
+	assert.Equal(t, []string{"1", "2"}, boundaries[0].ContainedAssets)
+}
+
+func TestExtractReference(t *testing.T) {
+	id := "0123456789abcdef0123456789abcdef"
+	assert.Equal(t, id, extractReference(stringPtr(referenceTag(id)+" Defined in diagram 'x'")))
+	assert.Empty(t, extractReference(stringPtr(analyzerIDTag(id))))
+	assert.Empty(t, extractReference(nil))
+}
+
+// TestGenerate_PerInputFile tests that flows to assets of other diagrams are connected to references,
+// which are not read as assets and are kept when the model is updated
+func TestGenerate_PerInputFile(t *testing.T) {
+	const path = "testdata/testoutput_threatdragon_diagrams.json"
+	defer os.Remove(path)
+
+	web := common.Asset{ID: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", DisplayName: "web", Type: common.AssetTypeWebserver, Source: common.DataSourceDockerCompose, Extra: map[string]any{"InputFile": "frontend.yml"}}
+	db := common.Asset{ID: "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", DisplayName: "db", Type: common.AssetTypeDatabase, Source: common.DataSourceDockerCompose, Extra: map[string]any{"InputFile": "backend.yml"}}
+	sql := common.DataFlow{ID: "11111111111111111111111111111111", Name: "sql", Protocol: "postgresql", Source: "web", Target: "db", DataSource: common.DataSourceDockerCompose}
+
+	tdo := NewThreatdragonOutput(path, dummyChangelog{}, slog.Default())
+	tdo.DiagramStrategy = DiagramPerInputFile
+	require.NoError(t, tdo.Generate(&common.ThreatModel{Assets: []common.Asset{web, db}, DataFlows: []common.DataFlow{sql}}))
+
+	project := readProject(t, path)
+	require.Len(t, project.Detail.Diagrams, 2)
+	assert.Equal(t, int64(2), project.Detail.DiagramTop)
+	assert.Equal(t, "frontend.yml", project.Detail.Diagrams[0].Title)
+	assert.Equal(t, "backend.yml", project.Detail.Diagrams[1].Title)
+	assert.Len(t, project.Detail.Diagrams[1].Cells, 1)
+
+	cells := map[string]Cell{}
+	var reference Cell
+	for _, cell := range project.Detail.Diagrams[0].Cells {
+		if extractReference(cell.Data.Description) != "" {
+			reference = cell
+			continue
+		}
+		cells[*cell.Data.Name] = cell
+	}
+	require.NotEmpty(t, reference.ID)
+	assert.Equal(t, "db", *reference.Data.Name)
+	assert.Equal(t, "tm.Store", reference.Data.Type)
+	assert.True(t, *reference.Data.OutOfScope)
+	assert.Equal(t, "Defined in diagram 'backend.yml'", *reference.Data.ReasonOutOfScope)
+	assert.Equal(t, cells["web"].ID, *cells["sql"].Source.Cell)
+	assert.Equal(t, reference.ID, *cells["sql"].Target.Cell)
+
+	model, err := NewThreatDragonInput(path, slog.Default()).Analyze()
+	require.NoError(t, err)
+	require.Len(t, model.Assets, 2)
+	require.Len(t, model.DataFlows, 1)
+	assert.Equal(t, "web", model.DataFlows[0].Source)
+	assert.Equal(t, "db", model.DataFlows[0].Target)
+	assert.Equal(t, db.ID, model.DataFlows[0].Extra["TargetAssetID"])
+
+	cl := &recordingChangelog{entries: &[]string{}}
+	require.NoError(t, NewThreatdragonOutput(path, cl, slog.Default()).Generate(model))
+	assert.Empty(t, *cl.entries)
+	updated := readProject(t, path)
+	assert.Len(t, updated.Detail.Diagrams[0].Cells, len(project.Detail.Diagrams[0].Cells))
+	assert.Len(t, updated.Detail.Diagrams[1].Cells, 1)
+}
+
+// TestGenerate_UpdatePlacesAssetsInRelatedDiagram tests that new assets of an existing model with several diagrams
+// are drawn next to their trust boundary or the assets they exchange data with
+func TestGenerate_UpdatePlacesAssetsInRelatedDiagram(t *testing.T) {
+	const path = "testdata/testoutput_threatdragon_related.json"
+	defer os.Remove(path)
+
+	web := common.Asset{ID: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", DisplayName: "web", Type: common.AssetTypeWebserver, Source: common.DataSourceDockerCompose}
+	db := common.Asset{ID: "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", DisplayName: "db", Type: common.AssetTypeDatabase, Source: common.DataSourceDockerCompose}
+	frontend := common.TrustBoundary{ID: "11111111111111111111111111111111", DisplayName: "frontend", ContainedAssets: []string{web.ID}, Source: common.DataSourceDockerCompose}
+	backend := common.TrustBoundary{ID: "22222222222222222222222222222222", DisplayName: "backend", ContainedAssets: []string{db.ID}, Source: common.DataSourceDockerCompose}
+
+	tdo := NewThreatdragonOutput(path, dummyChangelog{}, slog.Default())
+	tdo.DiagramStrategy = DiagramPerTrustBoundary
+	require.NoError(t, tdo.Generate(&common.ThreatModel{Assets: []common.Asset{web, db}, Boundaries: []common.TrustBoundary{frontend, backend}}))
+
+	model, err := NewThreatDragonInput(path, slog.Default()).Analyze()
+	require.NoError(t, err)
+
+	// the cache joins the backend network and talks to the web server, the proxy only talks to the web server
+	cache := common.Asset{ID: "cccccccccccccccccccccccccccccccc", DisplayName: "cache", Type: common.AssetTypeDatabase, Source: common.DataSourceDockerCompose}
+	proxy := common.Asset{ID: "dddddddddddddddddddddddddddddddd", DisplayName: "proxy", Type: common.AssetTypeWebserver, Source: common.DataSourceDockerCompose}
+	lonely := common.Asset{ID: "eeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee", DisplayName: "lonely", Type: common.AssetTypeApplication, Source: common.DataSourceDockerCompose}
+	model.Assets = append(model.Assets, cache, proxy, lonely)
+	for k := range model.Boundaries {
+		if model.Boundaries[k].ID == backend.ID {
+			model.Boundaries[k].ContainedAssets = append(model.Boundaries[k].ContainedAssets, cache.ID)
+		}
+	}
+	model.DataFlows = append(model.DataFlows,
+		common.DataFlow{ID: "33333333333333333333333333333333", Name: "session", Source: "cache", Target: "web", DataSource: common.DataSourceDockerCompose},
+		common.DataFlow{ID: "44444444444444444444444444444444", Name: "forward", Source: "proxy", Target: "web", DataSource: common.DataSourceDockerCompose},
+	)
+
+	cl := &recordingChangelog{entries: &[]string{}}
+	require.NoError(t, NewThreatdragonOutput(path, cl, slog.Default()).Generate(model))
+
+	project := readProject(t, path)
+	require.Len(t, project.Detail.Diagrams, 2)
+	diagrams := map[string]int{}
+	references := map[int][]string{}
+	for i, diagram := range project.Detail.Diagrams {
+		for _, cell := range diagram.Cells {
+			if extractReference(cell.Data.Description) != "" {
+				references[i] = append(references[i], *cell.Data.Name)
+			} else if cell.Data.Name != nil {
+				diagrams[*cell.Data.Name] = i
+			}
+		}
+	}
+	assert.Equal(t, diagrams["backend"], diagrams["cache"])
+	assert.Equal(t, diagrams["backend"], diagrams["session"])
+	assert.Equal(t, []string{"web"}, references[diagrams["backend"]])
+	assert.Equal(t, diagrams["web"], diagrams["proxy"])
+	assert.Equal(t, diagrams["web"], diagrams["forward"])
+	assert.Equal(t, 0, diagrams["lonely"])
+
+	assert.Contains(t, *cl.entries, "New asset 'cache' has been added from Docker Compose")
+	assert.Contains(t, *cl.entries, "New dataflow 'session' has been added from Docker Compose")
+}
@@ -0,0 +1,266 @@
+package threatdragon
+
+import (
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+const (
+	// incrementalStep is the distance between the positions that are tried for a new cell
+	incrementalStep = 20.0
+	// incrementalGap is the space kept free around every cell
+	incrementalGap = 40.0
+	// incrementalRadius limits how far from its anchor a new cell is placed, in steps
+	incrementalRadius = 60
+)
+
+// placedBox is a trust boundary box of the diagram
+type placedBox struct {
+	boundary common.TrustBoundary
+	rect     *common.Rectangle
+}
+
+// incrementalPlacement places new cells into an existing diagram without moving the cells that are already there.
+// A new asset is placed inside its trust boundary box, close to the assets it has dataflows with. If the box is full,
+// the asset is placed next to it and the box is grown around it later on. Assets without a box or a placed peer,
+// and assets for which no free space is found, are placed in a row below the diagram.
+type incrementalPlacement struct {
+	model     *common.ThreatModel
+	occupied  []*common.Rectangle          // cells that must not be covered
+	positions map[string]*common.Rectangle // asset ID -> cell, of the existing and the placed assets
+	boxes     []*placedBox
+	fallback  *simplePlacement
+}
+
+// newIncrementalPlacement prepares the placement of new cells into a diagram with the given cells.
+// cellAssetIDs maps the cells of assets to their asset, boundaryCells maps the boxes to their boundary.
+func newIncrementalPlacement(cells []Cell, model *common.ThreatModel, cellAssetIDs map[string]string, boundaryCells map[string]common.TrustBoundary) *incrementalPlacement {
+	ip := &incrementalPlacement{
+		model:     model,
+		occupied:  make([]*common.Rectangle, 0, len(cells)),
+		positions: make(map[string]*common.Rectangle),
+		fallback:  newSimplePlacement(),
+	}
+	ip.fallback.determineStartingPoint(cells)
+
+	for _, cell := range cells {
+		if isCurve(cell) || cell.Position == nil || cell.Size == nil {
+			continue
+		}
+		rect := common.NewRectangle(cell.Position.X, cell.Position.Y, cell.Size.Width, cell.Size.Height)
+		if isCellTrustBoudary(&cell) {
+			if boundary, ok := boundaryCells[cell.ID]; ok {
+				ip.boxes = append(ip.boxes, &placedBox{boundary: boundary, rect: rect})
+			}
+			continue
+		}
+		ip.occupied = append(ip.occupied, rect)
+		if id, ok := cellAssetIDs[cell.ID]; ok {
+			ip.positions[id] = rect
+		} else if ref := extractReference(cell.Data.Description); ref != "" {
+			ip.positions[referenceKey(ref)] = rect
+		}
+	}
+	return ip
+}
+
+func (ip *incrementalPlacement) GetPosition(assetID string) (float64, float64) {
+	asset := ip.asset(assetID)
+	width, height := cellSize(asset)
+
+	members := make([]*placedBox, 0)
+	if asset.ID == assetID { // references are not part of any boundary
+		for _, box := range ip.boxes {
+			if slices.Contains(box.boundary.ContainedAssets, assetID) {
+				members = append(members, box)
+			}
+		}
+	}
+	anchor, ok := ip.anchor(asset, members)
+
+	var rect *common.Rectangle
+	if ok {
+		// first inside the boxes of the asset, then next to them, growing the boxes
+		rect, ok = ip.search(anchor, width, height, func(r *common.Rectangle) bool { return ip.fits(r, members, false) })
+		if !ok && len(members) > 0 {
+			rect, ok = ip.search(anchor, width, height, func(r *common.Rectangle) bool { return ip.fits(r, members, true) })
+			if ok {
+				ip.grow(members, rect)
+			}
+		}
+	}
+	if !ok {
+		x, y := ip.fallback.GetPosition(assetID)
+		rect = common.NewRectangle(x, y, width, height)
+	}
+
+	ip.occupied = append(ip.occupied, rect)
+	ip.positions[assetID] = rect
+	return rect.Left, rect.Top
+}
+
+func (ip *incrementalPlacement) GetBoundaryPosition(boundaryID string) (float64, float64, float64, float64) {
+	return ip.fallback.GetBoundaryPosition(boundaryID)
+}
+
+// asset returns the asset with the ID. The asset of a reference is returned for a reference key.
+func (ip *incrementalPlacement) asset(assetID string) common.Asset {
+	id := strings.TrimPrefix(assetID, referenceKey(""))
+	for _, asset := range ip.model.Assets {
+		if asset.ID == id {
+			return asset
+		}
+	}
+	return common.Asset{ID: id}
+}
+
+// anchor returns the point a new cell is placed around: the center of the placed assets it has dataflows with,
+// or the center of its innermost boundary box. It returns false if the asset has neither.
+func (ip *incrementalPlacement) anchor(asset common.Asset, members []*placedBox) (point, bool) {
+	sum, count := point{}, 0
+	for _, dataflow := range ip.model.DataFlows {
+		var peer string
+		switch asset.DisplayName {
+		case dataflow.Source:
+			peer = dataflow.Target
+		case dataflow.Target:
+			peer = dataflow.Source
+		default:
+			continue
+		}
+		id := common.EndpointAsset(ip.model.Assets, dataflow, peer).ID
+		rect, ok := ip.positions[id]
+		if !ok {
+			rect, ok = ip.positions[referenceKey(id)]
+		}
+		if ok {
+			sum.x += rect.Left + rect.Width/2
+			sum.y += rect.Top + rect.Height/2
+			count++
+		}
+	}
+	if count > 0 {
+		return point{sum.x / float64(count), sum.y / float64(count)}, true
+	}
+
+	if len(members) == 0 {
+		return point{}, false
+	}
+	innermost := slices.MinFunc(members, func(a, b *placedBox) int {
+		return int(a.rect.Width*a.rect.Height - b.rect.Width*b.rect.Height)
+	})
+	return point{innermost.rect.Left + innermost.rect.Width/2, innermost.rect.Top + innermost.rect.Height/2}, true
+}
+
+// search tries the positions around the anchor ring by ring and returns the free one closest to the anchor
+func (ip *incrementalPlacement) search(anchor point, width, height float64, valid func(*common.Rectangle) bool) (*common.Rectangle, bool) {
+	left, top := anchor.x-width/2, anchor.y-height/2
+	for radius := 0; radius <= incrementalRadius; radius++ {
+		var best *common.Rectangle
+		bestDistance := math.Inf(1)
+		for dx := -radius; dx <= radius; dx++ {
+			for dy := -radius; dy <= radius; dy++ {
+				if max(abs(dx), abs(dy)) != radius {
+					continue
+				}
+				rect := common.NewRectangle(left+float64(dx)*incrementalStep, top+float64(dy)*incrementalStep, width, height)
+				if rect.Left < 0 || rect.Top < 0 || !valid(rect) {
+					continue
+				}
+				if distance := math.Hypot(float64(dx), float64(dy)); distance < bestDistance {
+					best, bestDistance = rect, distance
+				}
+			}
+		}
+		if best != nil {
+			return best, true
+		}
+	}
+	return nil, false
+}
+
+// fits checks if a new cell at the rectangle keeps its distance to the other cells, lies inside the boxes of its
+// boundaries and outside all other boxes. If the boxes of its boundaries may grow, the cell may lie outside of them
+// as long as the grown boxes would not enclose other cells or touch other boxes.
+func (ip *incrementalPlacement) fits(rect *common.Rectangle, members []*placedBox, grow bool) bool {
+	padded := pad(rect, incrementalGap)
+	for _, other := range ip.occupied {
+		if intersects(padded, other) {
+			return false
+		}
+	}
+
+	for _, box := range ip.boxes {
+		if slices.Contains(members, box) {
+			continue
+		}
+		if intersects(pad(rect, solutionBoundaryOversize), box.rect) {
+			return false
+		}
+	}
+
+	for _, box := range members {
+		inner := pad(box.rect, -solutionBoundaryOversize)
+		if rect.IsContained(inner) {
+			continue
+		}
+		if !grow {
+			return false
+		}
+		grown := unionRectangle(box.rect, pad(rect, solutionBoundaryOversize))
+		for _, other := range ip.occupied {
+			if other.IsContained(grown) && !other.IsContained(box.rect) {
+				return false
+			}
+		}
+		for _, other := range ip.boxes {
+			if !slices.Contains(members, other) && !intersects(box.rect, other.rect) && intersects(grown, other.rect) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// grow enlarges the boxes around the new cell, so that the following cells see the space it takes.
+// The cells of the boxes are fitted around their assets after the placement.
+func (ip *incrementalPlacement) grow(members []*placedBox, rect *common.Rectangle) {
+	for _, box := range members {
+		box.rect = unionRectangle(box.rect, pad(rect, solutionBoundaryOversize))
+	}
+}
+
+// cellSize returns the size of the cell that is generated for the asset
+func cellSize(asset common.Asset) (float64, float64) {
+	info, err := assetTypeToThreatdragonAssetInfo(asset.Type)
+	var cell Cell
+	switch {
+	case err != nil || info.IsActor:
+		cell = actor("", "", nil, 0, 0)
+	case info.IsStore:
+		cell = store("", "", false, nil, 0, 0)
+	default:
+		cell = process("", "", false, nil, 0, 0)
+	}
+	return cell.Size.Width, cell.Size.Height
+}
+
+// pad returns the rectangle enlarged by the margin on every side, or shrunk for a negative margin
+func pad(r *common.Rectangle, margin float64) *common.Rectangle {
+	return common.NewRectangle(r.Left-margin, r.Top-margin, r.Width+2*margin, r.Height+2*margin)
+}
+
+// intersects checks if two rectangles overlap
+func intersects(a, b *common.Rectangle) bool {
+	return a.Left < b.Left+b.Width && b.Left < a.Left+a.Width && a.Top < b.Top+b.Height && b.Top < a.Top+a.Height
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,152 @@
+package threatdragon
+
+import (
+	"log/slog"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// incrementalFixture is a diagram with a web server inside a frontend box and a database without a box
+type incrementalFixture struct {
+	model        *common.ThreatModel
+	cells        []Cell
+	cellAssetIDs map[string]string
+	boxCells     map[string]common.TrustBoundary
+}
+
+func newIncrementalFixture(box *common.Rectangle, newAsset common.Asset, inFrontend bool) incrementalFixture {
+	web := common.Asset{ID: "web", DisplayName: "web", Type: common.AssetTypeWebserver}
+	db := common.Asset{ID: "db", DisplayName: "db", Type: common.AssetTypeDatabase}
+	frontend := common.TrustBoundary{ID: "frontend", DisplayName: "frontend", ContainedAssets: []string{"web"}}
+	if inFrontend {
+		frontend.ContainedAssets = append(frontend.ContainedAssets, newAsset.ID)
+	}
+
+	webCell := process("web", "", true, nil, 50, 50)
+	dbCell := store("db", "", false, nil, 600, 400)
+	boxCell := trustBoundary(box.Left, box.Top, box.Width, box.Height, "frontend", "")
+	return incrementalFixture{
+		model: &common.ThreatModel{
+			Assets:     []common.Asset{web, db, newAsset},
+			Boundaries: []common.TrustBoundary{frontend},
+		},
+		cells:        []Cell{webCell, dbCell, boxCell},
+		cellAssetIDs: map[string]string{webCell.ID: "web", dbCell.ID: "db"},
+		boxCells:     map[string]common.TrustBoundary{boxCell.ID: frontend},
+	}
+}
+
+func (f incrementalFixture) placement() *incrementalPlacement {
+	return newIncrementalPlacement(f.cells, f.model, f.cellAssetIDs, f.boxCells)
+}
+
+// placedRect places the asset and returns the rectangle of its cell
+func placedRect(ip *incrementalPlacement, asset common.Asset) *common.Rectangle {
+	x, y := ip.GetPosition(asset.ID)
+	w, h := cellSize(asset)
+	return common.NewRectangle(x, y, w, h)
+}
+
+func assertFree(t *testing.T, f incrementalFixture, rect *common.Rectangle) {
+	t.Helper()
+	for _, cell := range f.cells[:2] {
+		assert.False(t, intersects(rect, cellRectangle(cell)), "overlaps %s", *cell.Data.Name)
+	}
+}
+
+func TestIncrementalPlacement_InsideBoundary(t *testing.T) {
+	box := common.NewRectangle(20, 20, 500, 300)
+	cache := common.Asset{ID: "cache", DisplayName: "cache", Type: common.AssetTypeDatabase}
+	f := newIncrementalFixture(box, cache, true)
+
+	ip := f.placement()
+	rect := placedRect(ip, cache)
+	assert.True(t, rect.IsContained(box))
+	assertFree(t, f, rect)
+	assert.Equal(t, box, ip.boxes[0].rect)
+}
+
+func TestIncrementalPlacement_GrowsFullBoundary(t *testing.T) {
+	// the box is just large enough for the web server
+	box := common.NewRectangle(40, 40, 80, 80)
+	cache := common.Asset{ID: "cache", DisplayName: "cache", Type: common.AssetTypeDatabase}
+	f := newIncrementalFixture(box, cache, true)
+
+	ip := f.placement()
+	rect := placedRect(ip, cache)
+	assertFree(t, f, rect)
+	assert.False(t, rect.IsContained(box))
+	assert.True(t, rect.IsContained(ip.boxes[0].rect))
+	// the grown box must not swallow the database
+	assert.False(t, cellRectangle(f.cells[1]).IsContained(ip.boxes[0].rect))
+}
+
+func TestIncrementalPlacement_NextToPeer(t *testing.T) {
+	api := common.Asset{ID: "api", DisplayName: "api", Type: common.AssetTypeApplication}
+	f := newIncrementalFixture(common.NewRectangle(40, 40, 80, 80), api, false)
+	f.model.DataFlows = []common.DataFlow{{ID: "sql", Source: "api", Target: "db"}}
+
+	rect := placedRect(f.placement(), api)
+	assertFree(t, f, rect)
+	assert.False(t, intersects(rect, common.NewRectangle(40, 40, 80, 80)))
+	db := cellRectangle(f.cells[1])
+	distance := math.Hypot(rect.Left+rect.Width/2-(db.Left+db.Width/2), rect.Top+rect.Height/2-(db.Top+db.Height/2))
+	assert.Less(t, distance, 250.0)
+}
+
+func TestIncrementalPlacement_Unrelated(t *testing.T) {
+	lonely := common.Asset{ID: "lonely", DisplayName: "lonely", Type: common.AssetTypeApplication}
+	f := newIncrementalFixture(common.NewRectangle(40, 40, 80, 80), lonely, false)
+
+	rect := placedRect(f.placement(), lonely)
+	db := cellRectangle(f.cells[1])
+	assert.GreaterOrEqual(t, rect.Top, db.Top+db.Height)
+}
+
+// TestGenerate_UpdatePlacesAssetNextToPeers tests that a new asset of an existing model is drawn inside its
+// trust boundary and that the existing cells keep their positions
+func TestGenerate_UpdatePlacesAssetNextToPeers(t *testing.T) {
+	const path = "testdata/testoutput_threatdragon_incremental.json"
+	defer os.Remove(path)
+
+	web := common.Asset{ID: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", DisplayName: "web", Type: common.AssetTypeWebserver, Source: common.DataSourceDockerCompose}
+	api := common.Asset{ID: "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", DisplayName: "api", Type: common.AssetTypeApplication, Source: common.DataSourceDockerCompose}
+	db := common.Asset{ID: "cccccccccccccccccccccccccccccccc", DisplayName: "db", Type: common.AssetTypeDatabase, Source: common.DataSourceDockerCompose}
+	backend := common.TrustBoundary{ID: "11111111111111111111111111111111", DisplayName: "backend", ContainedAssets: []string{api.ID, db.ID}, Source: common.DataSourceDockerCompose}
+	dataflows := []common.DataFlow{
+		{ID: "22222222222222222222222222222222", Name: "rest", Source: "web", Target: "api", DataSource: common.DataSourceDockerCompose},
+		{ID: "33333333333333333333333333333333", Name: "sql", Source: "api", Target: "db", DataSource: common.DataSourceDockerCompose},
+	}
+	require.NoError(t, NewThreatdragonOutput(path, dummyChangelog{}, slog.Default()).Generate(
+		&common.ThreatModel{Assets: []common.Asset{web, api, db}, DataFlows: dataflows, Boundaries: []common.TrustBoundary{backend}}))
+	before := map[string]Cell{}
+	for _, cell := range readProject(t, path).Detail.Diagrams[0].Cells {
+		before[*cell.Data.Name] = cell
+	}
+
+	model, err := NewThreatDragonInput(path, slog.Default()).Analyze()
+	require.NoError(t, err)
+	worker := common.Asset{ID: "dddddddddddddddddddddddddddddddd", DisplayName: "worker", Type: common.AssetTypeApplication, Source: common.DataSourceDockerCompose}
+	model.Assets = append(model.Assets, worker)
+	model.Boundaries[0].ContainedAssets = append(model.Boundaries[0].ContainedAssets, worker.ID)
+	model.DataFlows = append(model.DataFlows, common.DataFlow{ID: "44444444444444444444444444444444", Name: "jobs", Source: "worker", Target: "db", DataSource: common.DataSourceDockerCompose})
+	require.NoError(t, NewThreatdragonOutput(path, dummyChangelog{}, slog.Default()).Generate(model))
+
+	after := map[string]Cell{}
+	for _, cell := range readProject(t, path).Detail.Diagrams[0].Cells {
+		after[*cell.Data.Name] = cell
+	}
+	for _, name := range []string{"web", "api", "db"} {
+		assert.Equal(t, before[name].Position, after[name].Position, name)
+	}
+	assert.True(t, cellRectangle(after["worker"]).IsContained(cellRectangle(after["backend"])))
+	assert.False(t, cellRectangle(after["web"]).IsContained(cellRectangle(after["backend"])))
+	for _, name := range []string{"web", "api", "db"} {
+		assert.False(t, intersects(cellRectangle(after["worker"]), cellRectangle(after[name])), name)
+	}
+}
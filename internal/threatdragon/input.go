@@ -6,8 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"regexp"
+	"slices"
 
 	"github.com/threatcat-dev/threatcat/internal/common"
 )
@@ -37,8 +39,13 @@ func (i *ThreatDragonInput) Analyze() (*common.ThreatModel, error) {
 	if err != nil {
 		return nil, err
 	}
-	i.logger.Debug("Unmarshaling json")
-	err = json.Unmarshal(data, &parsed)
+	if isV1Project(data) {
+		i.logger.Info("Found a Threat Dragon v1 model. Migrating it to v2.")
+		parsed, err = migrateV1Project(data, i.logger)
+	} else {
+		i.logger.Debug("Unmarshaling json")
+		err = json.Unmarshal(data, &parsed)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -53,14 +60,32 @@ func (i *ThreatDragonInput) Analyze() (*common.ThreatModel, error) {
 		logger := i.logger.With("diagram.ID", diagram.ID)
 		//Iterate over each relevant cell in the diagram
 		logger.Debug("Iterating over cells", "count", len(diagram.Cells))
+		cellAssets := make(map[string]common.Asset)     // cell ID -> asset, to resolve the endpoints of flows
+		cellReferences := make(map[string]common.Asset) // cell ID -> referenced asset of another diagram
+		flowCells := make([]int, 0)
+		diagramBoundaries := make([]common.TrustBoundary, 0)
+		curveCells := make(map[int]int) // boundary index -> cell index of boundaries drawn as curves
+		flowIDs := make(map[int]string) // cell index -> ID of the dataflow read from the flow
 		for k, cell := range diagram.Cells {
 			logger := logger.With("cell.ID", cell.ID)
-			//Check if the cell is relevant for analysis
-			if !isRelevantType(cell.Data.Type) {
+			//Flows are read after all assets of the diagram are known
+			if isFlow(cell) {
+				flowCells = append(flowCells, k)
+				continue
+			}
+			//Check if the cell is relevant for analysis. Other cells, like text blocks, stay in the ThreatDragonModel extra
+			if !isRelevantType(cell.Data.Type) && !isCellTrustBoudary(&cell) && !isBoundaryCurve(cell) {
 				logger.Debug("Cell type is not relevant. Continuing.", "cellType", cell.Data.Type)
 				continue
 			}
 
+			//References to assets of other diagrams only connect flows to these assets
+			if ref := extractReference(cell.Data.Description); ref != "" && isRelevantType(cell.Data.Type) {
+				logger.Debug("Cell references an asset of another diagram", "id", ref)
+				cellReferences[cell.ID] = common.Asset{ID: ref, DisplayName: valueOr(cell.Data.Name, "")}
+				continue
+			}
+
 			//Check if the cell has an internal ID
 			internalID := extractID(cell.Data.Description, i.logger)
 			isGeneratedByUser := false
@@ -74,23 +99,32 @@ func (i *ThreatDragonInput) Analyze() (*common.ThreatModel, error) {
 				logger.Debug("Stored threatcat ID found.", "id", internalID)
 			}
 
-			if isCellTrustBoudary(&cell) {
+			if isCellTrustBoudary(&cell) || isBoundaryCurve(cell) {
 				trustBoudary := common.TrustBoundary{
-					ID:              internalID,
-					DisplayName:     *cell.Data.Name,
-					ContainedAssets: []string{},
-					Source:          common.DataSourceThreatDragon,
+					ID:                internalID,
+					DisplayName:       valueOr(cell.Data.Name, ""), // boundaries drawn in Threat Dragon can be unnamed
+					ContainedAssets:   []string{},
+					CrossingDataFlows: []string{},
+					Source:            common.DataSourceThreatDragon,
 					Extra: map[string]any{
-						"ThreatDragonPosition": common.NewRectangle(
-							cell.Position.X,
-							cell.Position.Y,
-							cell.Size.Width,
-							cell.Size.Height,
-						),
+						"ThreatDragonDiagramCellIdx": fmt.Sprintf("%d-%d", j, k),
+						"IsGeneratedByUser":          isGeneratedByUser,
 					},
 				}
+				//Curves separate the flows that cross them, boxes contain the assets within them
+				if isBoundaryCurve(cell) {
+					curveCells[len(diagramBoundaries)] = k
+				} else if cell.Position != nil && cell.Size != nil {
+					trustBoudary.Extra["ThreatDragonPosition"] = common.NewRectangle(
+						cell.Position.X,
+						cell.Position.Y,
+						cell.Size.Width,
+						cell.Size.Height,
+					)
+				}
 
-				model.Boundaries = append(model.Boundaries, trustBoudary)
+				logger.Debug("Created a new instance of TrustBoundary for ThreatDragon cell", "boundary", trustBoudary)
+				diagramBoundaries = append(diagramBoundaries, trustBoudary)
 
 				continue
 			}
@@ -115,30 +149,80 @@ func (i *ThreatDragonInput) Analyze() (*common.ThreatModel, error) {
 						cell.Size.Height,
 					),
 				},
+				StoresCredentials: cell.Data.StoresCredentials != nil && *cell.Data.StoresCredentials,
 			}
 
 			logger.Debug("Created a new instance of Asset for ThreatDragon cell", "asset", asset)
 
 			//add the asset to the model
 			model.Assets = append(model.Assets, asset)
+			cellAssets[cell.ID] = asset
 		}
-		logger.Debug("Finished analysing diagram", "currentAssetCount", len(model.Assets))
-	}
 
-	for _, trustBoundary := range model.Boundaries {
-		trustBoundaryRect, err := common.Get[common.Rectangle](trustBoundary.Extra, "ThreatDragonPosition")
-		if err != nil {
-			return nil, err
+		endpoints := maps.Clone(cellAssets)
+		maps.Copy(endpoints, cellReferences)
+		for _, k := range flowCells {
+			logger := logger.With("cell.ID", diagram.Cells[k].ID)
+			dataflow, ok := i.dataflowFromCell(diagram.Cells[k], endpoints, fmt.Sprintf("%d-%d", j, k), logger)
+			if !ok {
+				continue
+			}
+			logger.Debug("Created a new instance of DataFlow for ThreatDragon cell", "dataflow", dataflow)
+			model.DataFlows = append(model.DataFlows, dataflow)
+			flowIDs[k] = dataflow.ID
 		}
-		for _, asset := range model.Assets {
-			assetRect, err := common.Get[common.Rectangle](asset.Extra, "ThreatDragonPosition")
-			if err != nil {
-				return nil, err
+
+		//Boundary curves are crossed by the flows of the same diagram that intersect them
+		for b, k := range curveCells {
+			boundaryPoints, ok := curvePoints(diagram.Cells[k], diagram.Cells)
+			if !ok {
+				logger.Debug("Boundary curve has no coordinates. Skipping crossing flows.", "cell.ID", diagram.Cells[k].ID)
+				continue
+			}
+			for _, fk := range flowCells {
+				id, ok := flowIDs[fk]
+				if !ok {
+					continue
+				}
+				if flowPoints, ok := curvePoints(diagram.Cells[fk], diagram.Cells); ok && polylinesCross(flowPoints, boundaryPoints) {
+					diagramBoundaries[b].CrossingDataFlows = append(diagramBoundaries[b].CrossingDataFlows, id)
+				}
+			}
+		}
+
+		//Boundary boxes contain the assets of the same diagram that lie within them
+		for b := range diagramBoundaries {
+			trustBoundaryRect := common.GetOr[*common.Rectangle](diagramBoundaries[b].Extra, "ThreatDragonPosition", nil)
+			if trustBoundaryRect == nil {
+				continue
 			}
+			for _, asset := range cellAssets {
+				assetRect, err := common.Get[*common.Rectangle](asset.Extra, "ThreatDragonPosition")
+				if err != nil {
+					return nil, err
+				}
 
-			if trustBoundaryRect.IsContained(&assetRect) {
-				trustBoundary.ContainedAssets = append(trustBoundary.ContainedAssets, asset.ID)
+				if assetRect.IsContained(trustBoundaryRect) {
+					diagramBoundaries[b].ContainedAssets = append(diagramBoundaries[b].ContainedAssets, asset.ID)
+				}
 			}
+			slices.Sort(diagramBoundaries[b].ContainedAssets)
+		}
+		model.Boundaries = append(model.Boundaries, diagramBoundaries...)
+		logger.Debug("Finished analysing diagram", "currentAssetCount", len(model.Assets), "currentDataflowCount", len(model.DataFlows), "currentBoundaryCount", len(model.Boundaries))
+	}
+
+	//Flows to references carry the name of the reference cell, which can differ from the name of the asset
+	assetNames := make(map[string]string, len(model.Assets)) // asset ID -> display name
+	for _, asset := range model.Assets {
+		assetNames[asset.ID] = asset.DisplayName
+	}
+	for k := range model.DataFlows {
+		if name, ok := assetNames[common.GetOr(model.DataFlows[k].Extra, "SourceAssetID", "")]; ok {
+			model.DataFlows[k].Source = name
+		}
+		if name, ok := assetNames[common.GetOr(model.DataFlows[k].Extra, "TargetAssetID", "")]; ok {
+			model.DataFlows[k].Target = name
 		}
 	}
 
@@ -147,9 +231,72 @@ func (i *ThreatDragonInput) Analyze() (*common.ThreatModel, error) {
 	return &model, nil
 }
 
-// isRelevantType checks if the cell type is relevant for analysis
+// dataflowFromCell converts a flow cell into a dataflow. The source and target cells are resolved to the
+// assets of the diagram. Flows that are not connected to an asset on both ends cannot be represented and are skipped.
+func (i *ThreatDragonInput) dataflowFromCell(cell Cell, cellAssets map[string]common.Asset, cellIdx string, logger *slog.Logger) (common.DataFlow, bool) {
+	source, sourceOk := connectedAsset(cell.Source, cellAssets)
+	target, targetOk := connectedAsset(cell.Target, cellAssets)
+	if !sourceOk || !targetOk {
+		logger.Debug("Flow is not connected to an asset on both ends. Skipping.")
+		return common.DataFlow{}, false
+	}
+
+	internalID := extractID(cell.Data.Description, logger)
+	isGeneratedByUser := false
+	if internalID == "" {
+		isGeneratedByUser = true
+		internalID = generateIDHash(i.filePath, cell.ID)
+		logger.Debug("No stored threatcat ID found. This flow must be user created.", "generatedID", internalID)
+	}
+
+	threats, threatModelMap := getCellDataThreats(cell.Data, logger, i.filePath)
+	return common.DataFlow{
+		ID:            internalID,
+		Name:          valueOr(cell.Data.Name, ""),
+		Protocol:      valueOr(cell.Data.Protocol, ""),
+		Encrypted:     valueOr(cell.Data.IsEncrypted, false),
+		PublicNetwork: valueOr(cell.Data.IsPublicNetwork, false),
+		Source:        source.DisplayName,
+		Target:        target.DisplayName,
+		Bidirectional: valueOr(cell.Data.IsBidirectional, false),
+		Threats:       threats,
+		DataSource:    common.DataSourceThreatDragon,
+		Extra: map[string]any{
+			"ThreatDragonDiagramCellIdx": cellIdx,
+			"IsGeneratedByUser":          isGeneratedByUser,
+			"ThreatModelMap":             threatModelMap,
+			"SourceAssetID":              source.ID,
+			"TargetAssetID":              target.ID,
+		},
+	}, true
+}
+
+// connectedAsset returns the asset of the cell that an end of a flow is connected to
+func connectedAsset(end *Source, cellAssets map[string]common.Asset) (common.Asset, bool) {
+	if end == nil || end.Cell == nil {
+		return common.Asset{}, false
+	}
+	asset, ok := cellAssets[*end.Cell]
+	return asset, ok
+}
+
+// valueOr dereferences an optional field of a cell or returns the fallback if it is not set
+func valueOr[T any](value *T, fallback T) T {
+	if value == nil {
+		return fallback
+	}
+	return *value
+}
+
+// isFlow checks if the cell is a dataflow between two cells
+func isFlow(cell Cell) bool {
+	return cell.Data.Type == "tm.Flow"
+}
+
+// isRelevantType checks if the cell type is relevant for analysis.
+// Actors are read as well, as they are the ends of many flows, e.g. of the browser or the internet.
 func isRelevantType(cellType string) bool {
-	return cellType == "tm.Store" || cellType == "tm.Process"
+	return cellType == "tm.Store" || cellType == "tm.Process" || cellType == "tm.Actor"
 }
 
 // getCellDataType determines the asset type based on the data type
@@ -160,6 +307,8 @@ func getCellDataType(data Data) common.AssetType {
 		return common.AssetTypeApplication
 	case "tm.Store":
 		return common.AssetTypeDatabase
+	case "tm.Actor":
+		return common.AssetTypeExternalEntity
 	default:
 		return common.AssetTypeUnknown
 	}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/threatcat-dev/threatcat/internal/common"
 )
 
@@ -43,6 +44,15 @@ func TestAnalyze(t *testing.T) {
 							"ThreatDragonCell": map[string]any{}, // Not checked
 						},
 					},
+					{
+						ID:          "", // ID is not checked
+						DisplayName: "Actor Name",
+						Type:        common.AssetTypeExternalEntity,
+						Source:      common.DataSourceThreatDragon,
+						Extra: map[string]any{
+							"ThreatDragonCell": map[string]any{}, // Not checked
+						},
+					},
 				},
 				Extra: map[string]any{
 					"ThreatDragonModel": nil, // Not checked
@@ -57,6 +67,15 @@ func TestAnalyze(t *testing.T) {
 								"ThreatDragonCell": map[string]any{}, // Not checked
 							},
 					},
+					{
+							ID:          "", // ID is not checked
+							DisplayName: "Trust Boundary Name",
+							ContainedAssets: []string{}, // ContainedAssets is not Checked
+							Source: common.DataSourceThreatDragon,
+							Extra: map[string]any{
+								"ThreatDragonCell": map[string]any{}, // Not checked
+							},
+					},
 				},
 			},
 		},
@@ -92,6 +111,7 @@ func TestAnalyze(t *testing.T) {
 			}
 
 			// Validate each trust boundary's properties
+			require.Len(t, internalModel.Boundaries, len(tt.expectedModel.Boundaries))
 			for i, boundary := range internalModel.Boundaries {
 				assert.Equal(t, common.MaxIDHashLength, len(boundary.ID)) // Ensure ID length
 				assert.Equal(t, tt.expectedModel.Boundaries[i].DisplayName, boundary.DisplayName)
@@ -157,8 +177,8 @@ func TestAnalyzeWithBoundary(t *testing.T) {
 							ID:          "", // ID is not checked
 							DisplayName: "Trust Boundary 1",
 							ContainedAssets: []string{
-								"d899870e-853e-4378-aea1-c2c9d489e16f",
-								"7a065373-8d89-4fe2-a1cc-cdf6cd7aa1ba",
+								generateIDHash("./testdata/threatdragon_trustboundary_testjson.json", "d899870e-853e-4378-aea1-c2c9d489e16f"),
+								generateIDHash("./testdata/threatdragon_trustboundary_testjson.json", "7a065373-8d89-4fe2-a1cc-cdf6cd7aa1ba"),
 							},
 							Source: common.DataSourceThreatDragon,
 							Extra: map[string]any{
@@ -169,7 +189,7 @@ func TestAnalyzeWithBoundary(t *testing.T) {
 							ID:          "", // ID is not checked
 							DisplayName: "Trust Boundary 2",
 							ContainedAssets: []string{
-								"6e101964-58e8-4379-893a-a358ca1c086e",
+								generateIDHash("./testdata/threatdragon_trustboundary_testjson.json", "6e101964-58e8-4379-893a-a358ca1c086e"),
 							},
 							Source: common.DataSourceThreatDragon,
 							Extra: map[string]any{
@@ -211,6 +231,7 @@ func TestAnalyzeWithBoundary(t *testing.T) {
 			}
 
 			// Validate each trust boundary's properties
+			require.Len(t, internalModel.Boundaries, len(tt.expectedModel.Boundaries))
 			for i, boundary := range internalModel.Boundaries {
 				assert.Equal(t, common.MaxIDHashLength, len(boundary.ID)) // Ensure ID length
 				assert.Equal(t, len(tt.expectedModel.Boundaries[i].ContainedAssets) ,len(boundary.ContainedAssets)) // Ensure ContainedAssets length
@@ -324,9 +345,9 @@ func TestIsRelevantType(t *testing.T) {
 			expected: false,
 		},
 		{
-			name:     "Irrelevant type: tm.Actor",
+			name:     "Relevant type: tm.Actor",
 			cellType: "tm.Actor",
-			expected: false,
+			expected: true,
 		},
 		{
 			name:     "Irrelevant type: tm.Boundary",
@@ -378,6 +399,13 @@ func TestGetCellDataType(t *testing.T) {
 			},
 			expected: common.AssetTypeDatabase,
 		},
+		{
+			name: "Actor type",
+			data: Data{
+				Type: "tm.Actor",
+			},
+			expected: common.AssetTypeExternalEntity,
+		},
 		{
 			name: "Unknown type",
 			data: Data{
@@ -503,3 +531,98 @@ func TestGetCellDataThreats_NoThreats(t *testing.T) {
 	assert.Equal(t, 0, len(threats), "expected no threats returned when data.Threats is nil")
 	assert.Equal(t, 0, len(modelMap), "expected empty model map when data.Threats is nil")
 }
+
+func TestAnalyzeDataflows(t *testing.T) {
+	input := NewThreatDragonInput("./testdata/models/demo_model_web.json", slog.Default())
+	model, err := input.Analyze()
+	require.NoError(t, err)
+
+	// the 'Web Request' flow starts at a loose end and is skipped
+	require.Len(t, model.DataFlows, 9)
+
+	index := slices.IndexFunc(model.DataFlows, func(df common.DataFlow) bool { return df.Name == "Web Response" })
+	require.GreaterOrEqual(t, index, 0)
+	flow := model.DataFlows[index]
+
+	assert.Equal(t, common.MaxIDHashLength, len(flow.ID))
+	assert.Equal(t, "Web\nApplication", flow.Source)
+	assert.Equal(t, "Browser", flow.Target)
+	assert.Equal(t, "HTTP/S", flow.Protocol)
+	assert.True(t, flow.Encrypted)
+	assert.True(t, flow.PublicNetwork)
+	assert.False(t, flow.Bidirectional)
+	assert.Equal(t, common.DataSourceThreatDragon, flow.DataSource)
+	assert.Len(t, flow.Threats, 1)
+	assert.Equal(t, true, flow.Extra["IsGeneratedByUser"])
+
+	// the endpoints are resolved to the assets of the connected cells
+	for _, end := range []struct{ key, name string }{{"SourceAssetID", flow.Source}, {"TargetAssetID", flow.Target}} {
+		assetIndex := slices.IndexFunc(model.Assets, func(a common.Asset) bool { return a.ID == flow.Extra[end.key] })
+		require.GreaterOrEqual(t, assetIndex, 0)
+		assert.Equal(t, end.name, model.Assets[assetIndex].DisplayName)
+	}
+
+	// the browser at the target is an actor
+	browser := slices.IndexFunc(model.Assets, func(a common.Asset) bool { return a.ID == flow.Extra["TargetAssetID"] })
+	assert.Equal(t, common.AssetTypeExternalEntity, model.Assets[browser].Type)
+}
+
+func TestAnalyzeDataflowsWithStoredID(t *testing.T) {
+	id := "0123456789abcdef0123456789abcdef"
+	description := "some flow " + analyzerIDTag(id)
+	name := "Flow1"
+	source, target := "source-cell", "target-cell"
+	cellAssets := map[string]common.Asset{
+		source: {ID: "a1", DisplayName: "web"},
+		target: {ID: "a2", DisplayName: "db"},
+	}
+	input := NewThreatDragonInput("model.json", slog.Default())
+
+	flow, ok := input.dataflowFromCell(Cell{
+		ID:     "flow-cell",
+		Data:   Data{Type: "tm.Flow", Name: &name, Description: &description},
+		Source: &Source{Cell: &source},
+		Target: &Source{Cell: &target},
+	}, cellAssets, "0-2", slog.Default())
+	require.True(t, ok)
+	assert.Equal(t, id, flow.ID)
+	assert.Equal(t, "web", flow.Source)
+	assert.Equal(t, "db", flow.Target)
+	assert.Equal(t, false, flow.Extra["IsGeneratedByUser"])
+	assert.Equal(t, "0-2", flow.Extra["ThreatDragonDiagramCellIdx"])
+
+	unknown := "unknown-cell"
+	_, ok = input.dataflowFromCell(Cell{
+		ID:     "flow-cell",
+		Data:   Data{Type: "tm.Flow", Name: &name},
+		Source: &Source{Cell: &source},
+		Target: &Source{Cell: &unknown},
+	}, cellAssets, "0-3", slog.Default())
+	assert.False(t, ok)
+}
+
+// TestAnalyzeBoundaryCurves tests that boundaries drawn as curves are read with the flows crossing them
+func TestAnalyzeBoundaryCurves(t *testing.T) {
+	model, err := NewThreatDragonInput("./testdata/models/crypto_wallet_web.json", slog.Default()).Analyze()
+	require.NoError(t, err)
+
+	idx := slices.IndexFunc(model.Boundaries, func(b common.TrustBoundary) bool { return b.DisplayName == "Internet" })
+	require.GreaterOrEqual(t, idx, 0)
+	internet := model.Boundaries[idx]
+	assert.Empty(t, internet.ContainedAssets)
+	assert.True(t, common.GetOr(internet.Extra, "IsGeneratedByUser", false))
+
+	crossing := make([]string, 0, len(internet.CrossingDataFlows))
+	for _, df := range model.DataFlows {
+		if slices.Contains(internet.CrossingDataFlows, df.ID) {
+			crossing = append(crossing, df.Source+" -> "+df.Target)
+		}
+	}
+	assert.ElementsMatch(t, []string{
+		"wallet -> ElectrumX",
+		"Browser -> Exchange\nWeb Site",
+		"Browser -> Exchange\nBackend",
+		"Trading Bot -> Exchange\nAPI",
+		"Mobile App -> Exchange\nAPI",
+	}, crossing)
+}
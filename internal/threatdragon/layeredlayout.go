@@ -0,0 +1,286 @@
+package threatdragon
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// LayoutStrategy decides how the cells of new diagrams are placed
+type LayoutStrategy int
+
+const (
+	// LayoutLayered places the assets in layers along the direction of the dataflows
+	LayoutLayered LayoutStrategy = iota
+	// LayoutGrid places the assets in a grid around their trust boundaries and ignores the dataflows
+	LayoutGrid
+)
+
+// ParseLayoutStrategy converts the name of a layout as given on the command line
+func ParseLayoutStrategy(name string) (LayoutStrategy, error) {
+	switch name {
+	case "", "layered":
+		return LayoutLayered, nil
+	case "grid":
+		return LayoutGrid, nil
+	}
+	return LayoutLayered, fmt.Errorf("unknown layout '%s', expected layered or grid", name)
+}
+
+// layeredSweeps is the number of times the layers are reordered to reduce crossing flows, each time forth and back
+const layeredSweeps = 4
+
+// SolveLayered places the assets in layers from left to right, so that dataflows point to the right where possible.
+// The assets of a layer are ordered by the positions of the assets they exchange data with, which reduces crossing flows.
+// Assets with the same trust boundaries are kept together in horizontal bands, and the bands of a boundary follow each other,
+// so that every boundary is a box around its assets. It fails if the boundaries overlap in a way that cannot be drawn
+// with boxes. Assets without any dataflow are spread over the layers.
+func SolveLayered(assets []common.Asset, dataflows []common.DataFlow, boundaries []common.TrustBoundary) (Solution, error) {
+	index := make(map[string]int, len(assets))
+	for i, asset := range assets {
+		index[asset.ID] = i
+	}
+
+	// dataflows become edges between the indices of their assets, once per pair
+	adjacent := make([][]int, len(assets))
+	edges := make([][2]int, 0, len(dataflows))
+	seen := make(map[[2]int]bool)
+	for _, dataflow := range dataflows {
+		source, ok := index[common.EndpointAsset(assets, dataflow, dataflow.Source).ID]
+		if !ok {
+			continue
+		}
+		target, ok := index[common.EndpointAsset(assets, dataflow, dataflow.Target).ID]
+		if !ok || source == target || seen[[2]int{source, target}] {
+			continue
+		}
+		seen[[2]int{source, target}] = true
+		edges = append(edges, [2]int{source, target})
+		adjacent[source] = append(adjacent[source], target)
+		adjacent[target] = append(adjacent[target], source)
+	}
+
+	layers := assignLayers(len(assets), acyclicEdges(len(assets), edges))
+
+	// assets with the same boundaries form a region, regions of overlapping boundaries are ordered into consecutive bands
+	memberships := make(map[string][]string)
+	for _, boundary := range boundaries {
+		for _, id := range boundary.ContainedAssets {
+			if _, ok := index[id]; ok {
+				memberships[id] = append(memberships[id], boundary.ID)
+			}
+		}
+	}
+	regions := make([]region, 0)
+	free := region{}
+	regionIndex := make(map[string]int)
+	for _, asset := range assets {
+		boxes := memberships[asset.ID]
+		if len(boxes) == 0 {
+			free.nodes = append(free.nodes, asset.ID)
+			continue
+		}
+		key := fmt.Sprint(boxes)
+		i, ok := regionIndex[key]
+		if !ok {
+			i = len(regions)
+			regionIndex[key] = i
+			regions = append(regions, region{boxes: boxes})
+		}
+		regions[i].nodes = append(regions[i].nodes, asset.ID)
+	}
+	bands := make([]region, 0, len(regions)+1)
+	attempts := 0
+	for _, cluster := range clusterRegions(regions) {
+		ordered, ok := orderRegions(cluster, &attempts)
+		if !ok {
+			return Solution{}, fmt.Errorf("no order of the trust boundaries found in which every boundary covers consecutive bands")
+		}
+		bands = append(bands, ordered...)
+	}
+	if len(free.nodes) > 0 {
+		bands = append(bands, free)
+	}
+
+	// cells[band][layer] holds the indices of the assets in their order from top to bottom
+	layerCount := slices.Max(append(slices.Clone(layers), 0)) + 1
+	band := make([]int, len(assets))
+	for b, r := range bands {
+		isolated := 0
+		for _, id := range r.nodes {
+			if len(adjacent[index[id]]) == 0 {
+				isolated++
+			}
+		}
+		// isolated assets fill the layers of the band from left to right, at least in a square
+		width := max(layerCount, int(math.Ceil(math.Sqrt(float64(isolated)))))
+		k := 0
+		for _, id := range r.nodes {
+			i := index[id]
+			band[i] = b
+			if len(adjacent[i]) == 0 {
+				layers[i] = k % width
+				k++
+			}
+		}
+	}
+	layerCount = slices.Max(append(slices.Clone(layers), 0)) + 1
+	cells := make([][][]int, len(bands))
+	for b := range cells {
+		cells[b] = make([][]int, layerCount)
+	}
+	for i := range assets {
+		cells[band[i]][layers[i]] = append(cells[band[i]][layers[i]], i)
+	}
+
+	bandStarts := make([]int, len(bands)+1)
+	for b := range bands {
+		height := 0
+		for _, cell := range cells[b] {
+			height = max(height, len(cell))
+		}
+		bandStarts[b+1] = bandStarts[b] + height
+	}
+	rows := make([]int, len(assets))
+	updateRows := func(b, l int) {
+		for k, i := range cells[b][l] {
+			rows[i] = bandStarts[b] + k
+		}
+	}
+	for b := range cells {
+		for l := range cells[b] {
+			updateRows(b, l)
+		}
+	}
+
+	// barycenter sweeps: sort each layer by the mean row of the neighbours in the layers before, then after it
+	reorder := func(l int, neighbour func(int) bool) {
+		for b := range cells {
+			barycenters := make(map[int]float64, len(cells[b][l]))
+			for _, i := range cells[b][l] {
+				sum, count := 0, 0
+				for _, j := range adjacent[i] {
+					if neighbour(layers[j]) {
+						sum += rows[j]
+						count++
+					}
+				}
+				if count == 0 {
+					barycenters[i] = float64(rows[i])
+				} else {
+					barycenters[i] = float64(sum) / float64(count)
+				}
+			}
+			slices.SortStableFunc(cells[b][l], func(x, y int) int {
+				return cmp.Compare(barycenters[x], barycenters[y])
+			})
+			updateRows(b, l)
+		}
+	}
+	for range layeredSweeps {
+		for l := 1; l < layerCount; l++ {
+			reorder(l, func(other int) bool { return other < l })
+		}
+		for l := layerCount - 2; l >= 0; l-- {
+			reorder(l, func(other int) bool { return other > l })
+		}
+	}
+
+	solution := Solution{Boxes: []PlacedBox{}, Nodes: make([]PlacedNode, 0, len(assets))}
+	for i, asset := range assets {
+		solution.Nodes = append(solution.Nodes, PlacedNode{ID: asset.ID, X: layers[i], Y: rows[i]})
+	}
+
+	// boundaries enclose their assets; boundaries without assets are drawn in a row below all assets
+	empty := 0
+	for _, boundary := range boundaries {
+		box := PlacedBox{ID: boundary.ID, X: math.MaxInt, Y: math.MaxInt}
+		right, bottom := -1, -1
+		for _, id := range boundary.ContainedAssets {
+			i, ok := index[id]
+			if !ok {
+				continue
+			}
+			box.X, box.Y = min(box.X, layers[i]), min(box.Y, rows[i])
+			right, bottom = max(right, layers[i]), max(bottom, rows[i])
+		}
+		if right < 0 {
+			box = PlacedBox{ID: boundary.ID, X: empty, Y: bandStarts[len(bands)], W: 1, H: 1}
+			empty++
+		} else {
+			box.W, box.H = right-box.X+1, bottom-box.Y+1
+		}
+		solution.Boxes = append(solution.Boxes, box)
+	}
+	assignDepths(solution.Boxes)
+	return solution, nil
+}
+
+// acyclicEdges reverses the edges that close a cycle, found by a depth first search in the order of the nodes
+func acyclicEdges(n int, edges [][2]int) [][2]int {
+	outgoing := make([][]int, n)
+	for k, edge := range edges {
+		outgoing[edge[0]] = append(outgoing[edge[0]], k)
+	}
+
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	state := make([]int, n)
+	result := slices.Clone(edges)
+	var visit func(i int)
+	visit = func(i int) {
+		state[i] = active
+		for _, k := range outgoing[i] {
+			target := edges[k][1]
+			switch state[target] {
+			case unvisited:
+				visit(target)
+			case active:
+				result[k] = [2]int{target, i}
+			}
+		}
+		state[i] = done
+	}
+	for i := range n {
+		if state[i] == unvisited {
+			visit(i)
+		}
+	}
+	return result
+}
+
+// assignLayers puts every node one layer right of its rightmost predecessor. The edges must not form a cycle.
+func assignLayers(n int, edges [][2]int) []int {
+	outgoing := make([][]int, n)
+	incoming := make([]int, n)
+	for _, edge := range edges {
+		outgoing[edge[0]] = append(outgoing[edge[0]], edge[1])
+		incoming[edge[1]]++
+	}
+
+	layers := make([]int, n)
+	queue := make([]int, 0, n)
+	for i := range n {
+		if incoming[i] == 0 {
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		for _, j := range outgoing[i] {
+			layers[j] = max(layers[j], layers[i]+1)
+			incoming[j]--
+			if incoming[j] == 0 {
+				queue = append(queue, j)
+			}
+		}
+	}
+	return layers
+}
@@ -0,0 +1,173 @@
+package threatdragon
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+func namedAssets(names ...string) []common.Asset {
+	assets := make([]common.Asset, len(names))
+	for i, name := range names {
+		assets[i] = common.Asset{ID: name, DisplayName: name}
+	}
+	return assets
+}
+
+func flow(source, target string) common.DataFlow {
+	return common.DataFlow{ID: source + "->" + target, Source: source, Target: target}
+}
+
+func TestParseLayoutStrategy(t *testing.T) {
+	for name, expected := range map[string]LayoutStrategy{
+		"":        LayoutLayered,
+		"layered": LayoutLayered,
+		"grid":    LayoutGrid,
+	} {
+		layout, err := ParseLayoutStrategy(name)
+		require.NoError(t, err)
+		assert.Equal(t, expected, layout, name)
+	}
+
+	_, err := ParseLayoutStrategy("circle")
+	assert.Error(t, err)
+}
+
+func TestAcyclicEdges(t *testing.T) {
+	edges := [][2]int{{0, 1}, {1, 2}, {2, 0}, {2, 3}}
+	assert.Equal(t, [][2]int{{0, 1}, {1, 2}, {0, 2}, {2, 3}}, acyclicEdges(4, edges))
+	assert.Equal(t, []int{0, 1, 2, 3}, assignLayers(4, acyclicEdges(4, edges)))
+
+	// nodes are placed right of their rightmost predecessor
+	assert.Equal(t, []int{0, 1, 2, 0}, assignLayers(4, [][2]int{{0, 1}, {1, 2}, {0, 2}, {3, 2}}))
+}
+
+func TestSolveLayered_FlowDirection(t *testing.T) {
+	assets := namedAssets("user", "web", "api", "db")
+	// the flow back from the database closes a cycle and is drawn against the direction
+	dataflows := []common.DataFlow{flow("user", "web"), flow("web", "api"), flow("api", "db"), flow("db", "api")}
+
+	s, err := SolveLayered(assets, dataflows, nil)
+	require.NoError(t, err)
+	previous := -1.0
+	for _, asset := range assets {
+		x, _ := s.GetPosition(asset.ID)
+		assert.Greater(t, x, previous, asset.ID)
+		previous = x
+	}
+}
+
+func TestSolveLayered_Crossings(t *testing.T) {
+	// in the order of the input, the flows from a and b to their targets cross each other
+	assets := namedAssets("a", "b", "to-b", "to-a")
+	dataflows := []common.DataFlow{flow("a", "to-a"), flow("b", "to-b")}
+
+	s, err := SolveLayered(assets, dataflows, nil)
+	require.NoError(t, err)
+	_, ya := s.GetPosition("a")
+	_, yb := s.GetPosition("b")
+	_, yToA := s.GetPosition("to-a")
+	_, yToB := s.GetPosition("to-b")
+	assert.Equal(t, ya < yb, yToA < yToB)
+}
+
+func TestSolveLayered_Boundaries(t *testing.T) {
+	assets := namedAssets("user", "ingress", "web", "api", "db", "prometheus", "cache")
+	dataflows := []common.DataFlow{
+		flow("user", "ingress"), flow("ingress", "web"), flow("web", "api"), flow("api", "db"),
+		flow("prometheus", "api"), flow("api", "cache"),
+	}
+	boundaries := []common.TrustBoundary{
+		{ID: "cluster", ContainedAssets: []string{"ingress", "web", "api", "db", "prometheus", "cache"}},
+		{ID: "shop", ContainedAssets: []string{"web", "api", "db"}},
+		{ID: "monitoring", ContainedAssets: []string{"prometheus"}},
+		{ID: "backend", ContainedAssets: []string{"api", "db", "cache"}},
+		{ID: "unused", ContainedAssets: []string{}},
+	}
+
+	s, err := SolveLayered(assets, dataflows, boundaries)
+	require.NoError(t, err)
+
+	boxDefs := make([]BoxDef, 0, len(boundaries))
+	memberships := make([]Membership, 0)
+	for _, boundary := range boundaries {
+		boxDefs = append(boxDefs, BoxDef{ID: boundary.ID})
+		for _, id := range boundary.ContainedAssets {
+			memberships = append(memberships, Membership{NodeID: id, BoxID: boundary.ID})
+		}
+	}
+	nodeDefs := make([]NodeDef, 0, len(assets))
+	for _, asset := range assets {
+		nodeDefs = append(nodeDefs, NodeDef{ID: asset.ID})
+	}
+	assertPlacement(t, &s, boxDefs, nodeDefs, memberships)
+	assert.True(t, boundaryRect(&s, "shop").IsContained(boundaryRect(&s, "cluster")))
+	assert.True(t, boundaryRect(&s, "backend").IsContained(boundaryRect(&s, "cluster")))
+	assert.False(t, boundaryRect(&s, "unused").IsContained(boundaryRect(&s, "cluster")))
+}
+
+// Boundaries that overlap in a cycle cannot be drawn as boxes around consecutive bands
+func TestSolveLayered_Cycle(t *testing.T) {
+	boundaries := []common.TrustBoundary{
+		{ID: "A", ContainedAssets: []string{"ab", "ca"}},
+		{ID: "B", ContainedAssets: []string{"ab", "bc"}},
+		{ID: "C", ContainedAssets: []string{"bc", "ca"}},
+	}
+	_, err := SolveLayered(namedAssets("ab", "bc", "ca"), nil, boundaries)
+	assert.Error(t, err)
+}
+
+// Assets without dataflows are spread over a square instead of a single column
+func TestSolveLayered_Isolated(t *testing.T) {
+	names := make([]string, 9)
+	for i := range names {
+		names[i] = fmt.Sprintf("asset-%d", i)
+	}
+
+	s, err := SolveLayered(namedAssets(names...), nil, nil)
+	require.NoError(t, err)
+	cols, rows := s.gridSize()
+	assert.Equal(t, 3, cols)
+	assert.Equal(t, 3, rows)
+}
+
+func TestSolveLayered_Scale(t *testing.T) {
+	const assetCount, boundaryCount = 3000, 30
+	assets := make([]common.Asset, 0, assetCount)
+	dataflows := make([]common.DataFlow, 0, 2*assetCount)
+	boundaries := make([]common.TrustBoundary, boundaryCount, boundaryCount+1)
+	for i := range assetCount {
+		name := fmt.Sprintf("asset-%d", i)
+		assets = append(assets, common.Asset{ID: name, DisplayName: name})
+		dataflows = append(dataflows,
+			flow(name, fmt.Sprintf("asset-%d", (i*7+13)%assetCount)),
+			flow(name, fmt.Sprintf("asset-%d", (i+1)%assetCount)),
+		)
+		b := i % boundaryCount
+		boundaries[b].ID = fmt.Sprintf("boundary-%d", b)
+		boundaries[b].ContainedAssets = append(boundaries[b].ContainedAssets, name)
+	}
+	// a network around the first ten boundaries
+	outer := common.TrustBoundary{ID: "outer"}
+	for _, boundary := range boundaries[:10] {
+		outer.ContainedAssets = append(outer.ContainedAssets, boundary.ContainedAssets...)
+	}
+	boundaries = append(boundaries, outer)
+
+	start := time.Now()
+	s, err := SolveLayered(assets, dataflows, boundaries)
+	require.NoError(t, err)
+	for _, asset := range assets {
+		s.GetPosition(asset.ID)
+	}
+	for _, boundary := range boundaries {
+		s.GetBoundaryPosition(boundary.ID)
+	}
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.True(t, boundaryRect(&s, "boundary-3").IsContained(boundaryRect(&s, "outer")))
+	assert.False(t, nodeRect(&s, "asset-10").IsContained(boundaryRect(&s, "outer")))
+}
@@ -0,0 +1,335 @@
+package threatdragon
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+)
+
+// projectV1 is a Threat Dragon 1.x model. Its diagrams hold the JointJS graph in diagramJson.
+type projectV1 struct {
+	Summary Summary `json:"summary"`
+	Detail  struct {
+		Contributors []Contributor `json:"contributors"`
+		Diagrams     []diagramV1   `json:"diagrams"`
+		Reviewer     string        `json:"reviewer"`
+	} `json:"detail"`
+}
+
+type diagramV1 struct {
+	ID          int64  `json:"id"`
+	Title       string `json:"title"`
+	DiagramType string `json:"diagramType"`
+	DiagramJSON struct {
+		Cells []cellV1 `json:"cells"`
+	} `json:"diagramJson"`
+}
+
+type cellV1 struct {
+	Type     string       `json:"type"`
+	ID       string       `json:"id"`
+	Z        int64        `json:"z"`
+	Position *VertexClass `json:"position"`
+	Size     *Size        `json:"size"`
+	Attrs    struct {
+		Text *TextClass `json:"text"`
+	} `json:"attrs"`
+	Labels []struct {
+		Attrs struct {
+			Text *TextClass `json:"text"`
+		} `json:"attrs"`
+	} `json:"labels"`
+	Source   *endV1        `json:"source"`
+	Target   *endV1        `json:"target"`
+	Vertices []VertexClass `json:"vertices"`
+
+	Description            string     `json:"description"`
+	OutOfScope             bool       `json:"outOfScope"`
+	ReasonOutOfScope       string     `json:"reasonOutOfScope"`
+	PrivilegeLevel         string     `json:"privilegeLevel"`
+	ProvidesAuthentication bool       `json:"providesAuthentication"`
+	IsALog                 bool       `json:"isALog"`
+	IsEncrypted            bool       `json:"isEncrypted"`
+	IsSigned               bool       `json:"isSigned"`
+	StoresCredentials      bool       `json:"storesCredentials"`
+	IsPublicNetwork        bool       `json:"isPublicNetwork"`
+	Protocol               string     `json:"protocol"`
+	Threats                []threatV1 `json:"threats"`
+}
+
+// endV1 is the end of a flow or boundary, either connected to a cell or a free point
+type endV1 struct {
+	ID *string  `json:"id"`
+	X  *float64 `json:"x"`
+	Y  *float64 `json:"y"`
+}
+
+type threatV1 struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Status      string `json:"status"`
+	Severity    string `json:"severity"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Mitigation  string `json:"mitigation"`
+	ModelType   string `json:"modelType"`
+}
+
+// isV1Project checks if the data is a Threat Dragon 1.x model.
+// These models have no version and store the cells of their diagrams in diagramJson.
+func isV1Project(data []byte) bool {
+	var probe struct {
+		Version string `json:"version"`
+		Detail  struct {
+			Diagrams []struct {
+				DiagramJSON json.RawMessage `json:"diagramJson"`
+			} `json:"diagrams"`
+		} `json:"detail"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil || probe.Version != "" {
+		return false
+	}
+	for _, diagram := range probe.Detail.Diagrams {
+		if diagram.DiagramJSON != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// migrateV1Project converts a Threat Dragon 1.x model into a 2.x project.
+// Cells keep their IDs, so that flows stay connected and the IDs of the assets do not change.
+// Threats without an ID get one derived from their cell, so that migrating the same file again gives the same threats.
+func migrateV1Project(data []byte, logger *slog.Logger) (Project, error) {
+	var v1 projectV1
+	if err := json.Unmarshal(data, &v1); err != nil {
+		return Project{}, fmt.Errorf("failed to unmarshal Threat Dragon v1 model: %w", err)
+	}
+
+	project := Project{
+		Version: defaultVersion,
+		Summary: v1.Summary,
+		Detail: Detail{
+			Contributors: v1.Detail.Contributors,
+			Diagrams:     make([]Diagram, 0, len(v1.Detail.Diagrams)),
+			DiagramTop:   int64(len(v1.Detail.Diagrams)),
+			Reviewer:     v1.Detail.Reviewer,
+		},
+	}
+	if project.Detail.Contributors == nil {
+		project.Detail.Contributors = []Contributor{}
+	}
+
+	threatNumber := int64(0)
+	for _, diagram := range v1.Detail.Diagrams {
+		diagramType := diagram.DiagramType
+		if diagramType == "" {
+			diagramType = "STRIDE"
+		}
+		cells := make([]Cell, 0, len(diagram.DiagramJSON.Cells))
+
+		// elements first, so that flows can be connected to their ports
+		for _, v1Cell := range diagram.DiagramJSON.Cells {
+			if !isV1Element(v1Cell) {
+				continue
+			}
+			cell, _ := migrateV1Element(v1Cell, migrateV1Threats(v1Cell, &threatNumber))
+			cells = append(cells, cell)
+		}
+		for _, v1Cell := range diagram.DiagramJSON.Cells {
+			switch v1Cell.Type {
+			case "tm.Flow":
+				cells = append(cells, migrateV1Flow(v1Cell, migrateV1Threats(v1Cell, &threatNumber), cells))
+			case "tm.Boundary":
+				cells = append(cells, migrateV1Boundary(v1Cell))
+			case "tm.Actor", "tm.Process", "tm.Store":
+			default:
+				logger.Warn("Unknown cell type in Threat Dragon v1 model. Skipping.", "cellType", v1Cell.Type, "cell.ID", v1Cell.ID)
+			}
+		}
+
+		project.Detail.Diagrams = append(project.Detail.Diagrams, Diagram{
+			ID:          diagram.ID,
+			Title:       diagram.Title,
+			DiagramType: diagramType,
+			Thumbnail:   "./public/content/images/thumbnail.stride.jpg",
+			Version:     defaultVersion,
+			Cells:       cells,
+		})
+	}
+	project.Detail.ThreatTop = threatNumber
+
+	logger.Info("Migrated Threat Dragon v1 model", "diagramCount", len(project.Detail.Diagrams), "threatCount", threatNumber)
+	return project, nil
+}
+
+// isV1Element checks if the cell is an actor, process or store
+func isV1Element(v1Cell cellV1) bool {
+	return v1Cell.Type == "tm.Actor" || v1Cell.Type == "tm.Process" || v1Cell.Type == "tm.Store"
+}
+
+// migrateV1Element converts an actor, process or store. It returns false for all other cells.
+func migrateV1Element(v1Cell cellV1, threats []Threat) (Cell, bool) {
+	name := ""
+	if v1Cell.Attrs.Text != nil {
+		name = v1Cell.Attrs.Text.Text
+	}
+	x, y := 0.0, 0.0
+	if v1Cell.Position != nil {
+		x, y = v1Cell.Position.X, v1Cell.Position.Y
+	}
+
+	var cell Cell
+	switch v1Cell.Type {
+	case "tm.Actor":
+		cell = actor(name, v1Cell.Description, threats, x, y)
+		cell.Data.ProvidesAuthentication = &v1Cell.ProvidesAuthentication
+	case "tm.Process":
+		cell = process(name, v1Cell.Description, false, threats, x, y)
+		cell.Data.PrivilegeLevel = &v1Cell.PrivilegeLevel
+	case "tm.Store":
+		cell = store(name, v1Cell.Description, v1Cell.StoresCredentials, threats, x, y)
+		cell.Data.IsALog = &v1Cell.IsALog
+		cell.Data.IsEncrypted = &v1Cell.IsEncrypted
+		cell.Data.IsSigned = &v1Cell.IsSigned
+	default:
+		return Cell{}, false
+	}
+
+	cell.ID = v1Cell.ID
+	if v1Cell.Size != nil {
+		cell.Size = v1Cell.Size
+	}
+	cell.Data.OutOfScope = &v1Cell.OutOfScope
+	cell.Data.ReasonOutOfScope = &v1Cell.ReasonOutOfScope
+	return cell, true
+}
+
+// migrateV1Flow converts a flow. Ends connected to a migrated cell are connected to its port, other ends keep their point.
+func migrateV1Flow(v1Cell cellV1, threats []Threat, cells []Cell) Cell {
+	name := v1LabelText(v1Cell)
+	placeholder := Cell{} // the ends are migrated below
+	cell := newDataflow(name, v1Cell.Description, v1Cell.Protocol, v1Cell.IsPublicNetwork, v1Cell.IsEncrypted, &placeholder, &placeholder, false)
+	cell.ID = v1Cell.ID
+	cell.Source = migrateV1End(v1Cell.Source, cells)
+	cell.Target = migrateV1End(v1Cell.Target, cells)
+	cell.Vertices = v1Vertices(v1Cell)
+	cell.Data.OutOfScope = &v1Cell.OutOfScope
+	cell.Data.ReasonOutOfScope = &v1Cell.ReasonOutOfScope
+	cell.Data.Threats = &threats
+	cell.Data.HasOpenThreats = hasOpenThreats(threats)
+	return cell
+}
+
+// migrateV1Boundary converts a boundary line into a boundary curve
+func migrateV1Boundary(v1Cell cellV1) Cell {
+	name := v1LabelText(v1Cell)
+	return Cell{
+		ID:        v1Cell.ID,
+		Shape:     "trust-boundary-curve",
+		Width:     float64Ptr(200),
+		Height:    float64Ptr(100),
+		ZIndex:    10,
+		Connector: stringPtr("smooth"),
+		Data: Data{
+			Type:            "tm.Boundary",
+			Name:            &name,
+			Description:     stringPtr(v1Cell.Description),
+			IsTrustBoundary: boolPtr(true),
+		},
+		Labels: []LabelElement{
+			{
+				LabelLabel: &LabelLabel{
+					Attrs: LabelAttrs{
+						Label:     TextClass{Text: "Trust Boundary"},
+						LabelText: LabelText{Text: name, TextAnchor: "middle", TextVerticalAnchor: "middle"},
+						LabelBody: LabelBody{Ref: "labelText", RefRx: "50%", RefRy: "60%", Fill: "#fff"},
+					},
+					Markup: []Markup{
+						{TagName: "ellipse", Selector: "labelBody"},
+						{TagName: "text", Selector: "labelText"},
+					},
+					Position: &PositionUnion{
+						PositionPosition: &PositionPosition{
+							Distance: 0.5,
+							Args:     Args{KeepGradient: true, EnsureLegibility: true},
+						},
+					},
+				},
+			},
+		},
+		Source:   migrateV1End(v1Cell.Source, nil),
+		Target:   migrateV1End(v1Cell.Target, nil),
+		Vertices: v1Vertices(v1Cell),
+	}
+}
+
+// migrateV1End converts the end of a flow or boundary
+func migrateV1End(end *endV1, cells []Cell) *Source {
+	if end == nil {
+		return &Source{}
+	}
+	if end.ID != nil {
+		for k := range cells {
+			if cells[k].ID == *end.ID {
+				return &Source{Cell: &cells[k].ID, Port: connectionPort(&cells[k])}
+			}
+		}
+		return &Source{Cell: end.ID}
+	}
+	source := &Source{}
+	if end.X != nil && end.Y != nil {
+		x, y := int64(*end.X), int64(*end.Y)
+		source.X, source.Y = &x, &y
+	}
+	return source
+}
+
+// migrateV1Threats converts the threats of a cell and numbers them through the whole model
+func migrateV1Threats(v1Cell cellV1, number *int64) []Threat {
+	threats := make([]Threat, 0, len(v1Cell.Threats))
+	for k, v1Threat := range v1Cell.Threats {
+		*number++
+		id := v1Threat.ID
+		if id == "" {
+			id = uuid.NewSHA1(uuid.NameSpaceOID, fmt.Appendf(nil, "%s:%d", v1Cell.ID, k)).String()
+		}
+		modelType := v1Threat.ModelType
+		if modelType == "" {
+			modelType = "STRIDE"
+		}
+		threats = append(threats, Threat{
+			ID:          id,
+			Title:       v1Threat.Title,
+			Status:      v1Threat.Status,
+			Severity:    v1Threat.Severity,
+			Type:        v1Threat.Type,
+			Description: v1Threat.Description,
+			Mitigation:  v1Threat.Mitigation,
+			ModelType:   modelType,
+			Number:      Nullable[int64]{Set: true, Present: true, Value: *number},
+		})
+	}
+	return threats
+}
+
+// v1LabelText returns the text of the first label of a flow or boundary
+func v1LabelText(v1Cell cellV1) string {
+	for _, label := range v1Cell.Labels {
+		if label.Attrs.Text != nil {
+			return label.Attrs.Text.Text
+		}
+	}
+	return ""
+}
+
+// v1Vertices returns the vertices of a flow or boundary, or nil if it has none
+func v1Vertices(v1Cell cellV1) *[]VertexClass {
+	if len(v1Cell.Vertices) == 0 {
+		return nil
+	}
+	vertices := v1Cell.Vertices
+	return &vertices
+}
@@ -0,0 +1,140 @@
+package threatdragon
+
+import (
+	"log/slog"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+func TestIsV1Project(t *testing.T) {
+	v1, err := os.ReadFile("testdata/threatdragon_v1.json")
+	require.NoError(t, err)
+	v2, err := os.ReadFile("testdata/threatdragon_dynamicIn.json")
+	require.NoError(t, err)
+
+	assert.True(t, isV1Project(v1))
+	assert.False(t, isV1Project(v2))
+	assert.False(t, isV1Project([]byte("no json")))
+}
+
+func TestMigrateV1Project(t *testing.T) {
+	data, err := os.ReadFile("testdata/threatdragon_v1.json")
+	require.NoError(t, err)
+
+	project, err := migrateV1Project(data, slog.Default())
+	require.NoError(t, err)
+
+	assert.Equal(t, defaultVersion, project.Version)
+	assert.Equal(t, "Legacy Web Shop", project.Summary.Title)
+	assert.Equal(t, []Contributor{{Name: "Alice"}}, project.Detail.Contributors)
+	assert.Equal(t, "Bob", project.Detail.Reviewer)
+	assert.Equal(t, int64(3), project.Detail.ThreatTop)
+	require.Len(t, project.Detail.Diagrams, 1)
+
+	diagram := project.Detail.Diagrams[0]
+	assert.Equal(t, "Main Request Data Flow", diagram.Title)
+	assert.Equal(t, "STRIDE", diagram.DiagramType)
+	require.Len(t, diagram.Cells, 6)
+
+	cells := map[string]Cell{}
+	for _, cell := range diagram.Cells {
+		cells[*cell.Data.Name] = cell
+	}
+
+	browser := cells["Browser"]
+	assert.Equal(t, "tm.Actor", browser.Data.Type)
+	assert.Equal(t, "6183b7fa-eba5-4bf8-a0af-c3e30d144a10", browser.ID)
+	assert.Equal(t, &VertexClass{X: 50, Y: 100}, browser.Position)
+	assert.True(t, *browser.Data.ProvidesAuthentication)
+
+	server := cells["Web Server"]
+	assert.Equal(t, "tm.Process", server.Data.Type)
+	assert.Equal(t, &Size{Width: 100, Height: 100}, server.Size)
+	assert.Equal(t, "www-data", *server.Data.PrivilegeLevel)
+	assert.True(t, server.Data.HasOpenThreats)
+	require.Len(t, *server.Data.Threats, 2)
+	threats := *server.Data.Threats
+	assert.Equal(t, "SQL injection", threats[0].Title)
+	assert.Len(t, threats[0].ID, 36)
+	assert.Equal(t, "b0ec2fe5-9d3e-4b76-a87e-2f1c1e3f5d8a", threats[1].ID)
+	assert.Equal(t, "STRIDE", threats[1].ModelType)
+	assert.Equal(t, int64(2), threats[1].Number.Value)
+
+	database := cells["Database"]
+	assert.Equal(t, "tm.Store", database.Data.Type)
+	assert.True(t, *database.Data.StoresCredentials)
+	assert.True(t, *database.Data.IsEncrypted)
+
+	request := cells["Web Request"]
+	assert.Equal(t, "tm.Flow", request.Data.Type)
+	assert.Equal(t, browser.ID, *request.Source.Cell)
+	assert.Equal(t, server.ID, *request.Target.Cell)
+	assert.NotNil(t, request.Source.Port)
+	assert.True(t, request.Data.HasOpenThreats)
+	require.Len(t, *request.Data.Threats, 1)
+	assert.Equal(t, "Man in the middle", (*request.Data.Threats)[0].Title)
+	// flows are migrated after the elements, so their threats are numbered last without gaps
+	assert.Equal(t, int64(1), threats[0].Number.Value)
+	assert.Equal(t, int64(3), (*request.Data.Threats)[0].Number.Value)
+	assert.Equal(t, "HTTPS", *request.Data.Protocol)
+	assert.True(t, *request.Data.IsEncrypted)
+	assert.True(t, *request.Data.IsPublicNetwork)
+	assert.Equal(t, []VertexClass{{X: 300, Y: 80}}, *request.Vertices)
+	assert.Nil(t, cells["Query"].Vertices)
+
+	boundary := cells[""]
+	assert.Equal(t, "tm.Boundary", boundary.Data.Type)
+	assert.Equal(t, int64(300), *boundary.Source.X)
+	assert.Equal(t, int64(260), *boundary.Target.Y)
+
+	// migrating again gives the same threat IDs
+	again, err := migrateV1Project(data, slog.Default())
+	require.NoError(t, err)
+	assert.Equal(t, threats[0].ID, (*again.Detail.Diagrams[0].Cells[1].Data.Threats)[0].ID)
+}
+
+// TestAnalyzeV1 tests that v1 models are analyzed like v2 models
+func TestAnalyzeV1(t *testing.T) {
+	model, err := NewThreatDragonInput("testdata/threatdragon_v1.json", slog.Default()).Analyze()
+	require.NoError(t, err)
+
+	names := []string{}
+	for _, asset := range model.Assets {
+		names = append(names, asset.DisplayName)
+	}
+	assert.ElementsMatch(t, []string{"Browser", "Web Server", "Database"}, names)
+	require.Len(t, model.DataFlows, 2)
+	require.Len(t, model.Boundaries, 1)
+
+	// the boundary line is crossed by the web request only
+	idx := slices.IndexFunc(model.DataFlows, func(df common.DataFlow) bool { return df.Name == "Web Request" })
+	require.GreaterOrEqual(t, idx, 0)
+	assert.Equal(t, []string{model.DataFlows[idx].ID}, model.Boundaries[0].CrossingDataFlows)
+
+	project, ok := model.Extra["ThreatDragonModel"].(Project)
+	require.True(t, ok)
+	assert.Equal(t, defaultVersion, project.Version)
+}
+
+// TestGenerate_MigratedV1 tests that updating a v1 model writes a v2 model
+func TestGenerate_MigratedV1(t *testing.T) {
+	const path = "testdata/testoutput_threatdragon_v1.json"
+	defer os.Remove(path)
+
+	model, err := NewThreatDragonInput("testdata/threatdragon_v1.json", slog.Default()).Analyze()
+	require.NoError(t, err)
+	require.NoError(t, NewThreatdragonOutput(path, dummyChangelog{}, slog.Default()).Generate(model))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.False(t, isV1Project(data))
+
+	project := readProject(t, path)
+	assert.Equal(t, defaultVersion, project.Version)
+	assert.Len(t, project.Detail.Diagrams[0].Cells, 6)
+}
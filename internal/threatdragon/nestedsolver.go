@@ -0,0 +1,248 @@
+package threatdragon
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// region holds the nodes that belong to exactly the same boxes
+type region struct {
+	boxes []string
+	nodes []string
+}
+
+// nestedItem is a group of nodes and boxes that is laid out on its own and then packed into the grid.
+// Its positions are relative to its top left corner.
+type nestedItem struct {
+	w, h  int
+	boxes []PlacedBox
+	nodes []PlacedNode
+}
+
+// SolveNested places boxes that are nested in each other or overlap. Nodes with the same boxes form a region.
+// The regions of boxes that share nodes are placed next to each other, so that every box covers the consecutive
+// columns of its regions. Boxes of the same columns are drawn inside each other.
+// It fails if the regions can not be ordered like this, e.g. for three boxes that overlap in a cycle.
+func SolveNested(boxDefs []BoxDef, nodes []NodeDef, memberships []Membership) (Solution, error) {
+	nodeToBoxes := buildLookup(memberships)
+
+	regions := make([]region, 0)
+	regionIndex := make(map[string]int)
+	free := make([]string, 0)
+	for _, node := range nodes {
+		boxes := make([]string, 0)
+		for _, box := range boxDefs {
+			if nodeToBoxes[node.ID][box.ID] {
+				boxes = append(boxes, box.ID)
+			}
+		}
+		if len(boxes) == 0 {
+			free = append(free, node.ID)
+			continue
+		}
+		key := strings.Join(boxes, "\x00")
+		i, ok := regionIndex[key]
+		if !ok {
+			i = len(regions)
+			regionIndex[key] = i
+			regions = append(regions, region{boxes: boxes})
+		}
+		regions[i].nodes = append(regions[i].nodes, node.ID)
+	}
+
+	items := make([]nestedItem, 0)
+	attempts := 0
+	for _, cluster := range clusterRegions(regions) {
+		ordered, ok := orderRegions(cluster, &attempts)
+		if !ok {
+			return Solution{}, fmt.Errorf("no order of the regions found in which every box covers consecutive regions")
+		}
+		items = append(items, layoutCluster(ordered, boxDefs))
+	}
+	if len(free) > 0 {
+		items = append(items, layoutCluster([]region{{nodes: free}}, boxDefs))
+	}
+	for _, box := range boxDefs {
+		if !slices.ContainsFunc(regions, func(r region) bool { return slices.Contains(r.boxes, box.ID) }) {
+			items = append(items, nestedItem{w: 1, h: 1, boxes: []PlacedBox{{ID: box.ID, W: 1, H: 1}}})
+		}
+	}
+
+	solution := packItems(items)
+	slices.SortStableFunc(solution.Boxes, func(a, b PlacedBox) int {
+		return slices.IndexFunc(boxDefs, func(d BoxDef) bool { return d.ID == a.ID }) -
+			slices.IndexFunc(boxDefs, func(d BoxDef) bool { return d.ID == b.ID })
+	})
+	assignDepths(solution.Boxes)
+	return solution, nil
+}
+
+// clusterRegions groups the regions that are connected by shared boxes
+func clusterRegions(regions []region) [][]region {
+	parent := make([]int, len(regions))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	boxRegion := make(map[string]int)
+	for i, r := range regions {
+		for _, box := range r.boxes {
+			if j, ok := boxRegion[box]; ok {
+				parent[find(i)] = find(j)
+			} else {
+				boxRegion[box] = i
+			}
+		}
+	}
+
+	clusters := make([][]region, 0)
+	clusterIndex := make(map[int]int)
+	for i, r := range regions {
+		root := find(i)
+		k, ok := clusterIndex[root]
+		if !ok {
+			k = len(clusters)
+			clusterIndex[root] = k
+			clusters = append(clusters, nil)
+		}
+		clusters[k] = append(clusters[k], r)
+	}
+	return clusters
+}
+
+// orderRegions orders the regions of a cluster so that the regions of every box follow each other.
+// It gives up when the attempts exceed MAX_BOX_PLACEMENTS.
+func orderRegions(regions []region, attempts *int) ([]region, bool) {
+	remaining := make(map[string]int)
+	for _, r := range regions {
+		for _, box := range r.boxes {
+			remaining[box]++
+		}
+	}
+	closed := make(map[string]bool)
+	used := make([]bool, len(regions))
+	order := make([]region, 0, len(regions))
+
+	var backtrack func() bool
+	backtrack = func() bool {
+		if len(order) == len(regions) {
+			return true
+		}
+		for i, r := range regions {
+			if used[i] {
+				continue
+			}
+			*attempts++
+			if *attempts > MAX_BOX_PLACEMENTS {
+				return false
+			}
+
+			// boxes of the previous region that do not continue are closed and must not have regions left
+			var previous []string
+			if len(order) > 0 {
+				previous = order[len(order)-1].boxes
+			}
+			closing := make([]string, 0)
+			valid := true
+			for _, box := range previous {
+				if !slices.Contains(r.boxes, box) {
+					closing = append(closing, box)
+					valid = valid && remaining[box] == 0
+				}
+			}
+			for _, box := range r.boxes {
+				valid = valid && !closed[box]
+			}
+			if !valid {
+				continue
+			}
+
+			used[i] = true
+			order = append(order, r)
+			for _, box := range closing {
+				closed[box] = true
+			}
+			for _, box := range r.boxes {
+				remaining[box]--
+			}
+			if backtrack() {
+				return true
+			}
+			for _, box := range r.boxes {
+				remaining[box]++
+			}
+			for _, box := range closing {
+				closed[box] = false
+			}
+			order = order[:len(order)-1]
+			used[i] = false
+		}
+		return false
+	}
+
+	return order, backtrack()
+}
+
+// layoutCluster places the ordered regions of a cluster side by side. The nodes of a region fill a square of columns.
+// Every box spans the columns of its regions and the full height of the cluster.
+func layoutCluster(regions []region, boxDefs []BoxDef) nestedItem {
+	item := nestedItem{}
+	columns := make(map[string][2]int) // box ID -> first and last column
+	for _, r := range regions {
+		width := int(math.Ceil(math.Sqrt(float64(len(r.nodes)))))
+		for i, node := range r.nodes {
+			item.nodes = append(item.nodes, PlacedNode{ID: node, X: item.w + i%width, Y: i / width})
+		}
+		item.h = max(item.h, (len(r.nodes)+width-1)/width)
+
+		for _, box := range r.boxes {
+			span, ok := columns[box]
+			if !ok {
+				span[0] = item.w
+			}
+			span[1] = item.w + width - 1
+			columns[box] = span
+		}
+		item.w += width
+	}
+
+	for _, box := range boxDefs {
+		if span, ok := columns[box.ID]; ok {
+			item.boxes = append(item.boxes, PlacedBox{ID: box.ID, X: span[0], W: span[1] - span[0] + 1, H: item.h})
+		}
+	}
+	return item
+}
+
+// packItems packs the items row by row into the grid. A row holds items until it is GRID_W columns wide.
+func packItems(items []nestedItem) Solution {
+	solution := Solution{Boxes: []PlacedBox{}, Nodes: []PlacedNode{}}
+	x, y, rowHeight := 0, 0, 0
+	for _, item := range items {
+		if x > 0 && x+item.w > GRID_W {
+			x, y, rowHeight = 0, y+rowHeight, 0
+		}
+		for _, box := range item.boxes {
+			box.X += x
+			box.Y += y
+			solution.Boxes = append(solution.Boxes, box)
+		}
+		for _, node := range item.nodes {
+			node.X += x
+			node.Y += y
+			solution.Nodes = append(solution.Nodes, node)
+		}
+		x += item.w
+		rowHeight = max(rowHeight, item.h)
+	}
+	return solution
+}
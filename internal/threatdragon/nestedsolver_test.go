@@ -0,0 +1,157 @@
+package threatdragon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// boundaryRect returns the rectangle of a placed box in the diagram
+func boundaryRect(s *Solution, id string) *common.Rectangle {
+	x, y, w, h := s.GetBoundaryPosition(id)
+	return common.NewRectangle(x, y, w, h)
+}
+
+// nodeRect returns the rectangle of a placed node, drawn as an actor, the largest cell
+func nodeRect(s *Solution, id string) *common.Rectangle {
+	x, y := s.GetPosition(id)
+	return common.NewRectangle(x, y, solutionCellWidth, solutionCellHeight)
+}
+
+// assertPlacement checks that every node is drawn inside exactly the boxes it is a member of
+func assertPlacement(t *testing.T, s *Solution, boxDefs []BoxDef, nodeDefs []NodeDef, memberships []Membership) {
+	t.Helper()
+	lookup := buildLookup(memberships)
+	for _, node := range nodeDefs {
+		for _, box := range boxDefs {
+			inside := nodeRect(s, node.ID).IsContained(boundaryRect(s, box.ID))
+			assert.Equal(t, lookup[node.ID][box.ID], inside, "node %s in box %s", node.ID, box.ID)
+		}
+	}
+	for i, a := range nodeDefs {
+		for _, b := range nodeDefs[i+1:] {
+			ax, ay := s.GetPosition(a.ID)
+			bx, by := s.GetPosition(b.ID)
+			assert.False(t, ax == bx && ay == by, "nodes %s and %s share a position", a.ID, b.ID)
+		}
+	}
+}
+
+func TestSolveNested_Nested(t *testing.T) {
+	// a kubernetes cluster with two namespaces and an ingress that is in no namespace
+	boxDefs := boxes("cluster", "shop", "monitoring")
+	nodeDefs := nodes("ingress", "web", "api", "db", "prometheus", "user")
+	memberships := []Membership{
+		{NodeID: "ingress", BoxID: "cluster"},
+		{NodeID: "web", BoxID: "cluster"}, {NodeID: "web", BoxID: "shop"},
+		{NodeID: "api", BoxID: "cluster"}, {NodeID: "api", BoxID: "shop"},
+		{NodeID: "db", BoxID: "cluster"}, {NodeID: "db", BoxID: "shop"},
+		{NodeID: "prometheus", BoxID: "cluster"}, {NodeID: "prometheus", BoxID: "monitoring"},
+	}
+
+	s, err := SolveNested(boxDefs, nodeDefs, memberships)
+	require.NoError(t, err)
+	require.Len(t, s.Boxes, 3)
+	assert.Equal(t, "cluster", s.Boxes[0].ID)
+	assert.Equal(t, 1, s.Boxes[0].Depth)
+	assert.Equal(t, 0, s.Boxes[1].Depth)
+	assert.Equal(t, 0, s.Boxes[2].Depth)
+
+	assertPlacement(t, &s, boxDefs, nodeDefs, memberships)
+	assert.True(t, boundaryRect(&s, "shop").IsContained(boundaryRect(&s, "cluster")))
+	assert.True(t, boundaryRect(&s, "monitoring").IsContained(boundaryRect(&s, "cluster")))
+	assert.False(t, boundaryRect(&s, "shop").IsContained(boundaryRect(&s, "monitoring")))
+}
+
+func TestSolveNested_Overlapping(t *testing.T) {
+	// compose services on a frontend and a backend network, the api is on both
+	boxDefs := boxes("frontend", "backend")
+	nodeDefs := nodes("web", "api", "db", "cache")
+	memberships := []Membership{
+		{NodeID: "web", BoxID: "frontend"},
+		{NodeID: "api", BoxID: "frontend"}, {NodeID: "api", BoxID: "backend"},
+		{NodeID: "db", BoxID: "backend"},
+		{NodeID: "cache", BoxID: "backend"},
+	}
+
+	s, err := SolveNested(boxDefs, nodeDefs, memberships)
+	require.NoError(t, err)
+	assertPlacement(t, &s, boxDefs, nodeDefs, memberships)
+	assert.False(t, boundaryRect(&s, "frontend").IsContained(boundaryRect(&s, "backend")))
+	assert.False(t, boundaryRect(&s, "backend").IsContained(boundaryRect(&s, "frontend")))
+}
+
+// Boxes with the same members are drawn inside each other in the order they are given
+func TestSolveNested_SameMembers(t *testing.T) {
+	boxDefs := boxes("outer", "inner", "empty")
+	nodeDefs := nodes("a", "b")
+	memberships := []Membership{
+		{NodeID: "a", BoxID: "outer"}, {NodeID: "a", BoxID: "inner"},
+		{NodeID: "b", BoxID: "outer"}, {NodeID: "b", BoxID: "inner"},
+	}
+
+	s, err := SolveNested(boxDefs, nodeDefs, memberships)
+	require.NoError(t, err)
+	assertPlacement(t, &s, boxDefs, nodeDefs, memberships)
+	assert.True(t, boundaryRect(&s, "inner").IsContained(boundaryRect(&s, "outer")))
+	assert.False(t, boundaryRect(&s, "empty").IsContained(boundaryRect(&s, "outer")))
+}
+
+// Three boxes that overlap in a cycle can not be drawn with consecutive columns
+func TestSolveNested_Cycle(t *testing.T) {
+	memberships := []Membership{
+		{NodeID: "ab", BoxID: "A"}, {NodeID: "ab", BoxID: "B"},
+		{NodeID: "bc", BoxID: "B"}, {NodeID: "bc", BoxID: "C"},
+		{NodeID: "ca", BoxID: "C"}, {NodeID: "ca", BoxID: "A"},
+	}
+
+	_, err := SolveNested(boxes("A", "B", "C"), nodes("ab", "bc", "ca"), memberships)
+	assert.Error(t, err)
+}
+
+// The model that Solve gives up on is placed by SolveModel
+func TestSolveModel_NestedAndOverlapping(t *testing.T) {
+	assets := []common.Asset{{ID: "lb"}, {ID: "app"}, {ID: "db"}, {ID: "cache"}, {ID: "bucket"}}
+	boundaries := []common.TrustBoundary{
+		{ID: "vpc", ContainedAssets: []string{"lb", "app", "db", "cache"}},
+		{ID: "public", ContainedAssets: []string{"lb"}},
+		{ID: "private", ContainedAssets: []string{"app", "db"}},
+		{ID: "sg-lb", ContainedAssets: []string{"lb"}},
+		{ID: "sg-app", ContainedAssets: []string{"app"}},
+		{ID: "sg-db", ContainedAssets: []string{"db", "cache"}},
+	}
+
+	s, err := SolveModel(assets, boundaries)
+	require.NoError(t, err)
+
+	boxDefs := make([]BoxDef, 0, len(boundaries))
+	memberships := make([]Membership, 0)
+	for _, boundary := range boundaries {
+		boxDefs = append(boxDefs, BoxDef{ID: boundary.ID})
+		for _, id := range boundary.ContainedAssets {
+			memberships = append(memberships, Membership{NodeID: id, BoxID: boundary.ID})
+		}
+	}
+	assertPlacement(t, &s, boxDefs, nodes("lb", "app", "db", "cache", "bucket"), memberships)
+	for _, id := range []string{"public", "private", "sg-lb", "sg-app", "sg-db"} {
+		assert.True(t, boundaryRect(&s, id).IsContained(boundaryRect(&s, "vpc")), id)
+	}
+	assert.True(t, boundaryRect(&s, "sg-lb").IsContained(boundaryRect(&s, "public")))
+	assert.True(t, boundaryRect(&s, "sg-app").IsContained(boundaryRect(&s, "private")))
+}
+
+func TestAssignDepths(t *testing.T) {
+	placed := []PlacedBox{
+		{ID: "outer", X: 0, Y: 0, W: 4, H: 2},
+		{ID: "middle", X: 0, Y: 0, W: 2, H: 2},
+		{ID: "inner", X: 0, Y: 0, W: 2, H: 2},
+		{ID: "beside", X: 2, Y: 0, W: 3, H: 2},
+	}
+	assignDepths(placed)
+	assert.Equal(t, 2, placed[0].Depth)
+	assert.Equal(t, 1, placed[1].Depth)
+	assert.Equal(t, 0, placed[2].Depth)
+	assert.Equal(t, 0, placed[3].Depth)
+}
@@ -15,8 +15,12 @@ import (
 
 type ThreatdragonOutput struct {
 	OutputPath string
-	cl         changelog
-	logger     *slog.Logger
+	// DiagramStrategy decides how new models are split into diagrams
+	DiagramStrategy DiagramStrategy
+	// Layout decides how the cells of new diagrams are placed
+	Layout LayoutStrategy
+	cl     changelog
+	logger *slog.Logger
 }
 
 type changelog interface {
@@ -25,6 +29,9 @@ type changelog interface {
 
 var ErrAssetTypeNoMapping = errors.New("the used AssetType has no mapping to threatdragonAssetInfo")
 
+// defaultVersion is the Threat Dragon version of generated and migrated models
+const defaultVersion = "2.5.0"
+
 func NewThreatdragonOutput(outputPath string, cl changelog, logger *slog.Logger) *ThreatdragonOutput {
 	return &ThreatdragonOutput{
 		OutputPath: outputPath,
@@ -71,7 +78,6 @@ func (tdo *ThreatdragonOutput) Generate(model *common.ThreatModel) error {
 }
 
 func (tdo *ThreatdragonOutput) generateNewModel(model *common.ThreatModel) (*Project, error) {
-	const defaultVersion = "2.5.0"
 	const defaultTitle = "new Threatdragon Output"
 	const defaultOwner = ""
 	var defaultDescription = "this model is auto generated by threatcat"
@@ -90,52 +96,161 @@ func (tdo *ThreatdragonOutput) generateNewModel(model *common.ThreatModel) (*Pro
 		},
 	}
 
-	var placementLogic cellPlacer
-
-	solution, err := SolveModel(model.Assets, model.Boundaries)
-	if err != nil {
-		tdo.logger.Warn("Failed to solve with trust boundaries. Fallback to simple placement")
-		placementLogic = newSimplePlacement()
-	} else {
-		tdo.logger.Info("Solution for trust boundary placement was found")
-		placementLogic = &solution
-	}
-
-	trustBoundaryCells := tdo.generatePlaceTrustBoundaries(model.Boundaries, placementLogic)
-	cells, err := tdo.generatePlaceNewCellsAndDataflows(nil, model.Assets, model.DataFlows, placementLogic)
-	if err != nil {
-		return nil, err
+	groups := splitDiagrams(model, tdo.DiagramStrategy)
+	titles := make([]string, 0, len(groups))
+	assetDiagrams := make(map[string]int) // asset ID -> diagram index
+	for i, group := range groups {
+		titles = append(titles, group.title)
+		for _, asset := range group.assets {
+			assetDiagrams[asset.ID] = i
+		}
 	}
+	tdo.logger.Debug("Split model into diagrams", "strategy", tdo.DiagramStrategy, "diagramCount", len(groups))
 
-	outputJson.Detail.Diagrams = []Diagram{
-		{
-			ID:          0,
-			Title:       "new diagram 0",                                //TODO: add name to config file, if no input is given
+	outputJson.Detail.Diagrams = make([]Diagram, 0, len(groups))
+	for i, group := range groups {
+		cells, err := tdo.generateDiagramCells(model, group, assetDiagrams, titles, i)
+		if err != nil {
+			return nil, err
+		}
+		outputJson.Detail.Diagrams = append(outputJson.Detail.Diagrams, Diagram{
+			ID:          int64(i),
+			Title:       group.title,                                    //TODO: add name to config file, if no input is given
 			DiagramType: "STRIDE",                                       //TODO: add diagram type to config file if no input is given
 			Placeholder: &defaultDescription,                            //TODO: get better infos in the description if not given
 			Thumbnail:   "./public/content/images/thumbnail.stride.jpg", //TODO is it okay to hardcode this path? What if Threatdragon changes their file layout? //replace only if not given
 			Version:     defaultVersion,
-			Cells:       append(cells, trustBoundaryCells...),
-		},
+			Cells:       cells,
+		})
 	}
+	outputJson.Detail.DiagramTop = int64(len(groups))
 	return &outputJson, nil
 }
 
+// generateDiagramCells places the assets, trust boundaries and dataflows of one diagram of a new model.
+// Flows to assets of other diagrams are connected to references of these assets.
+func (tdo *ThreatdragonOutput) generateDiagramCells(model *common.ThreatModel, group diagramGroup, assetDiagrams map[string]int, titles []string, i int) ([]Cell, error) {
+	boundaries := diagramBoundaries(model.Boundaries, assetDiagrams, i)
+	dataflows, references := diagramDataflows(model, assetDiagrams, titles, i, nil)
+
+	placed := slices.Clone(group.assets)
+	for _, reference := range references {
+		placed = append(placed, common.Asset{ID: referenceKey(reference.asset.ID), DisplayName: reference.asset.DisplayName})
+	}
+
+	placementLogic := tdo.newDiagramPlacement(placed, dataflows, boundaries, group.title)
+
+	trustBoundaryCells := tdo.generatePlaceTrustBoundaries(boundaries, placementLogic)
+	referenceCells, err := tdo.generateReferenceCells(references, placementLogic)
+	if err != nil {
+		return nil, err
+	}
+	cells, err := tdo.generatePlaceNewCellsAndDataflows(referenceCells, group.assets, dataflows, placementLogic)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Concat(cells, referenceCells, trustBoundaryCells), nil
+}
+
+// newDiagramPlacement places the cells of a new diagram with the chosen layout. If the trust boundaries cannot be
+// drawn with it, the grid layout is tried and then the simple placement.
+func (tdo *ThreatdragonOutput) newDiagramPlacement(assets []common.Asset, dataflows []common.DataFlow, boundaries []common.TrustBoundary, title string) cellPlacer {
+	if tdo.Layout == LayoutLayered {
+		solution, err := SolveLayered(assets, dataflows, boundaries)
+		if err == nil {
+			tdo.logger.Info("Layered layout was found", "diagram", title)
+			return &solution
+		}
+		tdo.logger.Warn("Failed to lay out the diagram in layers. Fallback to grid placement", "diagram", title, "error", err)
+	}
+
+	solution, err := SolveModel(assets, boundaries)
+	if err != nil {
+		tdo.logger.Warn("Failed to solve with trust boundaries. Fallback to simple placement", "diagram", title)
+		return newSimplePlacement()
+	}
+	tdo.logger.Info("Solution for trust boundary placement was found", "diagram", title)
+	return &solution
+}
+
 func (tdo *ThreatdragonOutput) updateExistingModel(model *common.ThreatModel, existingTD Project) (*Project, error) {
-	placements := make([]*simplePlacement, len(existingTD.Detail.Diagrams))
+	placements := make([]*incrementalPlacement, len(existingTD.Detail.Diagrams))
 	existingAssets := make([]string, 0, len(model.Assets))
+	existingDataflows := make([]string, 0, len(model.DataFlows))
+	existingBoundaries := make([]string, 0, len(model.Boundaries))
+	boundaryCells := make(map[string]common.TrustBoundary)                // cell ID -> boundary, to fit the boxes after placement
+	cellAssetIDs := make(map[string]string)                               // cell ID -> asset ID, to find the contained assets of boundaries
+	assetDiagrams := make(map[string]int)                                 // asset ID -> diagram index, to place new assets next to related ones
+	boundaryDiagrams := make(map[string]int)                              // boundary ID -> diagram index
+	referencedAssets := make([][]string, len(existingTD.Detail.Diagrams)) // IDs of the assets referenced in each diagram
 
 	for i, diagram := range existingTD.Detail.Diagrams {
 		updatedCells := make([]Cell, 0, len(diagram.Cells))
 		removedCells := make([]Cell, 0, len(diagram.Cells))
 
 		for j, cell := range diagram.Cells {
+			// Flows are updated with the dataflow that was read from them
+			if isFlow(cell) {
+				idx := slices.IndexFunc(model.DataFlows, func(df common.DataFlow) bool {
+					indices, ok := df.Extra["ThreatDragonDiagramCellIdx"].(string)
+					return ok && indices == fmt.Sprintf("%d-%d", i, j)
+				})
+				switch {
+				case idx >= 0:
+					updatedCells = append(updatedCells, tdo.updateDataflowCell(cell, model.DataFlows[idx]))
+					existingDataflows = append(existingDataflows, model.DataFlows[idx].ID)
+				case isConnectedToolFlow(cell, tdo.logger):
+					// the merger dropped the dataflow, because its source no longer has it
+					tdo.logger.Debug("Dataflow is no longer in the model. Removing.", "cellID", cell.ID)
+				default:
+					// flows drawn by the user with a loose end cannot be read and are kept as they are
+					updatedCells = append(updatedCells, cell)
+				}
+				continue
+			}
+
+			// Trust boundaries are updated with the boundary that was read from them
+			if isCellTrustBoudary(&cell) || isBoundaryCurve(cell) {
+				idx := slices.IndexFunc(model.Boundaries, func(b common.TrustBoundary) bool {
+					indices, ok := b.Extra["ThreatDragonDiagramCellIdx"].(string)
+					return ok && indices == fmt.Sprintf("%d-%d", i, j)
+				})
+				switch {
+				case idx >= 0:
+					updatedCells = append(updatedCells, tdo.updateBoundaryCell(cell, model.Boundaries[idx]))
+					existingBoundaries = append(existingBoundaries, model.Boundaries[idx].ID)
+					if isCellTrustBoudary(&cell) {
+						// curves are drawn by the user and keep their course
+						boundaryCells[cell.ID] = model.Boundaries[idx]
+						boundaryDiagrams[model.Boundaries[idx].ID] = i
+					}
+				case extractID(cell.Data.Description, tdo.logger) != "":
+					// the merger dropped the boundary, because its source no longer has it
+					tdo.logger.Debug("Trust boundary is no longer in the model. Removing.", "cellID", cell.ID)
+				default:
+					updatedCells = append(updatedCells, cell)
+				}
+				continue
+			}
+
 			// Check if the cell is relevant for analysis
 			if !isRelevantType(cell.Data.Type) {
 				updatedCells = append(updatedCells, cell)
 				continue
 			}
 
+			// References to assets of other diagrams are kept as long as the asset is in the model
+			if ref := extractReference(cell.Data.Description); ref != "" {
+				idx := slices.IndexFunc(model.Assets, func(a common.Asset) bool { return a.ID == ref })
+				if idx < 0 {
+					removedCells = append(removedCells, cell)
+					continue
+				}
+				updatedCells = append(updatedCells, tdo.updateReferenceCell(cell, model.Assets[idx]))
+				referencedAssets[i] = append(referencedAssets[i], ref)
+				continue
+			}
+
 			// Check if the model has the asset
 			idx := slices.IndexFunc(model.Assets, func(a common.Asset) bool {
 				indices, ok := a.Extra["ThreatDragonDiagramCellIdx"].(string)
@@ -154,6 +269,8 @@ func (tdo *ThreatdragonOutput) updateExistingModel(model *common.ThreatModel, ex
 				}
 				updatedCells = append(updatedCells, cell)
 				existingAssets = append(existingAssets, asset.ID)
+				cellAssetIDs[cell.ID] = asset.ID
+				assetDiagrams[asset.ID] = i
 			} else {
 				// The asset is not in the model, so we save it to remove its connections in the next step
 				removedCells = append(removedCells, cell)
@@ -166,9 +283,7 @@ func (tdo *ThreatdragonOutput) updateExistingModel(model *common.ThreatModel, ex
 		// after updating switch the diagram cells with the updated cells
 		existingTD.Detail.Diagrams[i].Cells = updatedCells
 
-		placement := newSimplePlacement()
-		placement.determineStartingPoint(updatedCells)
-		placements[i] = placement
+		placements[i] = newIncrementalPlacement(updatedCells, model, cellAssetIDs, boundaryCells)
 	}
 
 	newAssets := slices.DeleteFunc(slices.Clone(model.Assets), func(a common.Asset) bool {
@@ -177,16 +292,201 @@ func (tdo *ThreatdragonOutput) updateExistingModel(model *common.ThreatModel, ex
 		})
 	})
 
-	newDataflows := []common.DataFlow{} // TODO: implement data flow updating
+	newAssetDiagrams(model, newAssets, assetDiagrams, boundaryDiagrams)
 
-	newCells, err := tdo.generatePlaceNewCellsAndDataflows(existingTD.Detail.Diagrams[0].Cells, newAssets, newDataflows, placements[0])
-	if err != nil {
-		return nil, err
+	newDataflows := slices.DeleteFunc(slices.Clone(model.DataFlows), func(df common.DataFlow) bool {
+		return slices.Contains(existingDataflows, df.ID)
+	})
+	allCells := make([]Cell, 0)
+	titles := make([]string, 0, len(existingTD.Detail.Diagrams))
+	for _, diagram := range existingTD.Detail.Diagrams {
+		allCells = append(allCells, diagram.Cells...)
+		titles = append(titles, diagram.Title)
+	}
+	newDataflows = tdo.connectableDataflows(newDataflows, allCells, newAssets)
+	newModel := &common.ThreatModel{Assets: model.Assets, DataFlows: newDataflows}
+
+	// new assets and dataflows are drawn in their diagram. Flows to assets of other diagrams get a reference.
+	for i := range existingTD.Detail.Diagrams {
+		diagramAssets := slices.DeleteFunc(slices.Clone(newAssets), func(a common.Asset) bool { return assetDiagrams[a.ID] != i })
+		dataflows, references := diagramDataflows(newModel, assetDiagrams, titles, i, referencedAssets[i])
+		if len(diagramAssets) == 0 && len(dataflows) == 0 {
+			continue
+		}
+
+		referenceCells, err := tdo.generateReferenceCells(references, placements[i])
+		if err != nil {
+			return nil, err
+		}
+		existingCells := slices.Concat(existingTD.Detail.Diagrams[i].Cells, referenceCells)
+		newCells, err := tdo.generatePlaceNewCellsAndDataflows(existingCells, diagramAssets, dataflows, placements[i])
+		if err != nil {
+			return nil, err
+		}
+		existingTD.Detail.Diagrams[i].Cells = slices.Concat(existingTD.Detail.Diagrams[i].Cells, newCells, referenceCells)
+		for _, cell := range newCells {
+			if isRelevantType(cell.Data.Type) {
+				cellAssetIDs[cell.ID] = extractID(cell.Data.Description, tdo.logger)
+			}
+		}
+	}
+
+	// boundaries are fitted last, so that they enclose the new assets as well
+	for i := range existingTD.Detail.Diagrams {
+		cells := existingTD.Detail.Diagrams[i].Cells
+		for j := range cells {
+			if boundary, ok := boundaryCells[cells[j].ID]; ok {
+				cells[j] = tdo.fitBoundaryCell(cells[j], boundary, cells, cellAssetIDs)
+			}
+		}
+	}
+
+	newBoundaries := slices.DeleteFunc(slices.Clone(model.Boundaries), func(b common.TrustBoundary) bool {
+		return slices.Contains(existingBoundaries, b.ID)
+	})
+	for i := range existingTD.Detail.Diagrams {
+		boundaries := slices.DeleteFunc(slices.Clone(newBoundaries), func(b common.TrustBoundary) bool {
+			return boundaryDiagram(b, assetDiagrams) != i
+		})
+		existingTD.Detail.Diagrams[i].Cells = append(existingTD.Detail.Diagrams[i].Cells,
+			tdo.generateFittedTrustBoundaries(boundaries, existingTD.Detail.Diagrams[i].Cells, cellAssetIDs)...)
 	}
-	existingTD.Detail.Diagrams[0].Cells = append(existingTD.Detail.Diagrams[0].Cells, newCells...)
 	return &existingTD, nil
 }
 
+// updateBoundaryCell renames a trust boundary box or curve. Position and size of boxes are fitted after all assets are placed.
+func (tdo *ThreatdragonOutput) updateBoundaryCell(cell Cell, boundary common.TrustBoundary) Cell {
+	oldName := valueOr(cell.Data.Name, "")
+	if oldName == boundary.DisplayName {
+		return cell
+	}
+	tdo.logger.Debug("Trust boundary has been renamed", "prevName", oldName, "newName", boundary.DisplayName)
+	tdo.cl.AddEntry(fmt.Sprintf("Trust boundary '%s' has been renamed to '%s'", oldName, boundary.DisplayName))
+	cell.Data.Name = &boundary.DisplayName
+	if cell.Attrs != nil && cell.Attrs.Label != nil {
+		cell.Attrs.Label.Text = boundary.DisplayName
+	}
+	setCurveLabels(&cell, boundary.DisplayName)
+	return cell
+}
+
+// setCurveLabels sets the text of the labels of a flow or boundary curve
+func setCurveLabels(cell *Cell, name string) {
+	for k := range cell.Labels {
+		if cell.Labels[k].String != nil {
+			cell.Labels[k].String = &name
+		} else if cell.Labels[k].LabelLabel != nil {
+			cell.Labels[k].LabelLabel.Attrs.LabelText.Text = name
+		}
+	}
+}
+
+// fitBoundaryCell grows a trust boundary cell, so that it encloses all of its contained assets.
+// Boxes are never shrunk, to keep the layout of the user.
+func (tdo *ThreatdragonOutput) fitBoundaryCell(cell Cell, boundary common.TrustBoundary, cells []Cell, cellAssetIDs map[string]string) Cell {
+	enclosing, ok := enclosingRectangle(boundary.ContainedAssets, cells, cellAssetIDs)
+	if !ok || cell.Position == nil || cell.Size == nil {
+		return cell
+	}
+	current := common.NewRectangle(cell.Position.X, cell.Position.Y, cell.Size.Width, cell.Size.Height)
+	if enclosing.IsContained(current) {
+		return cell
+	}
+
+	fitted := unionRectangle(current, enclosing)
+	tdo.logger.Debug("Resizing trust boundary to enclose its assets", "name", boundary.DisplayName, "rect", fitted)
+	tdo.cl.AddEntry(fmt.Sprintf("Trust boundary '%s' has been resized to enclose its assets", boundary.DisplayName))
+	cell.Position = &VertexClass{X: fitted.Left, Y: fitted.Top}
+	cell.Size = &Size{Width: fitted.Width, Height: fitted.Height}
+	return cell
+}
+
+// generateFittedTrustBoundaries generates a trust boundary cell around the contained assets of every new boundary.
+// Boundaries without any contained asset in the diagram cannot be placed and are skipped.
+func (tdo *ThreatdragonOutput) generateFittedTrustBoundaries(boundaries []common.TrustBoundary, cells []Cell, cellAssetIDs map[string]string) []Cell {
+	newTrustBoundaries := make([]Cell, 0, len(boundaries))
+	for _, boundary := range boundaries {
+		enclosing, ok := enclosingRectangle(boundary.ContainedAssets, cells, cellAssetIDs)
+		if !ok {
+			tdo.logger.Warn("Trust boundary contains no asset of the diagram. Skipping.", "name", boundary.DisplayName)
+			tdo.cl.AddEntry(fmt.Sprintf("Trust boundary '%s' could not be added, because none of its assets was found in the diagram", boundary.DisplayName))
+			continue
+		}
+		tdo.logger.Debug("Generating new trust boundary", "name", boundary.DisplayName)
+		placedBoundary := trustBoundary(enclosing.Left, enclosing.Top, enclosing.Width, enclosing.Height, boundary.DisplayName, analyzerIDTag(boundary.ID))
+		newTrustBoundaries = append(newTrustBoundaries, placedBoundary)
+		tdo.cl.AddEntry(fmt.Sprintf("New trust boundary '%s' has been added from %s", boundary.DisplayName, boundary.Source.ShortString()))
+	}
+	return newTrustBoundaries
+}
+
+// enclosingRectangle returns the smallest rectangle around the cells of the given assets, with the margin
+// that the placement solver leaves around boundaries. It returns false if none of the assets has a cell.
+func enclosingRectangle(assetIDs []string, cells []Cell, cellAssetIDs map[string]string) (*common.Rectangle, bool) {
+	var enclosing *common.Rectangle
+	for _, cell := range cells {
+		if cell.Position == nil || cell.Size == nil || !slices.Contains(assetIDs, cellAssetIDs[cell.ID]) {
+			continue
+		}
+		rect := common.NewRectangle(cell.Position.X, cell.Position.Y, cell.Size.Width, cell.Size.Height)
+		if enclosing == nil {
+			enclosing = rect
+		} else {
+			enclosing = unionRectangle(enclosing, rect)
+		}
+	}
+	if enclosing == nil {
+		return nil, false
+	}
+	return common.NewRectangle(
+		enclosing.Left-solutionBoundaryOversize,
+		enclosing.Top-solutionBoundaryOversize,
+		enclosing.Width+2*solutionBoundaryOversize,
+		enclosing.Height+2*solutionBoundaryOversize,
+	), true
+}
+
+// unionRectangle returns the smallest rectangle that contains both rectangles
+func unionRectangle(a, b *common.Rectangle) *common.Rectangle {
+	left, top := min(a.Left, b.Left), min(a.Top, b.Top)
+	right, bottom := max(a.Left+a.Width, b.Left+b.Width), max(a.Top+a.Height, b.Top+b.Height)
+	return common.NewRectangle(left, top, right-left, bottom-top)
+}
+
+// connectableDataflows returns the new dataflows whose source and target are found in the diagrams or among the new assets.
+// Endpoints can be missing if an asset was renamed in Threat Dragon.
+// These dataflows are skipped instead of failing the whole update.
+func (tdo *ThreatdragonOutput) connectableDataflows(dataflows []common.DataFlow, cells []Cell, newAssets []common.Asset) []common.DataFlow {
+	names := make([]string, 0, len(cells)+len(newAssets))
+	for _, cell := range cells {
+		if isRelevantType(cell.Data.Type) && cell.Data.Name != nil {
+			names = append(names, *cell.Data.Name)
+		}
+	}
+	for _, asset := range newAssets {
+		names = append(names, asset.DisplayName)
+	}
+
+	return slices.DeleteFunc(dataflows, func(df common.DataFlow) bool {
+		for _, endpoint := range []string{df.Source, df.Target} {
+			if !slices.Contains(names, endpoint) {
+				tdo.logger.Warn("Dataflow endpoint was not found in the diagram. Skipping.", "dataflow", df.Name, "endpoint", endpoint)
+				tdo.cl.AddEntry(fmt.Sprintf("Dataflow '%s' could not be added, because '%s' was not found in the diagram", df.Name, endpoint))
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// isConnectedToolFlow checks if a flow was generated by the tool and is connected on both ends.
+// Only these flows are read into the model, so only these can be removed when they are missing in the model.
+func isConnectedToolFlow(cell Cell, logger *slog.Logger) bool {
+	return extractID(cell.Data.Description, logger) != "" &&
+		cell.Source != nil && cell.Source.Cell != nil &&
+		cell.Target != nil && cell.Target.Cell != nil
+}
+
 // Removes connections to removed cells from the updated cells
 // If a curve's source or target is connected to a removed cell, the connection is removed
 func (tdo *ThreatdragonOutput) removeConnections(updatedCells []Cell, removedCells []Cell) []Cell {
@@ -261,6 +561,7 @@ func (tdo *ThreatdragonOutput) updateSource(source *Source, removedCell Cell) *S
 
 type assetTypeInfo struct {
 	IsStore          bool // false for process
+	IsActor          bool // false for process
 	IsWebApplication bool
 }
 
@@ -286,6 +587,10 @@ func assetTypeToThreatdragonAssetInfo(assetType common.AssetType) (assetTypeInfo
 			IsStore:          false,
 			IsWebApplication: false,
 		}, nil
+	case common.AssetTypeExternalEntity:
+		return assetTypeInfo{
+			IsActor: true,
+		}, nil
 	case common.AssetTypeUnknown:
 		//Unkown asset is respresented as a common process
 		return assetTypeInfo{
@@ -343,9 +648,11 @@ func (tdo *ThreatdragonOutput) updateCell(cell Cell, asset common.Asset) (Cell,
 		return Cell{}, err
 	}
 	var newType string
-	//currently the only types that are supported are tm.Process and tm.Store
+	//currently the only types that are supported are tm.Process, tm.Store and tm.Actor
 	if threatdragonAssetInfo.IsStore {
 		newType = "tm.Store"
+	} else if threatdragonAssetInfo.IsActor {
+		newType = "tm.Actor"
 	} else {
 		newType = "tm.Process"
 	}
@@ -378,13 +685,63 @@ func (tdo *ThreatdragonOutput) updateCell(cell Cell, asset common.Asset) (Cell,
 
 	cell.Data.Threats = updateThreats(asset, tdo.logger, tdo.cl)
 
-	cell.Attrs.Text = &TextClass{
-		Text: asset.DisplayName,
+	if newType == "tm.Store" && (cell.Data.StoresCredentials != nil || asset.StoresCredentials) {
+		cell.Data.StoresCredentials = &asset.StoresCredentials
+	}
+
+	// cells freshly created in ThreatDragon (e.g. actors) have no text attribute until they are renamed
+	if cell.Attrs.Text != nil || oldName != asset.DisplayName {
+		cell.Attrs.Text = &TextClass{
+			Text: asset.DisplayName,
+		}
 	}
 
 	return cell, nil
 }
 
+// updateDataflowCell updates name, protocol, encryption, public network and direction of a flow cell.
+// The routing of the flow, i.e. its endpoints and vertices, is kept.
+func (tdo *ThreatdragonOutput) updateDataflowCell(cell Cell, dataflow common.DataFlow) Cell {
+	oldName := ""
+	if cell.Data.Name != nil {
+		oldName = *cell.Data.Name
+	}
+	if oldName != dataflow.Name {
+		tdo.logger.Debug("Dataflow has been renamed", "prevName", oldName, "newName", dataflow.Name)
+		tdo.cl.AddEntry(fmt.Sprintf("Dataflow '%s' has been renamed to '%s'", oldName, dataflow.Name))
+		cell.Data.Name = &dataflow.Name
+		setCurveLabels(&cell, dataflow.Name)
+	}
+
+	if oldProtocol := valueOr(cell.Data.Protocol, ""); oldProtocol != dataflow.Protocol {
+		tdo.cl.AddEntry(fmt.Sprintf("Dataflow '%s' protocol has changed from '%s' to '%s'", dataflow.Name, oldProtocol, dataflow.Protocol))
+		cell.Data.Protocol = &dataflow.Protocol
+	}
+	if valueOr(cell.Data.IsEncrypted, false) != dataflow.Encrypted {
+		tdo.cl.AddEntry(fmt.Sprintf("Dataflow '%s' is now %s", dataflow.Name, map[bool]string{true: "encrypted", false: "unencrypted"}[dataflow.Encrypted]))
+		cell.Data.IsEncrypted = &dataflow.Encrypted
+	}
+	if valueOr(cell.Data.IsPublicNetwork, false) != dataflow.PublicNetwork {
+		tdo.cl.AddEntry(fmt.Sprintf("Dataflow '%s' now %s a public network", dataflow.Name, map[bool]string{true: "uses", false: "no longer uses"}[dataflow.PublicNetwork]))
+		cell.Data.IsPublicNetwork = &dataflow.PublicNetwork
+	}
+	if valueOr(cell.Data.IsBidirectional, false) != dataflow.Bidirectional {
+		tdo.cl.AddEntry(fmt.Sprintf("Dataflow '%s' is now %s", dataflow.Name, map[bool]string{true: "bidirectional", false: "unidirectional"}[dataflow.Bidirectional]))
+		cell.Data.IsBidirectional = &dataflow.Bidirectional
+		if cell.Attrs != nil && cell.Attrs.Line != nil {
+			marker := ""
+			if dataflow.Bidirectional {
+				marker = "block"
+			}
+			cell.Attrs.Line.SourceMarker = &Marker{Contributor: &Contributor{Name: marker}}
+		}
+	}
+
+	cell.Data.Threats = updateThreatList(dataflow.Threats, dataflow.Extra, tdo.logger, tdo.cl)
+	cell.Data.HasOpenThreats = cell.Data.Threats != nil && hasOpenThreats(*cell.Data.Threats)
+	return cell
+}
+
 func (tdo *ThreatdragonOutput) updateCellTypePreserveFields(cell Cell, asset common.Asset) (Cell, error) {
 	// generate a new cell based on the new type
 	newCell, err := tdo.generatePlacedCell(asset, dontPlace{})
@@ -432,10 +789,15 @@ func (tdo *ThreatdragonOutput) updateCellTypePreserveFields(cell Cell, asset com
 	return *newCell, nil
 }
 
-// generateThreats converts a slice of common.Threat to a slice of ThreatDragon Threat
+// generateThreats converts the threats of an asset to a slice of ThreatDragon Threat
 func generateThreats(asset common.Asset) []Threat {
+	return generateThreatList(asset.Threats)
+}
+
+// generateThreatList converts a slice of common.Threat to a slice of ThreatDragon Threat
+func generateThreatList(threats []common.Threat) []Threat {
 	tdThreats := []Threat{}
-	for _, threat := range asset.Threats {
+	for _, threat := range threats {
 		tdThreats = append(tdThreats, generateThreat(threat))
 	}
 	return tdThreats
@@ -484,23 +846,29 @@ func generateThreat(threat common.Threat) Threat {
 // It preserves the ID of existing threats and adds new threats
 // It also logs changes to the changelog
 func updateThreats(asset common.Asset, logger *slog.Logger, cl changelog) *[]Threat {
-	// safely obtain ThreatModelMap from asset.Extra if present
-	existingThreats := common.GetOr(asset.Extra, "ThreatModelMap", make(map[int]Threat))
+	return updateThreatList(asset.Threats, asset.Extra, logger, cl)
+}
 
-	updatedThreats := make([]Threat, 0, len(asset.Threats))
+// updateThreatList updates the threats of an asset or dataflow.
+// The extra data holds the original ThreatDragon threats, so that existing threats are kept unchanged.
+func updateThreatList(threats []common.Threat, extra map[string]any, logger *slog.Logger, cl changelog) *[]Threat {
+	// safely obtain ThreatModelMap from the extra data if present
+	existingThreats := common.GetOr(extra, "ThreatModelMap", make(map[int]Threat))
+
+	updatedThreats := make([]Threat, 0, len(threats))
 
 	// fast path: return nil when nil
-	if asset.Threats == nil && existingThreats == nil {
+	if threats == nil && existingThreats == nil {
 		return nil
 	}
 
 	// fast path: return empty slice when empty
-	if (len(asset.Threats) == 0) && (len(existingThreats) == 0) {
+	if (len(threats) == 0) && (len(existingThreats) == 0) {
 		return &updatedThreats
 	}
 
-	// Iterate over all threats in the asset
-	for _, threat := range asset.Threats {
+	// Iterate over all threats
+	for _, threat := range threats {
 		existingThreat := Threat{}
 		exists := false
 		if threat.MapIndex >= 0 {
@@ -577,7 +945,10 @@ func (tdo *ThreatdragonOutput) generatePlaceNewCellsAndDataflows(existingCells [
 		if err != nil {
 			return nil, fmt.Errorf("failed to generated connected dataflow: %w", err)
 		}
+		routeDataflow(connectedDataflow, placedCells)
 		newCells = append(newCells, *connectedDataflow)
+		placedCells = append(placedCells, *connectedDataflow)
+		tdo.cl.AddEntry(fmt.Sprintf("New dataflow '%s' has been added from %s", dataflow.Name, dataflow.DataSource.ShortString()))
 	}
 
 	return newCells, nil
@@ -598,7 +969,9 @@ func (tdo *ThreatdragonOutput) generatePlacedCell(asset common.Asset, placementL
 
 	var cell Cell
 	if isStore {
-		cell = store(name, description, threats, x, y)
+		cell = store(name, description, asset.StoresCredentials, threats, x, y)
+	} else if threatdragonAssetInfo.IsActor {
+		cell = actor(name, description, threats, x, y)
 	} else {
 		cell = process(name, description, isWebApp, threats, x, y)
 	}
@@ -609,6 +982,10 @@ func (tdo *ThreatdragonOutput) generatePlacedCell(asset common.Asset, placementL
 func (tdo *ThreatdragonOutput) generateConnectedDataflow(dataflow common.DataFlow, placedCells []Cell) (*Cell, error) {
 	var source, target *Cell
 	for _, cell := range placedCells {
+		// only assets can be connected, flows and boundaries can have the same name
+		if !isRelevantType(cell.Data.Type) || cell.Data.Name == nil {
+			continue
+		}
 		if *cell.Data.Name == dataflow.Source {
 			source = &cell
 		}
@@ -620,7 +997,7 @@ func (tdo *ThreatdragonOutput) generateConnectedDataflow(dataflow common.DataFlo
 		return nil, fmt.Errorf("failed to find cell id for dataflow source connection: '%s'", dataflow.Source)
 	}
 	if target == nil {
-		return nil, fmt.Errorf("failed to find cell id for dataflow target connection: '%s'", dataflow.Source)
+		return nil, fmt.Errorf("failed to find cell id for dataflow target connection: '%s'", dataflow.Target)
 	}
 
 	description := analyzerIDTag(dataflow.ID)
@@ -635,6 +1012,9 @@ func (tdo *ThreatdragonOutput) generateConnectedDataflow(dataflow common.DataFlo
 		target,
 		dataflow.Bidirectional,
 	)
+	threats := generateThreatList(dataflow.Threats)
+	cell.Data.Threats = &threats
+	cell.Data.HasOpenThreats = hasOpenThreats(threats)
 
 	return &cell, nil
 }
@@ -699,7 +1079,7 @@ func process(name, description string, isWebApp bool, threats []Threat, x, y flo
 
 // defaultStore creates a new store cell with default values
 // The values were copied from a freshly created store in ThreatDragon
-func store(name, description string, threats []Threat, x, y float64) Cell {
+func store(name, description string, storesCredentials bool, threats []Threat, x, y float64) Cell {
 	return Cell{
 		Position: &VertexClass{
 			X: x,
@@ -750,13 +1130,66 @@ func store(name, description string, threats []Threat, x, y float64) Cell {
 			IsALog:            boolPtr(false),
 			IsEncrypted:       boolPtr(false),
 			IsSigned:          boolPtr(false),
-			StoresCredentials: boolPtr(false),
+			StoresCredentials: &storesCredentials,
 			StoresInventory:   boolPtr(false),
 			Threats:           &threats,
 		},
 	}
 }
 
+// actor creates a new actor cell with default values
+// The values were copied from a freshly created actor in ThreatDragon
+func actor(name, description string, threats []Threat, x, y float64) Cell {
+	return Cell{
+		Position: &VertexClass{
+			X: x,
+			Y: y,
+		},
+		Size: &Size{
+			Height: 80,
+			Width:  160,
+		},
+		Attrs: &CellAttrs{
+			Body: &Body{
+				Stroke:          stringPtr("#333333"),
+				StrokeWidth:     1.5,
+				StrokeDasharray: nullString(),
+			},
+			Text: &TextClass{
+				Text: name,
+			},
+		},
+		Visible: boolPtr(true),
+		Shape:   "actor",
+		Ports: &Ports{
+			Groups: PortGroups{
+				Top:    defaultPortGroup("top"),
+				Right:  defaultPortGroup("right"),
+				Bottom: defaultPortGroup("bottom"),
+				Left:   defaultPortGroup("left"),
+			},
+			Items: []Port{
+				defaultPort("top"),
+				defaultPort("right"),
+				defaultPort("bottom"),
+				defaultPort("left"),
+			},
+		},
+		ID:     uuid.NewString(),
+		ZIndex: 1,
+		Data: Data{
+			Type:                   "tm.Actor",
+			Name:                   &name,
+			Description:            &description,
+			OutOfScope:             boolPtr(false),
+			ReasonOutOfScope:       stringPtr(""),
+			HasOpenThreats:         hasOpenThreats(threats),
+			ProvidesAuthentication: boolPtr(false),
+			Threats:                &threats,
+		},
+	}
+}
+
 func hasOpenThreats(threats []Threat) bool {
 	return slices.ContainsFunc(threats, func(t Threat) bool {
 		return t.Status == common.StatusString(common.Open)
@@ -858,14 +1291,19 @@ func newDataflow(name, description string, protocol string, publicNetwork, encry
 		},
 		Source: &Source{
 			Cell: &source.ID,
-			Port: &source.Ports.Items[1].ID,
+			Port: connectionPort(source),
 		},
 		Target: &Source{
 			Cell: &target.ID,
-			Port: &target.Ports.Items[1].ID,
+			Port: connectionPort(target),
 		},
 		ID: uuid.NewString(),
 	}
+	// placed cells are connected on the sides that face each other
+	if sourceSide, targetSide, ok := connectionSides(source, target); ok {
+		cell.Source.Port = sidePort(source, sourceSide)
+		cell.Target.Port = sidePort(target, targetSide)
+	}
 
 	if bidirectional {
 		cell.Attrs.Line.SourceMarker.Contributor.Name = "block"
@@ -874,6 +1312,14 @@ func newDataflow(name, description string, protocol string, publicNetwork, encry
 	return cell
 }
 
+// connectionPort returns the port that flows are connected to. Cells of existing models can lack ports.
+func connectionPort(cell *Cell) *string {
+	if cell.Ports == nil || len(cell.Ports.Items) < 2 {
+		return nil
+	}
+	return &cell.Ports.Items[1].ID
+}
+
 func nullString() Nullable[string] {
 	return Nullable[string]{Set: true, Present: false, Value: ""}
 }
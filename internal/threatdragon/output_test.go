@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log/slog"
 	"os"
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -15,6 +16,15 @@ type dummyChangelog struct{}
 
 func (dc dummyChangelog) AddEntry(string) {}
 
+// recordingChangelog keeps all entries for assertions
+type recordingChangelog struct {
+	entries *[]string
+}
+
+func (rc recordingChangelog) AddEntry(entry string) {
+	*rc.entries = append(*rc.entries, entry)
+}
+
 func TestGenerate(t *testing.T) {
 	newThreatDragonOutput := NewThreatdragonOutput("./testdata/testoutput_threatdragon.json", dummyChangelog{}, slog.Default())
 
@@ -282,6 +292,26 @@ func TestUpdateCell_Rename(t *testing.T) {
 	assert.Equal(t, "NewName", *newCell.Data.Name)
 }
 
+// TestUpdateCell_StoresCredentials tests that stores keep the credential flag of the asset
+func TestUpdateCell_StoresCredentials(t *testing.T) {
+	tdo := NewThreatdragonOutput("testdata/testoutput_threatdragon.json", dummyChangelog{}, slog.Default())
+	asset := common.Asset{
+		Type:              common.AssetTypeDatabase,
+		ID:                "id9",
+		DisplayName:       "db_password",
+		StoresCredentials: true,
+	}
+	cell, err := tdo.generatePlacedCell(asset, dontPlace{})
+	require.NoError(t, err)
+	assert.Equal(t, "tm.Store", cell.Data.Type)
+	assert.True(t, *cell.Data.StoresCredentials)
+
+	cell.Data.StoresCredentials = nil
+	newCell, err := tdo.updateCell(*cell, asset)
+	require.NoError(t, err)
+	assert.True(t, *newCell.Data.StoresCredentials)
+}
+
 // TestUpdateCell_ErrorOnUnknownType tests the updateCell function for handling an unknown asset type
 func TestUpdateCell_ErrorOnUnknownType(t *testing.T) {
 	tdo := NewThreatdragonOutput("testdata/testoutput_threatdragon.json", dummyChangelog{}, slog.Default())
@@ -322,6 +352,21 @@ func TestGenerateCell_ProcessAndStore(t *testing.T) {
 	assert.Equal(t, "Store6", *cell2.Data.Name)
 }
 
+// TestGenerateCell_Actor tests the generateCell function for creating Actor cells for external entities
+func TestGenerateCell_Actor(t *testing.T) {
+	tdo := NewThreatdragonOutput("testdata/testoutput_threatdragon.json", dummyChangelog{}, slog.Default())
+	cell, err := tdo.generatePlacedCell(common.Asset{
+		Type:        common.AssetTypeExternalEntity,
+		ID:          "id8",
+		DisplayName: "Internet",
+	}, dontPlace{})
+	require.NoError(t, err)
+	assert.Equal(t, "tm.Actor", cell.Data.Type)
+	assert.Equal(t, "actor", cell.Shape)
+	assert.Equal(t, "Internet", *cell.Data.Name)
+	assert.False(t, *cell.Data.ProvidesAuthentication)
+}
+
 // TestGenerateCell_ErrorOnUnknownType tests the generateCell function for handling an unknown asset type
 func TestGenerateCell_ErrorOnUnknownType(t *testing.T) {
 	tdo := NewThreatdragonOutput("testdata/testoutput_threatdragon.json", dummyChangelog{}, slog.Default())
@@ -591,3 +636,271 @@ func TestGenerate_ExistingThreatsWithUnknownTypeOrModel(t *testing.T) {
 	assert.Equal(t, 3, len(*threats), "expected three threats")
 
 }
+
+// TestGenerate_UpdateDataflows tests that updating an existing model adds new dataflows, updates
+// existing ones and removes dataflows that are gone, while keeping user-drawn flows and routing
+func TestGenerate_UpdateDataflows(t *testing.T) {
+	const path = "testdata/testoutput_threatdragon_dataflows.json"
+	defer os.Remove(path)
+
+	assets := []common.Asset{
+		{ID: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", DisplayName: "web", Type: common.AssetTypeWebserver, Source: common.DataSourceDockerCompose},
+		{ID: "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", DisplayName: "db", Type: common.AssetTypeDatabase, Source: common.DataSourceDockerCompose},
+		{ID: "cccccccccccccccccccccccccccccccc", DisplayName: "cache", Type: common.AssetTypeDatabase, Source: common.DataSourceDockerCompose},
+	}
+	updated := common.DataFlow{ID: "11111111111111111111111111111111", Name: "sql", Protocol: "postgresql", Source: "web", Target: "db", DataSource: common.DataSourceDockerCompose}
+	removed := common.DataFlow{ID: "22222222222222222222222222222222", Name: "cache", Protocol: "redis", Source: "web", Target: "cache", DataSource: common.DataSourceDockerCompose}
+
+	tdo := NewThreatdragonOutput(path, dummyChangelog{}, slog.Default())
+	require.NoError(t, tdo.Generate(&common.ThreatModel{Assets: assets, DataFlows: []common.DataFlow{updated, removed}}))
+
+	// the user routes the updated flow and draws a flow of its own
+	project := readProject(t, path)
+	cells := project.Detail.Diagrams[0].Cells
+	for j := range cells {
+		if isFlow(cells[j]) && *cells[j].Data.Name == "sql" {
+			cells[j].Vertices = &[]VertexClass{{X: 10, Y: 20}}
+		}
+	}
+	userFlow := cells[len(cells)-1]
+	userFlow.ID = "user-flow"
+	userFlow.Data.Name = stringPtr("backup")
+	userFlow.Data.Description = stringPtr("drawn by the user")
+	project.Detail.Diagrams[0].Cells = append(cells, userFlow)
+	writeProject(t, path, project)
+
+	model, err := NewThreatDragonInput(path, slog.Default()).Analyze()
+	require.NoError(t, err)
+	require.Len(t, model.DataFlows, 3)
+
+	// the tool now reports an encrypted connection to the db, no cache flow and a new flow to the cache
+	model.DataFlows = slices.DeleteFunc(model.DataFlows, func(df common.DataFlow) bool { return df.ID == removed.ID })
+	for k := range model.DataFlows {
+		if model.DataFlows[k].ID == updated.ID {
+			model.DataFlows[k].Protocol = "postgresql+tls"
+			model.DataFlows[k].Encrypted = true
+		}
+	}
+	added := common.DataFlow{ID: "33333333333333333333333333333333", Name: "session", Protocol: "redis", Source: "db", Target: "cache", DataSource: common.DataSourceDockerCompose}
+	model.DataFlows = append(model.DataFlows, added)
+
+	cl := &recordingChangelog{entries: &[]string{}}
+	require.NoError(t, NewThreatdragonOutput(path, cl, slog.Default()).Generate(model))
+
+	flows := map[string]Cell{}
+	for _, cell := range readProject(t, path).Detail.Diagrams[0].Cells {
+		if isFlow(cell) {
+			flows[*cell.Data.Name] = cell
+		}
+	}
+	require.Len(t, flows, 3)
+	assert.NotContains(t, flows, "cache")
+
+	assert.Equal(t, "postgresql+tls", *flows["sql"].Data.Protocol)
+	assert.True(t, *flows["sql"].Data.IsEncrypted)
+	assert.Equal(t, []VertexClass{{X: 10, Y: 20}}, *flows["sql"].Vertices)
+
+	assert.Equal(t, "drawn by the user", *flows["backup"].Data.Description)
+
+	require.Contains(t, flows, "session")
+	assert.Equal(t, analyzerIDTag(added.ID), *flows["session"].Data.Description)
+	assert.Equal(t, "redis", *flows["session"].Data.Protocol)
+
+	assert.Contains(t, *cl.entries, "Dataflow 'sql' protocol has changed from 'postgresql' to 'postgresql+tls'")
+	assert.Contains(t, *cl.entries, "Dataflow 'sql' is now encrypted")
+	assert.Contains(t, *cl.entries, "New dataflow 'session' has been added from Docker Compose")
+}
+
+// TestGenerate_UpdateDataflowsClearsFlags tests that flags set in an earlier run are turned off again
+func TestGenerate_UpdateDataflowsClearsFlags(t *testing.T) {
+	const path = "testdata/testoutput_threatdragon_dataflow_flags.json"
+	defer os.Remove(path)
+
+	assets := []common.Asset{
+		{ID: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", DisplayName: "web", Type: common.AssetTypeWebserver, Source: common.DataSourceDockerCompose},
+		{ID: "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", DisplayName: "api", Type: common.AssetTypeWebserver, Source: common.DataSourceDockerCompose},
+	}
+	flow := common.DataFlow{
+		ID: "11111111111111111111111111111111", Name: "api calls", Protocol: "https", Source: "web", Target: "api",
+		Encrypted: true, PublicNetwork: true, Bidirectional: true, DataSource: common.DataSourceDockerCompose,
+	}
+
+	tdo := NewThreatdragonOutput(path, dummyChangelog{}, slog.Default())
+	require.NoError(t, tdo.Generate(&common.ThreatModel{Assets: assets, DataFlows: []common.DataFlow{flow}}))
+
+	model, err := NewThreatDragonInput(path, slog.Default()).Analyze()
+	require.NoError(t, err)
+	require.Len(t, model.DataFlows, 1)
+	require.True(t, model.DataFlows[0].Encrypted)
+	require.True(t, model.DataFlows[0].PublicNetwork)
+	require.True(t, model.DataFlows[0].Bidirectional)
+
+	// the tool now reports a plain, internal and one-way connection
+	model.DataFlows[0].Protocol = "http"
+	model.DataFlows[0].Encrypted = false
+	model.DataFlows[0].PublicNetwork = false
+	model.DataFlows[0].Bidirectional = false
+
+	cl := &recordingChangelog{entries: &[]string{}}
+	require.NoError(t, NewThreatdragonOutput(path, cl, slog.Default()).Generate(model))
+
+	var cell Cell
+	for _, c := range readProject(t, path).Detail.Diagrams[0].Cells {
+		if isFlow(c) {
+			cell = c
+		}
+	}
+	require.NotNil(t, cell.Data.Name)
+	assert.Equal(t, "http", *cell.Data.Protocol)
+	assert.False(t, *cell.Data.IsEncrypted)
+	assert.False(t, *cell.Data.IsPublicNetwork)
+	assert.False(t, *cell.Data.IsBidirectional)
+	assert.Empty(t, cell.Attrs.Line.SourceMarker.Contributor.Name)
+
+	assert.Contains(t, *cl.entries, "Dataflow 'api calls' is now unencrypted")
+	assert.Contains(t, *cl.entries, "Dataflow 'api calls' now no longer uses a public network")
+	assert.Contains(t, *cl.entries, "Dataflow 'api calls' is now unidirectional")
+
+	// reading the model again gives the cleared flags
+	model, err = NewThreatDragonInput(path, slog.Default()).Analyze()
+	require.NoError(t, err)
+	require.Len(t, model.DataFlows, 1)
+	assert.False(t, model.DataFlows[0].Encrypted)
+	assert.False(t, model.DataFlows[0].PublicNetwork)
+	assert.False(t, model.DataFlows[0].Bidirectional)
+}
+
+// TestConnectableDataflows tests that dataflows with unknown endpoints are skipped
+func TestConnectableDataflows(t *testing.T) {
+	cl := &recordingChangelog{entries: &[]string{}}
+	tdo := NewThreatdragonOutput("testdata/testoutput_threatdragon.json", cl, slog.Default())
+	cell, err := tdo.generatePlacedCell(common.Asset{ID: "id1", DisplayName: "web", Type: common.AssetTypeWebserver}, dontPlace{})
+	require.NoError(t, err)
+
+	flows := tdo.connectableDataflows([]common.DataFlow{
+		{Name: "known", Source: "web", Target: "db"},
+		{Name: "unknown", Source: "web", Target: "gone"},
+	}, []Cell{*cell}, []common.Asset{{DisplayName: "db"}})
+
+	require.Len(t, flows, 1)
+	assert.Equal(t, "known", flows[0].Name)
+	assert.Equal(t, []string{"Dataflow 'unknown' could not be added, because 'gone' was not found in the diagram"}, *cl.entries)
+}
+
+func readProject(t *testing.T, path string) Project {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var project Project
+	require.NoError(t, json.Unmarshal(data, &project))
+	return project
+}
+
+func writeProject(t *testing.T, path string, project Project) {
+	t.Helper()
+	data, err := json.Marshal(project)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+}
+
+// TestGenerate_UpdateTrustBoundaries tests that updating an existing model adds new trust boundaries,
+// fits existing ones around their assets and removes boundaries that are gone
+func TestGenerate_UpdateTrustBoundaries(t *testing.T) {
+	const path = "testdata/testoutput_threatdragon_boundaries.json"
+	defer os.Remove(path)
+
+	web := common.Asset{ID: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", DisplayName: "web", Type: common.AssetTypeWebserver, Source: common.DataSourceDockerCompose}
+	db := common.Asset{ID: "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", DisplayName: "db", Type: common.AssetTypeDatabase, Source: common.DataSourceDockerCompose}
+	frontend := common.TrustBoundary{ID: "11111111111111111111111111111111", DisplayName: "frontend", ContainedAssets: []string{web.ID}, Source: common.DataSourceDockerCompose}
+	removed := common.TrustBoundary{ID: "22222222222222222222222222222222", DisplayName: "backend", ContainedAssets: []string{db.ID}, Source: common.DataSourceDockerCompose}
+
+	tdo := NewThreatdragonOutput(path, dummyChangelog{}, slog.Default())
+	require.NoError(t, tdo.Generate(&common.ThreatModel{Assets: []common.Asset{web, db}, Boundaries: []common.TrustBoundary{frontend, removed}}))
+
+	model, err := NewThreatDragonInput(path, slog.Default()).Analyze()
+	require.NoError(t, err)
+	require.Len(t, model.Boundaries, 2)
+
+	// the backend network is gone, a new cache joins the frontend network and a new network holds the db
+	cache := common.Asset{ID: "cccccccccccccccccccccccccccccccc", DisplayName: "cache", Type: common.AssetTypeDatabase, Source: common.DataSourceDockerCompose}
+	added := common.TrustBoundary{ID: "33333333333333333333333333333333", DisplayName: "storage", ContainedAssets: []string{db.ID}, Source: common.DataSourceDockerCompose}
+	model.Assets = append(model.Assets, cache)
+	model.Boundaries = slices.DeleteFunc(model.Boundaries, func(b common.TrustBoundary) bool { return b.ID == removed.ID })
+	model.Boundaries[0].ContainedAssets = append(model.Boundaries[0].ContainedAssets, cache.ID)
+	model.Boundaries = append(model.Boundaries, added)
+
+	cl := &recordingChangelog{entries: &[]string{}}
+	require.NoError(t, NewThreatdragonOutput(path, cl, slog.Default()).Generate(model))
+
+	cells := map[string]Cell{}
+	boundaries := []string{}
+	for _, cell := range readProject(t, path).Detail.Diagrams[0].Cells {
+		cells[*cell.Data.Name] = cell
+		if isCellTrustBoudary(&cell) {
+			boundaries = append(boundaries, *cell.Data.Name)
+		}
+	}
+	assert.ElementsMatch(t, []string{"frontend", "storage"}, boundaries)
+
+	assert.True(t, cellRectangle(cells["web"]).IsContained(cellRectangle(cells["frontend"])))
+	assert.True(t, cellRectangle(cells["cache"]).IsContained(cellRectangle(cells["frontend"])))
+	assert.True(t, cellRectangle(cells["db"]).IsContained(cellRectangle(cells["storage"])))
+	assert.Equal(t, analyzerIDTag(added.ID), *cells["storage"].Data.Description)
+
+	assert.Contains(t, *cl.entries, "Trust boundary 'frontend' has been resized to enclose its assets")
+	assert.Contains(t, *cl.entries, "New trust boundary 'storage' has been added from Docker Compose")
+}
+
+// TestUpdateBoundaryCell_Rename tests that renaming a boundary updates its name and label
+func TestUpdateBoundaryCell_Rename(t *testing.T) {
+	cl := &recordingChangelog{entries: &[]string{}}
+	tdo := NewThreatdragonOutput("testdata/testoutput_threatdragon.json", cl, slog.Default())
+	cell := trustBoundary(0, 0, 100, 100, "old", "")
+
+	newCell := tdo.updateBoundaryCell(cell, common.TrustBoundary{DisplayName: "new"})
+	assert.Equal(t, "new", *newCell.Data.Name)
+	assert.Equal(t, "new", newCell.Attrs.Label.Text)
+	assert.Equal(t, []string{"Trust boundary 'old' has been renamed to 'new'"}, *cl.entries)
+}
+
+// TestGenerateFittedTrustBoundaries_NoAssets tests that boundaries without placed assets are skipped
+func TestGenerateFittedTrustBoundaries_NoAssets(t *testing.T) {
+	cl := &recordingChangelog{entries: &[]string{}}
+	tdo := NewThreatdragonOutput("testdata/testoutput_threatdragon.json", cl, slog.Default())
+
+	cells := tdo.generateFittedTrustBoundaries([]common.TrustBoundary{{ID: "id1", DisplayName: "empty", ContainedAssets: []string{"missing"}}}, []Cell{}, map[string]string{})
+	assert.Empty(t, cells)
+	assert.Equal(t, []string{"Trust boundary 'empty' could not be added, because none of its assets was found in the diagram"}, *cl.entries)
+}
+
+func cellRectangle(cell Cell) *common.Rectangle {
+	return common.NewRectangle(cell.Position.X, cell.Position.Y, cell.Size.Width, cell.Size.Height)
+}
+
+// TestGenerate_KeepsCurvesAndTexts tests that updating a model keeps boundary curves and text cells
+// and does not add them again as boundary boxes
+func TestGenerate_KeepsCurvesAndTexts(t *testing.T) {
+	const path = "testdata/testoutput_threatdragon_curves.json"
+	defer os.Remove(path)
+
+	countTypes := func(project Project) map[string]int {
+		counts := map[string]int{}
+		for _, cell := range project.Detail.Diagrams[0].Cells {
+			counts[cell.Data.Type]++
+		}
+		return counts
+	}
+
+	model, err := NewThreatDragonInput("testdata/models/crypto_wallet_web.json", slog.Default()).Analyze()
+	require.NoError(t, err)
+	before := countTypes(readProject(t, "testdata/models/crypto_wallet_web.json"))
+
+	cl := &recordingChangelog{entries: &[]string{}}
+	require.NoError(t, NewThreatdragonOutput(path, cl, slog.Default()).Generate(model))
+
+	after := countTypes(readProject(t, path))
+	assert.Equal(t, before, after)
+	assert.Equal(t, 1, after["tm.Boundary"])
+	assert.Equal(t, 1, after["tm.Text"])
+	assert.Empty(t, *cl.entries)
+}
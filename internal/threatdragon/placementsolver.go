@@ -16,9 +16,16 @@ const (
 	MIN_BOX_H = 2
 	MAX_BOX_H = 4
 
+	// MAX_BOX_PLACEMENTS limits the number of box placements and region orders tried while backtracking.
+	// Models with many overlapping boxes would otherwise take practically forever to fail.
+	MAX_BOX_PLACEMENTS = 20000
+
 	solutionOffsetX          = 200.0
 	solutionOffsetY          = 100.0
 	solutionBoundaryOversize = 10.0
+	solutionBoundaryNesting  = 20.0  // additional space around each nested box, which keeps the labels apart
+	solutionCellWidth        = 160.0 // width of the widest cell, an actor
+	solutionCellHeight       = 80.0  // height of the highest cell, an actor
 )
 
 // Input types
@@ -41,6 +48,8 @@ type PlacedBox struct {
 	Y  int
 	W  int
 	H  int
+	// Depth is the number of levels of boxes nested inside of this box. Outer boxes keep a larger margin around their content.
+	Depth int
 }
 type PlacedNode struct {
 	ID        string
@@ -53,12 +62,17 @@ type Solution struct {
 	Boxes      []PlacedBox
 	Nodes      []PlacedNode
 	RowHeights []int
+
+	colStarts []float64      // x of every grid column
+	rowStarts []float64      // y of every grid row
+	nodeIndex map[string]int // asset ID -> index in Nodes
 }
 
 func (s *Solution) CalculateRowHeights() {
-	nodeCount := make([][]int, GRID_H)
-	for i := range GRID_H {
-		nodeCount[i] = make([]int, GRID_W)
+	cols, rows := s.gridSize()
+	nodeCount := make([][]int, rows)
+	for i := range rows {
+		nodeCount[i] = make([]int, cols)
 	}
 
 	for i, node := range s.Nodes {
@@ -66,42 +80,82 @@ func (s *Solution) CalculateRowHeights() {
 		nodeCount[node.Y][node.X]++
 	}
 
-	s.RowHeights = make([]int, GRID_H)
+	s.RowHeights = make([]int, rows)
 	for i, nodeCountCols := range nodeCount {
 		s.RowHeights[i] = slices.Max(nodeCountCols)
 	}
 }
 
-func (s *Solution) GetPosition(assetID string) (float64, float64) {
+// gridSize returns the number of columns and rows used by the boxes and nodes, at least one of each
+func (s *Solution) gridSize() (int, int) {
+	cols, rows := 1, 1
+	for _, node := range s.Nodes {
+		cols, rows = max(cols, node.X+1), max(rows, node.Y+1)
+	}
+	for _, box := range s.Boxes {
+		cols, rows = max(cols, box.X+box.W), max(rows, box.Y+box.H)
+	}
+	return cols, rows
+}
+
+// margin is the space a box keeps around its content. Boxes around other boxes keep more space.
+func (b PlacedBox) margin() float64 {
+	return solutionBoundaryOversize + float64(b.Depth)*solutionBoundaryNesting
+}
+
+// calculateStarts computes the position of every column and row. The gaps between columns and rows grow by the
+// margins of the boxes whose edges lie between them, so that nested and neighbouring boxes do not touch their content.
+func (s *Solution) calculateStarts() {
 	if s.RowHeights == nil {
 		s.CalculateRowHeights()
 	}
+	cols, rows := s.gridSize()
+	left, right := make([]float64, cols), make([]float64, cols)
+	top, bottom := make([]float64, rows), make([]float64, rows)
+	for _, box := range s.Boxes {
+		left[box.X] = max(left[box.X], box.margin())
+		right[box.X+box.W-1] = max(right[box.X+box.W-1], box.margin())
+		top[box.Y] = max(top[box.Y], box.margin())
+		bottom[box.Y+box.H-1] = max(bottom[box.Y+box.H-1], box.margin())
+	}
 
-	var foundNode PlacedNode
-	for _, node := range s.Nodes {
-		if node.ID == assetID {
-			foundNode = node
-			break
-		}
+	s.nodeIndex = make(map[string]int, len(s.Nodes))
+	for i, node := range s.Nodes {
+		s.nodeIndex[node.ID] = i
 	}
-	if foundNode.ID == "" {
-		panic("asset does not exist in solution")
+
+	s.colStarts = make([]float64, cols)
+	s.colStarts[0] = 50 + left[0]
+	for c := 1; c < cols; c++ {
+		s.colStarts[c] = s.colStarts[c-1] + solutionOffsetX + right[c-1] + left[c]
+	}
+	s.rowStarts = make([]float64, rows)
+	s.rowStarts[0] = 50 + top[0]
+	for r := 1; r < rows; r++ {
+		s.rowStarts[r] = s.rowStarts[r-1] + float64(s.RowHeights[r-1])*solutionOffsetY + bottom[r-1] + top[r]
 	}
+}
 
-	x := float64(foundNode.X) * solutionOffsetX
+func (s *Solution) GetPosition(assetID string) (float64, float64) {
+	if s.colStarts == nil {
+		s.calculateStarts()
+	}
 
-	sumRowHeight := 0
-	for _, rowHeight := range s.RowHeights[:foundNode.Y] {
-		sumRowHeight += rowHeight
+	i, ok := s.nodeIndex[assetID]
+	if !ok {
+		panic("asset does not exist in solution")
 	}
-	y := float64(sumRowHeight+foundNode.SubOffset) * solutionOffsetY
+	foundNode := s.Nodes[i]
 
-	return x + 50, y + 50
+	x := s.colStarts[foundNode.X]
+	y := s.rowStarts[foundNode.Y] + float64(foundNode.SubOffset)*solutionOffsetY
+
+	return x, y
 }
 
 func (s *Solution) GetBoundaryPosition(boundaryID string) (float64, float64, float64, float64) {
-	if s.RowHeights == nil {
-		s.CalculateRowHeights()
+	if s.colStarts == nil {
+		s.calculateStarts()
 	}
 
 	var foundBox PlacedBox
@@ -115,24 +169,49 @@ func (s *Solution) GetBoundaryPosition(boundaryID string) (float64, float64, flo
 		panic("boundary does not exist in solution")
 	}
 
-	x := float64(foundBox.X)*solutionOffsetX - solutionBoundaryOversize
-	w := float64(foundBox.W)*solutionOffsetX + 2*solutionBoundaryOversize
+	margin := foundBox.margin()
+	lastCol, lastRow := foundBox.X+foundBox.W-1, foundBox.Y+foundBox.H-1
 
-	sumRowHeight1 := 0
-	for _, rowHeight := range s.RowHeights[:foundBox.Y] {
-		sumRowHeight1 += rowHeight
+	x := s.colStarts[foundBox.X] - margin
+	w := s.colStarts[lastCol] + solutionCellWidth + margin - x
+
+	y := s.rowStarts[foundBox.Y] - margin
+	bottom := s.rowStarts[lastRow] + margin
+	if s.RowHeights[lastRow] > 0 {
+		bottom += float64(s.RowHeights[lastRow]-1)*solutionOffsetY + solutionCellHeight
 	}
 
-	y := float64(sumRowHeight1)*solutionOffsetY - solutionBoundaryOversize
+	return x, y, w, bottom - y
+}
 
-	sumRowHeight2 := sumRowHeight1
-	for _, rowHeight := range s.RowHeights[foundBox.Y : foundBox.Y+foundBox.H] {
-		sumRowHeight2 += rowHeight
+// assignDepths sets the depth of every box to the number of levels of boxes that lie within it.
+// Boxes covering the same grid cells are nested in the order they are given.
+func assignDepths(boxes []PlacedBox) {
+	contains := func(i, j int) bool {
+		a, b := boxes[i], boxes[j]
+		within := a.X <= b.X && b.X+b.W <= a.X+a.W && a.Y <= b.Y && b.Y+b.H <= a.Y+a.H
+		same := a.X == b.X && a.W == b.W && a.Y == b.Y && a.H == b.H
+		return within && (!same || i < j)
 	}
 
-	h := float64(sumRowHeight2)*solutionOffsetY + 2*solutionBoundaryOversize
-
-	return x + 50, y + 50, w, h
+	depths := make(map[int]int)
+	var depth func(i int) int
+	depth = func(i int) int {
+		if d, ok := depths[i]; ok {
+			return d
+		}
+		d := 0
+		for j := range boxes {
+			if i != j && contains(i, j) {
+				d = max(d, depth(j)+1)
+			}
+		}
+		depths[i] = d
+		return d
+	}
+	for i := range boxes {
+		boxes[i].Depth = depth(i)
+	}
 }
 
 // Helper: membership lookup maps
@@ -233,7 +312,7 @@ func finalPossibleCells(placed []PlacedBox, nodes []NodeDef, nodeToBoxes map[str
 }
 
 // Deterministic backtracking to place boxes
-func placeBoxesRecursive(boxDefs []BoxDef, idx int, placed []PlacedBox, nodes []NodeDef, nodeToBoxes map[string]map[string]bool, boxIndexMap map[string]int) ([]PlacedBox, bool) {
+func placeBoxesRecursive(boxDefs []BoxDef, idx int, placed []PlacedBox, nodes []NodeDef, nodeToBoxes map[string]map[string]bool, boxIndexMap map[string]int, attempts *int) ([]PlacedBox, bool) {
 	if idx >= len(boxDefs) {
 		// all boxes placed
 		return placed, true
@@ -268,6 +347,11 @@ func placeBoxesRecursive(boxDefs []BoxDef, idx int, placed []PlacedBox, nodes []
 						break
 					}
 
+					*attempts++
+					if *attempts > MAX_BOX_PLACEMENTS {
+						return nil, false
+					}
+
 					b := PlacedBox{ID: bdef.ID, X: x, Y: y, W: w, H: h}
 					placed2 := append(placed, b)
 					// Build map of placed box IDs for fast lookups
@@ -290,7 +374,7 @@ func placeBoxesRecursive(boxDefs []BoxDef, idx int, placed []PlacedBox, nodes []
 						continue // try next box placement
 					}
 					// Recurse
-					if solution, found := placeBoxesRecursive(boxDefs, idx+1, placed2, nodes, nodeToBoxes, boxIndexMap); found {
+					if solution, found := placeBoxesRecursive(boxDefs, idx+1, placed2, nodes, nodeToBoxes, boxIndexMap, attempts); found {
 						return solution, true
 					}
 				}
@@ -345,7 +429,11 @@ func Solve(boxDefs []BoxDef, nodes []NodeDef, memberships []Membership) (Solutio
 	}
 
 	// Place boxes deterministically using backtracking with pruning
-	placedBoxes, ok := placeBoxesRecursive(boxDefs, 0, []PlacedBox{}, nodes, nodeToBoxes, boxIndexMap)
+	attempts := 0
+	placedBoxes, ok := placeBoxesRecursive(boxDefs, 0, []PlacedBox{}, nodes, nodeToBoxes, boxIndexMap, &attempts)
+	if attempts > MAX_BOX_PLACEMENTS {
+		return Solution{}, fmt.Errorf("no arrangement of boxes found within %d placement attempts", MAX_BOX_PLACEMENTS)
+	}
 	if !ok {
 		return Solution{}, fmt.Errorf("no arrangement of boxes and nodes found on a %dx%d grid with the chosen sizes", GRID_W, GRID_H)
 	}
@@ -372,10 +460,13 @@ func Solve(boxDefs []BoxDef, nodes []NodeDef, memberships []Membership) (Solutio
 		x, y := encodeXY(idx)
 		placedNodes = append(placedNodes, PlacedNode{ID: n.ID, X: x, Y: y})
 	}
+	assignDepths(placedBoxes)
 
 	return Solution{Boxes: placedBoxes, Nodes: placedNodes}, nil
 }
 
+// SolveModel places the assets and trust boundaries of a diagram. Nested and overlapping boundaries are placed by
+// SolveNested, the grid search of Solve is only tried if that fails.
 func SolveModel(assets []common.Asset, boundaries []common.TrustBoundary) (Solution, error) {
 	nodeDefs := make([]NodeDef, 0, len(assets))
 	for _, asset := range assets {
@@ -398,5 +489,9 @@ func SolveModel(assets []common.Asset, boundaries []common.TrustBoundary) (Solut
 		}
 	}
 
+	solution, err := SolveNested(boxDefs, nodeDefs, memberships)
+	if err == nil {
+		return solution, nil
+	}
 	return Solve(boxDefs, nodeDefs, memberships)
 }
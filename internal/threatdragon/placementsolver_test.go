@@ -248,3 +248,17 @@ func TestSolve(t *testing.T) {
 		})
 	}
 }
+
+// Overlapping boxes with many members can not be placed in reasonable time.
+// The solver has to give up instead of searching the whole space.
+func TestSolveGivesUpAfterPlacementLimit(t *testing.T) {
+	memberships := []Membership{
+		{NodeID: "lb", BoxID: "vpc"}, {NodeID: "lb", BoxID: "public"}, {NodeID: "lb", BoxID: "sg-lb"},
+		{NodeID: "app", BoxID: "vpc"}, {NodeID: "app", BoxID: "private"}, {NodeID: "app", BoxID: "sg-app"},
+		{NodeID: "db", BoxID: "vpc"}, {NodeID: "db", BoxID: "private"}, {NodeID: "db", BoxID: "sg-db"},
+		{NodeID: "cache", BoxID: "vpc"}, {NodeID: "cache", BoxID: "sg-db"},
+	}
+
+	_, err := Solve(boxes("vpc", "public", "private", "sg-lb", "sg-app", "sg-db"), nodes("lb", "app", "db", "cache", "bucket"), memberships)
+	assert.Error(t, err)
+}
@@ -0,0 +1,240 @@
+package threatdragon
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+const (
+	// routingStub is the length of the straight piece with which a flow leaves and enters its port
+	routingStub = 20.0
+	// routingGap is the distance that routes keep from the cells they pass
+	routingGap = 15.0
+	// routingLane is the distance between the routes of parallel flows between the same cells
+	routingLane = 20.0
+)
+
+// sideDirections are the directions in which flows leave the ports of a side
+var sideDirections = map[string]point{
+	"top":    {0, -1},
+	"right":  {1, 0},
+	"bottom": {0, 1},
+	"left":   {-1, 0},
+}
+
+// connectionSides chooses the sides of two cells that face each other. Cells that are further apart horizontally than
+// vertically, relative to their size, are connected left to right. It returns false if a cell has not been placed.
+func connectionSides(source, target *Cell) (string, string, bool) {
+	if source.Position == nil || source.Size == nil || target.Position == nil || target.Size == nil {
+		return "", "", false
+	}
+	from, to := cellCenter(source), cellCenter(target)
+	dx, dy := to.x-from.x, to.y-from.y
+	width := (source.Size.Width + target.Size.Width) / 2
+	height := (source.Size.Height + target.Size.Height) / 2
+	if math.Abs(dx)/width >= math.Abs(dy)/height {
+		if dx >= 0 {
+			return "right", "left", true
+		}
+		return "left", "right", true
+	}
+	if dy >= 0 {
+		return "bottom", "top", true
+	}
+	return "top", "bottom", true
+}
+
+// sidePort returns the port of the side of a cell. Cells without a port on that side are connected to their default port.
+func sidePort(cell *Cell, side string) *string {
+	if cell.Ports != nil {
+		for k := range cell.Ports.Items {
+			if cell.Ports.Items[k].Group == side {
+				return &cell.Ports.Items[k].ID
+			}
+		}
+	}
+	return connectionPort(cell)
+}
+
+// portPoint returns the point of the port in the middle of a side of a placed cell
+func portPoint(cell *Cell, side string) point {
+	center := cellCenter(cell)
+	direction := sideDirections[side]
+	return point{center.x + direction.x*cell.Size.Width/2, center.y + direction.y*cell.Size.Height/2}
+}
+
+func cellCenter(cell *Cell) point {
+	return point{cell.Position.X + cell.Size.Width/2, cell.Position.Y + cell.Size.Height/2}
+}
+
+// routeDataflow adds vertices to a new flow, so that it runs in horizontal and vertical lines between its ports
+// and around the other cells. Flows between the same two cells, in either direction, get their own lane.
+func routeDataflow(flow *Cell, cells []Cell) {
+	if flow.Source == nil || flow.Source.Cell == nil || flow.Target == nil || flow.Target.Cell == nil {
+		return
+	}
+	source := findCell(cells, *flow.Source.Cell)
+	target := findCell(cells, *flow.Target.Cell)
+	if source == nil || target == nil {
+		return
+	}
+	fromSide, toSide, ok := connectionSides(source, target)
+	if !ok {
+		return
+	}
+
+	parallel := 0
+	obstacles := make([]*common.Rectangle, 0, len(cells))
+	for _, cell := range cells {
+		if cell.ID == flow.ID {
+			continue
+		}
+		if isFlow(cell) && connects(cell, source.ID, target.ID) {
+			parallel++
+		}
+		if isCurve(cell) || isCellTrustBoudary(&cell) || cell.Position == nil || cell.Size == nil || cell.ID == source.ID || cell.ID == target.ID {
+			continue
+		}
+		obstacles = append(obstacles, pad(cellRect(cell), routingGap))
+	}
+	// the lanes alternate around the direct route: 0, +1, -1, +2, ...
+	lane := float64((parallel+1)/2) * routingLane
+	if parallel%2 == 0 {
+		lane = -lane
+	}
+
+	vertices := orthogonalRoute(portPoint(source, fromSide), portPoint(target, toSide), fromSide, toSide, obstacles, lane)
+	if len(vertices) > 0 {
+		flow.Vertices = &vertices
+		flow.Connector = stringPtr("rounded")
+	}
+}
+
+// orthogonalRoute returns the vertices of a route from one port to another. The route leaves and enters the ports
+// straight and changes its direction in a channel between them. Apart from these short pieces at the ports, the route
+// is moved sideways by the lane. Channels along the edges of the obstacles are tried until a route crosses no obstacle.
+// If every route crosses one, the route crossing the fewest is taken.
+func orthogonalRoute(from, to point, fromSide, toSide string, obstacles []*common.Rectangle, lane float64) []VertexClass {
+	fromDirection, toDirection := sideDirections[fromSide], sideDirections[toSide]
+
+	// routes leaving at the top or bottom are the routes leaving to the side with x and y swapped
+	vertical := fromDirection.x == 0
+	swap := func(p point) point {
+		if vertical {
+			return point{p.y, p.x}
+		}
+		return p
+	}
+	from, to, fromDirection, toDirection = swap(from), swap(to), swap(fromDirection), swap(toDirection)
+	swapped := make([]*common.Rectangle, 0, len(obstacles))
+	for _, obstacle := range obstacles {
+		if vertical {
+			obstacle = common.NewRectangle(obstacle.Top, obstacle.Left, obstacle.Height, obstacle.Width)
+		}
+		swapped = append(swapped, obstacle)
+	}
+
+	start := point{from.x + fromDirection.x*routingStub, from.y + fromDirection.y*routingStub}
+	end := point{to.x + toDirection.x*routingStub, to.y + toDirection.y*routingStub}
+	base := (start.x + end.x) / 2
+	if toDirection.x == 0 {
+		base = end.x
+	}
+	alternatives := make([]float64, 0, 2*len(swapped))
+	for _, obstacle := range swapped {
+		alternatives = append(alternatives, obstacle.Left-routingGap, obstacle.Left+obstacle.Width+routingGap)
+	}
+	slices.SortFunc(alternatives, func(a, b float64) int {
+		return cmp.Compare(math.Abs(a-base), math.Abs(b-base))
+	})
+
+	var best []point
+	bestCrossings := math.MaxInt
+	for _, channel := range append([]float64{base}, alternatives...) {
+		channel += lane
+		route := []point{from, start, {start.x, start.y + lane}, {channel, start.y + lane}}
+		if toDirection.x == 0 {
+			route = append(route, point{channel, end.y}, end, to)
+		} else {
+			route = append(route, point{channel, end.y + lane}, point{end.x, end.y + lane}, end, to)
+		}
+		crossings := routeCrossings(route, swapped)
+		if crossings < bestCrossings {
+			best, bestCrossings = route, crossings
+		}
+		if crossings == 0 {
+			break
+		}
+	}
+
+	best = simplifyRoute(best)
+	vertices := make([]VertexClass, 0, len(best))
+	for _, p := range best[1 : len(best)-1] {
+		p = swap(p)
+		vertices = append(vertices, VertexClass{X: p.x, Y: p.y})
+	}
+	return vertices
+}
+
+// routeCrossings counts the obstacles that the segments of a route run through
+func routeCrossings(route []point, obstacles []*common.Rectangle) int {
+	crossings := 0
+	for _, obstacle := range obstacles {
+		for i := 1; i < len(route); i++ {
+			a, b := route[i-1], route[i]
+			segment := common.NewRectangle(min(a.x, b.x), min(a.y, b.y), math.Abs(a.x-b.x), math.Abs(a.y-b.y))
+			if segmentHitsRect(segment, obstacle) {
+				crossings++
+				break
+			}
+		}
+	}
+	return crossings
+}
+
+// segmentHitsRect checks if a horizontal or vertical segment, given as its bounding rectangle, touches the rectangle
+func segmentHitsRect(segment, r *common.Rectangle) bool {
+	return segment.Left <= r.Left+r.Width && r.Left <= segment.Left+segment.Width &&
+		segment.Top <= r.Top+r.Height && r.Top <= segment.Top+segment.Height
+}
+
+// simplifyRoute removes repeated points and points in the middle of a straight line
+func simplifyRoute(route []point) []point {
+	result := make([]point, 0, len(route))
+	for _, p := range route {
+		if n := len(result); n > 0 && result[n-1] == p {
+			continue
+		}
+		if n := len(result); n > 1 && orientation(result[n-2], result[n-1], p) == 0 && onSegment(result[n-2], p, result[n-1]) {
+			result[n-1] = p
+			continue
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// connects checks if a flow runs between the two cells, in either direction
+func connects(flow Cell, a, b string) bool {
+	if flow.Source == nil || flow.Source.Cell == nil || flow.Target == nil || flow.Target.Cell == nil {
+		return false
+	}
+	source, target := *flow.Source.Cell, *flow.Target.Cell
+	return (source == a && target == b) || (source == b && target == a)
+}
+
+func findCell(cells []Cell, id string) *Cell {
+	for k := range cells {
+		if cells[k].ID == id {
+			return &cells[k]
+		}
+	}
+	return nil
+}
+
+func cellRect(cell Cell) *common.Rectangle {
+	return common.NewRectangle(cell.Position.X, cell.Position.Y, cell.Size.Width, cell.Size.Height)
+}
@@ -0,0 +1,125 @@
+package threatdragon
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threatcat-dev/threatcat/internal/common"
+)
+
+// portGroup returns the side of the port with the ID
+func portGroup(cell Cell, id *string) string {
+	for _, port := range cell.Ports.Items {
+		if id != nil && port.ID == *id {
+			return port.Group
+		}
+	}
+	return ""
+}
+
+func TestConnectionSides(t *testing.T) {
+	center := process("center", "", false, nil, 400, 400)
+	tests := []struct {
+		name     string
+		x, y     float64
+		from, to string
+	}{
+		{"right", 700, 420, "right", "left"},
+		{"left", 100, 380, "left", "right"},
+		{"below", 420, 700, "bottom", "top"},
+		{"above", 380, 100, "top", "bottom"},
+	}
+	for _, tt := range tests {
+		other := process("other", "", false, nil, tt.x, tt.y)
+		from, to, ok := connectionSides(&center, &other)
+		require.True(t, ok)
+		assert.Equal(t, tt.from, from, tt.name)
+		assert.Equal(t, tt.to, to, tt.name)
+	}
+
+	_, _, ok := connectionSides(&center, &Cell{})
+	assert.False(t, ok)
+}
+
+func TestSidePort(t *testing.T) {
+	cell := store("db", "", false, nil, 0, 0)
+	assert.Equal(t, "bottom", portGroup(cell, sidePort(&cell, "bottom")))
+	assert.Equal(t, "left", portGroup(cell, sidePort(&cell, "left")))
+	assert.Nil(t, sidePort(&Cell{}, "left"))
+}
+
+func TestOrthogonalRoute(t *testing.T) {
+	from, to := point{100, 100}, point{500, 100}
+
+	// cells on the same height are connected straight
+	assert.Empty(t, orthogonalRoute(from, to, "right", "left", nil, 0))
+
+	// cells on different heights are connected in a channel between them
+	assert.Equal(t, []VertexClass{{X: 300, Y: 100}, {X: 300, Y: 300}}, orthogonalRoute(from, point{500, 300}, "right", "left", nil, 0))
+
+	// a cell in the channel is passed by a channel beside it
+	obstacle := common.NewRectangle(250, 150, 100, 100)
+	vertices := orthogonalRoute(from, point{500, 300}, "right", "left", []*common.Rectangle{obstacle}, 0)
+	require.Len(t, vertices, 2)
+	route := []point{from}
+	for _, vertex := range vertices {
+		route = append(route, point{vertex.X, vertex.Y})
+	}
+	assert.Zero(t, routeCrossings(append(route, point{500, 300}), []*common.Rectangle{obstacle}))
+
+	// routes leaving at the bottom and entering at the side turn once
+	assert.Equal(t, []VertexClass{{X: 100, Y: 300}}, orthogonalRoute(from, point{300, 300}, "bottom", "left", nil, 0))
+
+	// lanes move the route sideways
+	assert.Equal(t, []VertexClass{{X: 120, Y: 100}, {X: 120, Y: 80}, {X: 480, Y: 80}, {X: 480, Y: 100}}, orthogonalRoute(from, to, "right", "left", nil, -20))
+}
+
+func TestRouteDataflow_Parallel(t *testing.T) {
+	a := process("a", "", false, nil, 100, 100)
+	b := process("b", "", false, nil, 500, 100)
+	cells := []Cell{a, b}
+
+	there := newDataflow("there", "", "", false, false, &a, &b, false)
+	routeDataflow(&there, cells)
+	cells = append(cells, there)
+	back := newDataflow("back", "", "", false, false, &b, &a, false)
+	routeDataflow(&back, cells)
+
+	assert.Nil(t, there.Vertices)
+	require.NotNil(t, back.Vertices)
+	assert.Equal(t, "rounded", *back.Connector)
+	for _, vertex := range (*back.Vertices)[1:3] {
+		assert.Equal(t, 150.0, vertex.Y) // one lane below the centers at 130
+	}
+	assert.Equal(t, "left", portGroup(b, back.Source.Port))
+	assert.Equal(t, "right", portGroup(a, back.Target.Port))
+}
+
+// TestGenerate_RoutesDataflows tests that generated flows are connected on the facing sides of their cells
+func TestGenerate_RoutesDataflows(t *testing.T) {
+	const path = "testdata/testoutput_threatdragon_routing.json"
+	defer os.Remove(path)
+
+	web := common.Asset{ID: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", DisplayName: "web", Type: common.AssetTypeWebserver, Source: common.DataSourceDockerCompose}
+	db := common.Asset{ID: "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", DisplayName: "db", Type: common.AssetTypeDatabase, Source: common.DataSourceDockerCompose}
+	require.NoError(t, NewThreatdragonOutput(path, dummyChangelog{}, slog.Default()).Generate(&common.ThreatModel{
+		Assets: []common.Asset{web, db},
+		DataFlows: []common.DataFlow{
+			{ID: "11111111111111111111111111111111", Name: "sql", Source: "web", Target: "db", DataSource: common.DataSourceDockerCompose},
+			{ID: "22222222222222222222222222222222", Name: "events", Source: "db", Target: "web", DataSource: common.DataSourceDockerCompose},
+		},
+	}))
+
+	cells := map[string]Cell{}
+	for _, cell := range readProject(t, path).Detail.Diagrams[0].Cells {
+		cells[*cell.Data.Name] = cell
+	}
+	// the layered layout draws the database right of the web server
+	assert.Equal(t, "right", portGroup(cells["web"], cells["sql"].Source.Port))
+	assert.Equal(t, "left", portGroup(cells["db"], cells["sql"].Target.Port))
+	assert.Equal(t, "left", portGroup(cells["db"], cells["events"].Source.Port))
+	assert.NotNil(t, cells["events"].Vertices)
+}
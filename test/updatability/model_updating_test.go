@@ -49,7 +49,7 @@ func TestUpdateWorkflowAddAssets(t *testing.T) {
 	merged := merger.Merge([]common.ThreatModel{*updatedAssets, *tdAssets})
 
 	require.NoError(t, output.Generate(&merged), "failed to regenerate ThreatDragon output with merged model")
-	assert.Len(t, merged.Assets, 4, "expected 4 assets in the merged model")
+	assert.Len(t, merged.Assets, 5, "expected 5 assets in the merged model")
 }
 
 func TestUpdateWorkflowRemoveAssets(t *testing.T) {
@@ -81,7 +81,7 @@ func TestUpdateWorkflowRemoveAssets(t *testing.T) {
 	merged := merger.Merge([]common.ThreatModel{*updatedAssets, *tdAssets})
 
 	require.NoError(t, output.Generate(&merged), "failed to regenerate ThreatDragon output with merged model")
-	assert.Len(t, merged.Assets, 3, "expected 3 assets in the merged model")
+	assert.Len(t, merged.Assets, 4, "expected 4 assets in the merged model")
 }
 
 func TestUpdateWorkflowOnThreatDragon(t *testing.T) {
@@ -113,10 +113,16 @@ func TestUpdateWorkflowOnThreatDragon(t *testing.T) {
 	project, ok := threatdragonFromExtra.(threatdragon.Project)
 	assert.True(t, ok, "failed to cast input to ThreatDragonModel from first input")
 
-	cells := project.Detail.Diagrams[0].Cells
-	// after the update there should be 5 elements. The user_created_process was created by the user
-	// the other elements are from the docker compose
-	assert.Len(t, cells, 5, "expected 5 cells in the threatdragon model")
+	cells, flows, boundaries := splitCells(project.Detail.Diagrams[0].Cells)
+	// after the update there should be 6 elements. The user_created_process was created by the user
+	// the other elements are from the docker compose, including the internet actor for the published ports
+	assert.Len(t, cells, 6, "expected 6 cells in the threatdragon model")
+	// the published ports of web and web2 are added as flows from the internet actor
+	assert.Len(t, flows, 2, "expected 2 flows in the threatdragon model")
+	// the internet actor and the services of the default network are enclosed by trust boundaries
+	assert.Len(t, boundaries, 2, "expected 2 trust boundaries in the threatdragon model")
+	assert.True(t, hasCellWithName(boundaries, "Internet"), "failed to find Internet boundary")
+	assert.True(t, hasCellWithName(cells, "Internet / External User"), "failed to find Internet / External User")
 	assert.True(t, hasCellWithName(cells, "user_created_process"), "failed to find user_created_process")
 	assert.True(t, hasCellWithName(cells, "web"), "failed to find web")
 	assert.True(t, hasCellWithName(cells, "web2"), "failed to find web2")
@@ -138,16 +144,38 @@ func TestUpdateWorkflowOnThreatDragon(t *testing.T) {
 	project, ok = threatdragonFromExtra.(threatdragon.Project)
 	assert.True(t, ok, "failed to cast input to ThreatDragonModel from second input")
 
-	cells = project.Detail.Diagrams[0].Cells
-	// after the update there should be 4 elements. The user_created_process was created by the user
+	cells, flows, boundaries = splitCells(project.Detail.Diagrams[0].Cells)
+	// after the update there should be 5 elements. The user_created_process was created by the user
 	// the other elements are from the docker compose. web2 should be removed
-	assert.Len(t, cells, 4, "expected 4 cells in the threatdragon model")
+	assert.Len(t, cells, 5, "expected 5 cells in the threatdragon model")
+	// the flow to web2 is removed with it
+	assert.Len(t, flows, 1, "expected 1 flow in the threatdragon model")
+	assert.Len(t, boundaries, 2, "expected 2 trust boundaries in the threatdragon model")
+	assert.True(t, hasCellWithName(cells, "Internet / External User"), "failed to find Internet / External User")
 	assert.True(t, hasCellWithName(cells, "user_created_process"), "failed to find user_created_process")
 	assert.True(t, hasCellWithName(cells, "web"), "failed to find web")
 	assert.True(t, hasCellWithName(cells, "db"), "failed to find db")
 	assert.True(t, hasCellWithName(cells, "db2"), "failed to find db2")
 }
 
+// splitCells splits the cells of a diagram into the flows, the trust boundaries and all other cells
+func splitCells(cells []threatdragon.Cell) ([]threatdragon.Cell, []threatdragon.Cell, []threatdragon.Cell) {
+	others := make([]threatdragon.Cell, 0, len(cells))
+	flows := make([]threatdragon.Cell, 0, len(cells))
+	boundaries := make([]threatdragon.Cell, 0, len(cells))
+	for _, cell := range cells {
+		switch cell.Data.Type {
+		case "tm.Flow":
+			flows = append(flows, cell)
+		case "tm.BoundaryBox":
+			boundaries = append(boundaries, cell)
+		default:
+			others = append(others, cell)
+		}
+	}
+	return others, flows, boundaries
+}
+
 func hasCellWithName(cells []threatdragon.Cell, name string) bool {
 	return slices.ContainsFunc(cells, func(cell threatdragon.Cell) bool {
 		return *cell.Data.Name == name